@@ -0,0 +1,48 @@
+// Command cf-train trains the collaborative filtering model on the recent ad
+// clicks and playback events and stores the factors and co-clicked ads of
+// every item. It retrains every CF_TRAIN_INTERVAL; -once trains a single time.
+package main
+
+import (
+	"Ad-Recommendations/config"
+	"Ad-Recommendations/db"
+	"Ad-Recommendations/services"
+	"context"
+	"flag"
+	"log"
+	"time"
+)
+
+func main() {
+	once := flag.Bool("once", false, "train a single time and exit")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	ctx := context.Background()
+	store, err := db.OpenStore(ctx, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+
+	if _, err := services.TrainCF(ctx, store, cfg.CF, time.Now()); err != nil {
+		if *once {
+			log.Fatalf("Collaborative filtering training failed: %v", err)
+		}
+		log.Printf("⚠️ Collaborative filtering training failed: %v", err)
+	}
+	if *once {
+		return
+	}
+
+	ticker := time.NewTicker(cfg.CF.TrainInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if _, err := services.TrainCF(ctx, store, cfg.CF, time.Now()); err != nil {
+			log.Printf("⚠️ Collaborative filtering training failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+// Command dynamo-migrate copies data from legacy DynamoDB tables into the
+// current key schemas created by db.EnsureTables.
+package main
+
+import (
+	"Ad-Recommendations/db"
+	"context"
+	"log"
+)
+
+func main() {
+	dynamoClient, err := db.InitDynamoDB()
+	if err != nil {
+		log.Fatalf("Failed to initialize DynamoDB: %v", err)
+	}
+
+	ctx := context.Background()
+	if _, err := db.MigrateLegacyPlaybackTable(ctx, dynamoClient); err != nil {
+		log.Fatalf("Playback migration failed: %v", err)
+	}
+	if _, err := db.MigrateLegacyAdClickTable(ctx, dynamoClient); err != nil {
+		log.Fatalf("Ad click migration failed: %v", err)
+	}
+	if _, err := db.BackfillAdStatus(ctx, dynamoClient); err != nil {
+		log.Fatalf("Ad status backfill failed: %v", err)
+	}
+	if _, err := db.BackfillEventDays(ctx, dynamoClient); err != nil {
+		log.Fatalf("Day backfill failed: %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+// Command embed-ads computes and stores the embedding of every ad. By default
+// only ads without a current embedding are embedded; -force re-embeds all ads,
+// e.g. after switching EMBEDDING_MODEL or EMBEDDING_MODEL_VERSION.
+package main
+
+import (
+	"Ad-Recommendations/config"
+	"Ad-Recommendations/db"
+	"Ad-Recommendations/services"
+	"context"
+	"flag"
+	"log"
+)
+
+func main() {
+	force := flag.Bool("force", false, "re-embed ads that already have a current embedding")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	ctx := context.Background()
+	store, err := db.OpenStore(ctx, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+
+	embedder, err := services.NewEmbedder(cfg.Embedding)
+	if err != nil {
+		log.Fatalf("Failed to initialize embedder: %v", err)
+	}
+
+	embeddings := services.NewAdEmbeddingService(store, embedder, cfg.Embedding)
+	if _, err := embeddings.Backfill(ctx, *force); err != nil {
+		log.Fatalf("Ad embedding backfill failed: %v", err)
+	}
+}
@@ -0,0 +1,607 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Storage backends selectable through STORAGE_BACKEND
+const (
+	StorageDynamoDB = "dynamodb"
+	StorageMemory   = "memory"
+	StoragePostgres = "postgres"
+)
+
+// Embedding providers selectable through EMBEDDING_PROVIDER
+const (
+	EmbeddingProviderFlask   = "flask"   // The Python AI service
+	EmbeddingProviderOpenAI  = "openai"  // Any OpenAI-compatible /v1/embeddings API
+	EmbeddingProviderHashing = "hashing" // Deterministic local embedder for tests
+)
+
+// Content signals selectable through RECOMMENDATION_SIGNAL
+const (
+	SignalEmbedding = "embedding" // Embedding similarity, falling back to text when the embedder fails
+	SignalText      = "text"      // In-process TF-IDF / BM25 text relevance only
+)
+
+// Ranking pipeline scorers, weighted through RANKING_WEIGHTS
+const (
+	ScorerCategory   = "category"   // Affinity of the user's playback to the ad category
+	ScorerContent    = "content"    // Embedding similarity, or text relevance per RECOMMENDATION_SIGNAL
+	ScorerKeywords   = "keywords"   // Overlap of ad keywords with the playback history
+	ScorerRecency    = "recency"    // Decays with the age of the ad
+	ScorerPopularity = "popularity" // Recent clicks on the ad across all users
+	ScorerCTR        = "ctr"        // Smoothed click-through rate of the ad for the user's movie taste
+	ScorerCF         = "cf"         // Collaborative filtering: the user's factors times the ad's
+)
+
+// Scorers lists every scorer name accepted in weights
+var Scorers = []string{ScorerCategory, ScorerContent, ScorerKeywords, ScorerRecency, ScorerPopularity, ScorerCTR, ScorerCF}
+
+// Candidate sources selectable through RANKING_CANDIDATES
+const (
+	CandidatesCategory = "category" // Active ads of the mapped ad categories
+	CandidatesNearest  = "nearest"  // Ads nearest to the user vector in the ANN index
+	CandidatesCF       = "cf"       // Ads co-clicked with the user's clicks, then best by collaborative filtering
+)
+
+// CandidateSources lists every candidate source accepted in RANKING_CANDIDATES
+var CandidateSources = []string{CandidatesCategory, CandidatesNearest, CandidatesCF}
+
+// Policies for ads the user already clicked often, selectable through CLICK_REPEAT_POLICY
+const (
+	RepeatClickNone   = "none"   // Rank them like any other ad
+	RepeatClickDemote = "demote" // Multiply their score by CLICK_REPEAT_MULTIPLIER below 1
+	RepeatClickBoost  = "boost"  // Multiply their score by CLICK_REPEAT_MULTIPLIER above 1
+)
+
+// Exploration policies selectable through EXPLORATION_POLICY
+const (
+	ExplorationNone          = "none"           // Serve the ranked list as is
+	ExplorationThompson      = "thompson"       // Thompson sampling over the smoothed CTR posteriors
+	ExplorationEpsilonGreedy = "epsilon_greedy" // Random unranked candidates with probability EXPLORATION_EPSILON
+)
+
+// Config holds the settings read at startup
+type Config struct {
+	StorageBackend string // One of the Storage* constants
+	FixtureFile    string // Optional JSON/JSONL file used to seed the store
+	Postgres       PostgresConfig
+	Recommendation RecommendationConfig
+	Embedding      EmbeddingConfig
+	ANN            ANNConfig
+	EmbeddingCache EmbeddingCacheConfig
+	CTR            CTRConfig
+	Experiments    ExperimentConfig
+	CF             CFConfig
+}
+
+// PostgresConfig holds the PostgreSQL connection and pool settings
+type PostgresConfig struct {
+	URL               string
+	MaxConns          int32
+	MinConns          int32
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+}
+
+// RecommendationConfig holds the settings of the recommendation pipeline
+type RecommendationConfig struct {
+	HistoryLimit    int           // Newest playback events used to build the user profile, 0 for all
+	HistoryWindow   time.Duration // How far back playback is considered, 0 for all time
+	ProfileHalfLife time.Duration // Age at which a play or click counts half in the user profile, 0 for no decay
+	PlaybackWeight  float64       // Share of playback in the user profile
+	ClickWeight     float64       // Share of ad clicks in the user profile
+
+	RepeatClickPolicy     string  // One of the RepeatClick* constants
+	RepeatClickThreshold  int     // Clicks by the user from which the policy applies to an ad
+	RepeatClickMultiplier float64 // Score multiplier of the policy
+	Signal                string  // One of the Signal* constants
+	ANNCandidates         int     // Ads retrieved by embedding similarity in addition to category candidates, 0 to disable
+	CFCandidates          int     // Ads retrieved by collaborative filtering when its source is selected
+
+	Candidates       []string           // Candidate sources in order, Candidates* constants
+	Weights          map[string]float64 // Weight per scorer name; scorers without weight are skipped
+	RecencyHalfLife  time.Duration      // Ad age at which the recency score halves
+	PopularityWindow time.Duration      // How far back clicks count towards popularity
+	DiversityLambda  float64            // MMR trade-off: 1 ranks by score only, lower values favour diversity
+	MaxPerCategory   int                // Ads of one category in the final list, 0 for no cap
+	MaxPerAdvertiser int                // Ads of one advertiser in the final list, 0 for no cap
+	Limit            int                // Ads returned per request unless overridden
+	MaxLimit         int                // Upper bound for a per-request limit
+	MinScore         float64            // Ads scoring below this are not returned
+	LogImpressions   bool               // Log every served list as impressions under a recommendation ID
+
+	ExplorationPolicy  string  // One of the Exploration* constants
+	ExplorationBudget  int     // Slots per request that may be given to exploration unless overridden
+	ExplorationEpsilon float64 // Chance of exploring each slot of the budget with epsilon-greedy
+}
+
+// CTRConfig holds the settings of the rolling click-through rates and the smoothed CTR estimates
+type CTRConfig struct {
+	Window  time.Duration // How far back impressions count
+	Buckets int           // Time buckets the window is divided into; the oldest is dropped as time passes
+
+	SnapshotInterval time.Duration // How often estimator counts are saved and its priors refitted
+	PriorStrength    float64       // Beta prior weight, in impressions, when it cannot be fitted from the data
+}
+
+// CFConfig holds the settings of collaborative filtering training, done by
+// cmd/cf-train, and of serving the trained model
+type CFConfig struct {
+	Factors        int     // Size of the latent factor vectors
+	Iterations     int     // ALS alternations per training
+	Regularization float64 // L2 penalty on the factors
+	Alpha          float64 // Confidence gained per click
+	PlaybackWeight float64 // Weight of a play of a movie category relative to a click on an ad
+	Neighbours     int     // Co-clicked ads kept per ad
+	MinCoClicks    int     // Users who must have clicked both ads of a co-clicked pair
+
+	Window         time.Duration // How far back clicks and plays are trained on
+	TrainInterval  time.Duration // How often cf-train retrains unless run once
+	ReloadInterval time.Duration // How often the server reloads the stored model
+}
+
+// EmbeddingCacheConfig holds the settings of the embedding cache tiers
+type EmbeddingCacheConfig struct {
+	Size          int           // Vectors kept in memory, 0 disables the cache
+	TTL           time.Duration // Lifetime of a cached vector, 0 for no expiry
+	RedisAddr     string        // Optional shared Redis tier, e.g. localhost:6379
+	RedisPassword string
+	RedisDB       int
+	WarmUp        bool // Load stored ad embeddings into the cache at boot
+}
+
+// ANNConfig holds the parameters of the in-process nearest-neighbour index
+type ANNConfig struct {
+	M              int // Neighbours per node and layer
+	EfConstruction int // Candidate list size while inserting
+	EfSearch       int // Candidate list size while searching
+}
+
+// EmbeddingConfig selects the embedding provider and model. Changing the model
+// version marks every stored ad embedding as stale.
+type EmbeddingConfig struct {
+	Provider     string // One of the EmbeddingProvider* constants
+	Endpoint     string // Full URL of the embedding endpoint
+	APIKey       string
+	Model        string
+	ModelVersion string
+	Dimension    int // Expected vector size, 0 to accept what the model returns
+
+	Timeout          time.Duration // Deadline of a single call to the provider
+	MaxRetries       int           // Retries of a failed call
+	RetryBackoff     time.Duration // Base delay between retries, jittered
+	BatchSize        int           // Texts per call; larger lists are split into parallel calls
+	MaxParallel      int           // Calls in flight for one embedding request
+	BreakerThreshold int           // Consecutive failures that open the circuit breaker, 0 disables it
+	BreakerCooldown  time.Duration // How long an open breaker rejects calls
+}
+
+// embeddingDefaults holds the endpoint, model and dimension used for each provider when unset
+var embeddingDefaults = map[string]EmbeddingConfig{
+	EmbeddingProviderFlask:   {Endpoint: "http://localhost:5001/generate-bert", Model: "bert-base-uncased", Dimension: 768},
+	EmbeddingProviderOpenAI:  {Endpoint: "https://api.openai.com/v1/embeddings", Model: "text-embedding-3-small"},
+	EmbeddingProviderHashing: {Model: "hashing", Dimension: 768},
+}
+
+// Load reads the configuration from environment variables
+func Load() (Config, error) {
+	cfg := Config{
+		StorageBackend: getEnv("STORAGE_BACKEND", StorageDynamoDB),
+		FixtureFile:    os.Getenv("FIXTURE_FILE"),
+		Postgres: PostgresConfig{
+			URL: os.Getenv("DATABASE_URL"),
+		},
+	}
+
+	var err error
+	if cfg.Embedding, err = loadEmbeddingConfig(); err != nil {
+		return cfg, err
+	}
+	if cfg.Postgres.MaxConns, err = getEnvInt32("PG_MAX_CONNS", 10); err != nil {
+		return cfg, err
+	}
+	if cfg.Postgres.MinConns, err = getEnvInt32("PG_MIN_CONNS", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.Postgres.MaxConnLifetime, err = getEnvDuration("PG_MAX_CONN_LIFETIME", time.Hour); err != nil {
+		return cfg, err
+	}
+	if cfg.Postgres.MaxConnIdleTime, err = getEnvDuration("PG_MAX_CONN_IDLE_TIME", 30*time.Minute); err != nil {
+		return cfg, err
+	}
+	if cfg.Postgres.HealthCheckPeriod, err = getEnvDuration("PG_HEALTH_CHECK_PERIOD", time.Minute); err != nil {
+		return cfg, err
+	}
+
+	if cfg.Recommendation.HistoryLimit, err = getEnvInt("PLAYBACK_HISTORY_LIMIT", 50); err != nil {
+		return cfg, err
+	}
+	if cfg.Recommendation.HistoryWindow, err = getEnvDuration("PLAYBACK_HISTORY_WINDOW", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.Recommendation.ProfileHalfLife, err = getEnvDuration("PLAYBACK_HALF_LIFE", 7*24*time.Hour); err != nil {
+		return cfg, err
+	}
+	if cfg.Recommendation.PlaybackWeight, err = getEnvFloat("PROFILE_PLAYBACK_WEIGHT", 1); err != nil {
+		return cfg, err
+	}
+	if cfg.Recommendation.ClickWeight, err = getEnvFloat("PROFILE_CLICK_WEIGHT", 0.5); err != nil {
+		return cfg, err
+	}
+	if cfg.Recommendation.PlaybackWeight < 0 || cfg.Recommendation.ClickWeight < 0 ||
+		cfg.Recommendation.PlaybackWeight+cfg.Recommendation.ClickWeight == 0 {
+		return cfg, fmt.Errorf("invalid PROFILE_*_WEIGHT: weights must not be negative and not both 0")
+	}
+	if err := loadRepeatClickPolicy(&cfg.Recommendation); err != nil {
+		return cfg, err
+	}
+	if cfg.Recommendation.ANNCandidates, err = getEnvInt("ANN_CANDIDATES", 20); err != nil {
+		return cfg, err
+	}
+	if cfg.ANN.M, err = getEnvInt("ANN_M", 16); err != nil {
+		return cfg, err
+	}
+	if cfg.ANN.EfConstruction, err = getEnvInt("ANN_EF_CONSTRUCTION", 200); err != nil {
+		return cfg, err
+	}
+	if cfg.ANN.EfSearch, err = getEnvInt("ANN_EF_SEARCH", 64); err != nil {
+		return cfg, err
+	}
+	if cfg.EmbeddingCache.Size, err = getEnvInt("EMBEDDING_CACHE_SIZE", 10000); err != nil {
+		return cfg, err
+	}
+	if cfg.EmbeddingCache.TTL, err = getEnvDuration("EMBEDDING_CACHE_TTL", 24*time.Hour); err != nil {
+		return cfg, err
+	}
+	cfg.EmbeddingCache.RedisAddr = os.Getenv("REDIS_ADDR")
+	cfg.EmbeddingCache.RedisPassword = os.Getenv("REDIS_PASSWORD")
+	if cfg.EmbeddingCache.RedisDB, err = getEnvInt("REDIS_DB", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.EmbeddingCache.WarmUp, err = getEnvBool("EMBEDDING_CACHE_WARMUP", true); err != nil {
+		return cfg, err
+	}
+	if cfg.Recommendation.Candidates, err = loadRankingCandidates(); err != nil {
+		return cfg, err
+	}
+	if cfg.Recommendation.Weights, err = loadRankingWeights(); err != nil {
+		return cfg, err
+	}
+	if cfg.Recommendation.RecencyHalfLife, err = getEnvDuration("RANKING_RECENCY_HALF_LIFE", 30*24*time.Hour); err != nil {
+		return cfg, err
+	}
+	if cfg.Recommendation.PopularityWindow, err = getEnvDuration("RANKING_POPULARITY_WINDOW", 7*24*time.Hour); err != nil {
+		return cfg, err
+	}
+	if cfg.Recommendation.DiversityLambda, err = getEnvFloat("RANKING_MMR_LAMBDA", 1); err != nil {
+		return cfg, err
+	}
+	if cfg.Recommendation.DiversityLambda <= 0 || cfg.Recommendation.DiversityLambda > 1 {
+		return cfg, fmt.Errorf("invalid RANKING_MMR_LAMBDA: must be in (0, 1]")
+	}
+	if cfg.Recommendation.MaxPerCategory, err = getEnvInt("RANKING_MAX_PER_CATEGORY", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.Recommendation.MaxPerAdvertiser, err = getEnvInt("RANKING_MAX_PER_ADVERTISER", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.Recommendation.Limit, err = getEnvInt("RECOMMENDATION_LIMIT", 5); err != nil {
+		return cfg, err
+	}
+	if cfg.Recommendation.MaxLimit, err = getEnvInt("RECOMMENDATION_MAX_LIMIT", 50); err != nil {
+		return cfg, err
+	}
+	if cfg.Recommendation.Limit < 1 || cfg.Recommendation.MaxLimit < cfg.Recommendation.Limit {
+		return cfg, fmt.Errorf("invalid RECOMMENDATION_LIMIT: must be between 1 and RECOMMENDATION_MAX_LIMIT")
+	}
+	if cfg.Recommendation.MinScore, err = getEnvFloat("RECOMMENDATION_MIN_SCORE", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.Recommendation.LogImpressions, err = getEnvBool("RECOMMENDATION_LOG_IMPRESSIONS", false); err != nil {
+		return cfg, err
+	}
+	if cfg.CTR.Window, err = getEnvDuration("CTR_WINDOW", 24*time.Hour); err != nil {
+		return cfg, err
+	}
+	if cfg.CTR.Buckets, err = getEnvInt("CTR_BUCKETS", 24); err != nil {
+		return cfg, err
+	}
+	if cfg.CTR.Window <= 0 || cfg.CTR.Buckets < 1 {
+		return cfg, fmt.Errorf("invalid CTR_WINDOW or CTR_BUCKETS: window must be positive with at least one bucket")
+	}
+	if cfg.CTR.SnapshotInterval, err = getEnvDuration("CTR_SNAPSHOT_INTERVAL", 5*time.Minute); err != nil {
+		return cfg, err
+	}
+	if cfg.CTR.PriorStrength, err = getEnvFloat("CTR_PRIOR_STRENGTH", 100); err != nil {
+		return cfg, err
+	}
+	if cfg.CTR.SnapshotInterval <= 0 || cfg.CTR.PriorStrength <= 0 {
+		return cfg, fmt.Errorf("invalid CTR_SNAPSHOT_INTERVAL or CTR_PRIOR_STRENGTH: both must be positive")
+	}
+	if err := loadExploration(&cfg.Recommendation); err != nil {
+		return cfg, err
+	}
+	if cfg.Experiments, err = loadExperiments(); err != nil {
+		return cfg, err
+	}
+	if err := loadCF(&cfg); err != nil {
+		return cfg, err
+	}
+	cfg.Recommendation.Signal = getEnv("RECOMMENDATION_SIGNAL", SignalEmbedding)
+	if cfg.Recommendation.Signal != SignalEmbedding && cfg.Recommendation.Signal != SignalText {
+		return cfg, fmt.Errorf("invalid RECOMMENDATION_SIGNAL: unknown signal %q", cfg.Recommendation.Signal)
+	}
+
+	return cfg, nil
+}
+
+// repeatClickMultipliers are the default multipliers of the repeat-click policies
+var repeatClickMultipliers = map[string]float64{
+	RepeatClickNone:   1,
+	RepeatClickDemote: 0.5,
+	RepeatClickBoost:  1.5,
+}
+
+// loadRepeatClickPolicy reads the policy for ads the user already clicked often
+func loadRepeatClickPolicy(cfg *RecommendationConfig) error {
+	cfg.RepeatClickPolicy = getEnv("CLICK_REPEAT_POLICY", RepeatClickNone)
+	fallback, ok := repeatClickMultipliers[cfg.RepeatClickPolicy]
+	if !ok {
+		return fmt.Errorf("invalid CLICK_REPEAT_POLICY: unknown policy %q", cfg.RepeatClickPolicy)
+	}
+
+	var err error
+	if cfg.RepeatClickThreshold, err = getEnvInt("CLICK_REPEAT_THRESHOLD", 3); err != nil {
+		return err
+	}
+	if cfg.RepeatClickMultiplier, err = getEnvFloat("CLICK_REPEAT_MULTIPLIER", fallback); err != nil {
+		return err
+	}
+	switch {
+	case cfg.RepeatClickPolicy == RepeatClickDemote && (cfg.RepeatClickMultiplier < 0 || cfg.RepeatClickMultiplier >= 1):
+		return fmt.Errorf("invalid CLICK_REPEAT_MULTIPLIER: demote needs a multiplier in [0, 1)")
+	case cfg.RepeatClickPolicy == RepeatClickBoost && cfg.RepeatClickMultiplier <= 1:
+		return fmt.Errorf("invalid CLICK_REPEAT_MULTIPLIER: boost needs a multiplier above 1")
+	}
+	return nil
+}
+
+// loadExploration reads the exploration policy and its budget
+func loadExploration(cfg *RecommendationConfig) error {
+	cfg.ExplorationPolicy = getEnv("EXPLORATION_POLICY", ExplorationNone)
+	if !slices.Contains([]string{ExplorationNone, ExplorationThompson, ExplorationEpsilonGreedy}, cfg.ExplorationPolicy) {
+		return fmt.Errorf("invalid EXPLORATION_POLICY: unknown policy %q", cfg.ExplorationPolicy)
+	}
+
+	var err error
+	if cfg.ExplorationBudget, err = getEnvInt("EXPLORATION_BUDGET", 1); err != nil {
+		return err
+	}
+	if cfg.ExplorationEpsilon, err = getEnvFloat("EXPLORATION_EPSILON", 0.1); err != nil {
+		return err
+	}
+	if cfg.ExplorationBudget < 0 || cfg.ExplorationEpsilon < 0 || cfg.ExplorationEpsilon > 1 {
+		return fmt.Errorf("invalid EXPLORATION_BUDGET or EXPLORATION_EPSILON: budget must not be negative and epsilon must be in [0, 1]")
+	}
+	return nil
+}
+
+// loadCF reads the collaborative filtering settings
+func loadCF(cfg *Config) error {
+	var err error
+	if cfg.Recommendation.CFCandidates, err = getEnvInt("CF_CANDIDATES", 20); err != nil {
+		return err
+	}
+	if cfg.CF.Factors, err = getEnvInt("CF_FACTORS", 32); err != nil {
+		return err
+	}
+	if cfg.CF.Iterations, err = getEnvInt("CF_ITERATIONS", 10); err != nil {
+		return err
+	}
+	if cfg.CF.Regularization, err = getEnvFloat("CF_REGULARIZATION", 0.1); err != nil {
+		return err
+	}
+	if cfg.CF.Alpha, err = getEnvFloat("CF_ALPHA", 40); err != nil {
+		return err
+	}
+	if cfg.CF.PlaybackWeight, err = getEnvFloat("CF_PLAYBACK_WEIGHT", 0.2); err != nil {
+		return err
+	}
+	if cfg.CF.Neighbours, err = getEnvInt("CF_NEIGHBOURS", 20); err != nil {
+		return err
+	}
+	if cfg.CF.MinCoClicks, err = getEnvInt("CF_MIN_CO_CLICKS", 2); err != nil {
+		return err
+	}
+	if cfg.CF.Window, err = getEnvDuration("CF_WINDOW", 90*24*time.Hour); err != nil {
+		return err
+	}
+	if cfg.CF.TrainInterval, err = getEnvDuration("CF_TRAIN_INTERVAL", 6*time.Hour); err != nil {
+		return err
+	}
+	if cfg.CF.ReloadInterval, err = getEnvDuration("CF_RELOAD_INTERVAL", 15*time.Minute); err != nil {
+		return err
+	}
+	if cfg.CF.Factors < 1 || cfg.CF.Iterations < 1 || cfg.CF.Regularization <= 0 || cfg.CF.Alpha < 0 || cfg.CF.PlaybackWeight < 0 {
+		return fmt.Errorf("invalid CF_*: factors and iterations must be at least 1, regularization positive, alpha and playback weight not negative")
+	}
+	if cfg.CF.TrainInterval <= 0 || cfg.CF.ReloadInterval <= 0 {
+		return fmt.Errorf("invalid CF_TRAIN_INTERVAL or CF_RELOAD_INTERVAL: both must be positive")
+	}
+	return nil
+}
+
+// loadRankingCandidates reads the comma-separated candidate sources of RANKING_CANDIDATES
+func loadRankingCandidates() ([]string, error) {
+	candidates := []string{}
+	for _, name := range strings.Split(getEnv("RANKING_CANDIDATES", "category,nearest"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(CandidateSources, name) {
+			return nil, fmt.Errorf("invalid RANKING_CANDIDATES: unknown candidate source %q", name)
+		}
+		candidates = append(candidates, name)
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("invalid RANKING_CANDIDATES: no candidate source")
+	}
+	return candidates, nil
+}
+
+// loadRankingWeights reads RANKING_WEIGHTS, a comma-separated list of scorer=weight pairs
+func loadRankingWeights() (map[string]float64, error) {
+	weights := map[string]float64{}
+	for _, pair := range strings.Split(getEnv("RANKING_WEIGHTS", "category=0.4,content=0.6"), ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !slices.Contains(Scorers, name) {
+			return nil, fmt.Errorf("invalid RANKING_WEIGHTS: unknown scorer in %q", pair)
+		}
+		weight, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid RANKING_WEIGHTS: weight of %s must be a non-negative number", name)
+		}
+		weights[name] = weight
+	}
+
+	positive := false
+	for _, weight := range weights {
+		positive = positive || weight > 0
+	}
+	if !positive {
+		return nil, fmt.Errorf("invalid RANKING_WEIGHTS: at least one weight must be positive")
+	}
+	return weights, nil
+}
+
+// loadEmbeddingConfig reads the embedding provider settings, filling unset
+// values with the defaults of the selected provider
+func loadEmbeddingConfig() (EmbeddingConfig, error) {
+	provider := getEnv("EMBEDDING_PROVIDER", EmbeddingProviderFlask)
+	defaults, ok := embeddingDefaults[provider]
+	if !ok {
+		return EmbeddingConfig{}, fmt.Errorf("invalid EMBEDDING_PROVIDER: unknown provider %q", provider)
+	}
+
+	cfg := EmbeddingConfig{
+		Provider:     provider,
+		Endpoint:     getEnv("EMBEDDING_ENDPOINT", defaults.Endpoint),
+		APIKey:       getEnv("EMBEDDING_API_KEY", os.Getenv("OPENAI_API_KEY")),
+		Model:        getEnv("EMBEDDING_MODEL", defaults.Model),
+		ModelVersion: getEnv("EMBEDDING_MODEL_VERSION", "1"),
+	}
+
+	var err error
+	if cfg.Dimension, err = getEnvInt("EMBEDDING_DIMENSION", defaults.Dimension); err != nil {
+		return cfg, err
+	}
+	if cfg.Timeout, err = getEnvDuration("EMBEDDING_TIMEOUT", 10*time.Second); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxRetries, err = getEnvInt("EMBEDDING_MAX_RETRIES", 2); err != nil {
+		return cfg, err
+	}
+	if cfg.RetryBackoff, err = getEnvDuration("EMBEDDING_RETRY_BACKOFF", 200*time.Millisecond); err != nil {
+		return cfg, err
+	}
+	if cfg.BatchSize, err = getEnvInt("EMBEDDING_BATCH_SIZE", 32); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxParallel, err = getEnvInt("EMBEDDING_MAX_PARALLEL", 4); err != nil {
+		return cfg, err
+	}
+	if cfg.BreakerThreshold, err = getEnvInt("EMBEDDING_BREAKER_THRESHOLD", 5); err != nil {
+		return cfg, err
+	}
+	if cfg.BreakerCooldown, err = getEnvDuration("EMBEDDING_BREAKER_COOLDOWN", 30*time.Second); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// getEnv returns the value of an environment variable or a fallback when unset
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// getEnvInt parses an integer environment variable
+func getEnvInt(key string, fallback int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return fallback, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return parsed, nil
+}
+
+// getEnvInt32 parses an integer environment variable
+func getEnvInt32(key string, fallback int32) (int32, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	parsed, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return fallback, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return int32(parsed), nil
+}
+
+// getEnvFloat parses a floating-point environment variable
+func getEnvFloat(key string, fallback float64) (float64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fallback, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return parsed, nil
+}
+
+// getEnvBool parses a boolean environment variable such as "true" or "0"
+func getEnvBool(key string, fallback bool) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return fallback, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return parsed, nil
+}
+
+// getEnvDuration parses a duration environment variable such as "30s" or "1h"
+func getEnvDuration(key string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return fallback, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"time"
+)
+
+// ExperimentConfig holds the A/B experiments read from EXPERIMENTS_FILE
+type ExperimentConfig struct {
+	Experiments     []Experiment  // In order of precedence; a user takes part in the first that enrolls them
+	ReportWindow    time.Duration // How far back impressions count in the experiment report unless requested otherwise
+	ReportMaxWindow time.Duration // How far back a report may be requested
+}
+
+// maxReportWindow bounds EXPERIMENT_REPORT_MAX_WINDOW to a year, within the
+// days a DynamoDB read by day may span
+const maxReportWindow = 365 * 24 * time.Hour
+
+// Experiment splits a share of the users between ranking variants
+type Experiment struct {
+	Name     string              `json:"name"`
+	Traffic  float64             `json:"traffic"`  // Share of users enrolled, in (0, 1]
+	Variants []ExperimentVariant `json:"variants"` // The first is the control the others are compared to
+}
+
+// ExperimentVariant changes the ranking configuration for the users assigned
+// to it; unset fields keep the configured value
+type ExperimentVariant struct {
+	Name  string  `json:"name"`
+	Split float64 `json:"split"` // Share of the enrolled users, relative to the other variants
+
+	Weights           map[string]float64 `json:"weights,omitempty"`    // Replace RANKING_WEIGHTS
+	Candidates        []string           `json:"candidates,omitempty"` // Replace RANKING_CANDIDATES
+	DiversityLambda   *float64           `json:"mmr_lambda,omitempty"`
+	MaxPerCategory    *int               `json:"max_per_category,omitempty"`
+	MaxPerAdvertiser  *int               `json:"max_per_advertiser,omitempty"`
+	Limit             *int               `json:"limit,omitempty"`
+	MinScore          *float64           `json:"min_score,omitempty"`
+	ExplorationPolicy string             `json:"exploration_policy,omitempty"`
+	ExplorationBudget *int               `json:"exploration_budget,omitempty"`
+}
+
+// Apply returns cfg changed by the variant
+func (v ExperimentVariant) Apply(cfg RecommendationConfig) RecommendationConfig {
+	if v.Weights != nil {
+		cfg.Weights = maps.Clone(v.Weights)
+	}
+	if v.Candidates != nil {
+		cfg.Candidates = slices.Clone(v.Candidates)
+	}
+	if v.DiversityLambda != nil {
+		cfg.DiversityLambda = *v.DiversityLambda
+	}
+	if v.MaxPerCategory != nil {
+		cfg.MaxPerCategory = *v.MaxPerCategory
+	}
+	if v.MaxPerAdvertiser != nil {
+		cfg.MaxPerAdvertiser = *v.MaxPerAdvertiser
+	}
+	if v.Limit != nil {
+		cfg.Limit = min(*v.Limit, cfg.MaxLimit)
+	}
+	if v.MinScore != nil {
+		cfg.MinScore = *v.MinScore
+	}
+	if v.ExplorationPolicy != "" {
+		cfg.ExplorationPolicy = v.ExplorationPolicy
+	}
+	if v.ExplorationBudget != nil {
+		cfg.ExplorationBudget = *v.ExplorationBudget
+	}
+	return cfg
+}
+
+// loadExperiments reads the experiments of EXPERIMENTS_FILE, a JSON array of
+// experiments; without a file no experiment runs
+func loadExperiments() (ExperimentConfig, error) {
+	cfg := ExperimentConfig{}
+	var err error
+	if cfg.ReportWindow, err = getEnvDuration("EXPERIMENT_REPORT_WINDOW", 30*24*time.Hour); err != nil {
+		return cfg, err
+	}
+	if cfg.ReportWindow <= 0 {
+		return cfg, fmt.Errorf("invalid EXPERIMENT_REPORT_WINDOW: must be positive")
+	}
+	if cfg.ReportMaxWindow, err = getEnvDuration("EXPERIMENT_REPORT_MAX_WINDOW", 90*24*time.Hour); err != nil {
+		return cfg, err
+	}
+	if cfg.ReportMaxWindow < cfg.ReportWindow || cfg.ReportMaxWindow > maxReportWindow {
+		return cfg, fmt.Errorf("invalid EXPERIMENT_REPORT_MAX_WINDOW: must be between EXPERIMENT_REPORT_WINDOW and %s", maxReportWindow)
+	}
+
+	path := os.Getenv("EXPERIMENTS_FILE")
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid EXPERIMENTS_FILE: %w", err)
+	}
+	if err := json.Unmarshal(data, &cfg.Experiments); err != nil {
+		return cfg, fmt.Errorf("invalid EXPERIMENTS_FILE: %w", err)
+	}
+
+	names := map[string]bool{}
+	for _, experiment := range cfg.Experiments {
+		if experiment.Name == "" || names[experiment.Name] {
+			return cfg, fmt.Errorf("invalid EXPERIMENTS_FILE: experiment names must be set and unique")
+		}
+		names[experiment.Name] = true
+		if err := experiment.validate(); err != nil {
+			return cfg, fmt.Errorf("invalid EXPERIMENTS_FILE: experiment %s: %w", experiment.Name, err)
+		}
+	}
+	return cfg, nil
+}
+
+// validate checks the traffic and variants of an experiment
+func (e Experiment) validate() error {
+	if e.Traffic <= 0 || e.Traffic > 1 {
+		return fmt.Errorf("traffic must be in (0, 1]")
+	}
+	if len(e.Variants) < 2 {
+		return fmt.Errorf("at least two variants are needed")
+	}
+
+	names := map[string]bool{}
+	for _, variant := range e.Variants {
+		if variant.Name == "" || names[variant.Name] {
+			return fmt.Errorf("variant names must be set and unique")
+		}
+		names[variant.Name] = true
+		if err := variant.validate(); err != nil {
+			return fmt.Errorf("variant %s: %w", variant.Name, err)
+		}
+	}
+	return nil
+}
+
+// validate checks the values a variant sets like the environment variables they replace
+func (v ExperimentVariant) validate() error {
+	if v.Split <= 0 {
+		return fmt.Errorf("split must be positive")
+	}
+	if v.Weights != nil {
+		positive := false
+		for name, weight := range v.Weights {
+			if !slices.Contains(Scorers, name) || weight < 0 {
+				return fmt.Errorf("weights must be non-negative weights of known scorers")
+			}
+			positive = positive || weight > 0
+		}
+		if !positive {
+			return fmt.Errorf("at least one weight must be positive")
+		}
+	}
+	if v.Candidates != nil {
+		if len(v.Candidates) == 0 {
+			return fmt.Errorf("no candidate source")
+		}
+		for _, name := range v.Candidates {
+			if !slices.Contains(CandidateSources, name) {
+				return fmt.Errorf("unknown candidate source %q", name)
+			}
+		}
+	}
+	if v.DiversityLambda != nil && (*v.DiversityLambda <= 0 || *v.DiversityLambda > 1) {
+		return fmt.Errorf("mmr_lambda must be in (0, 1]")
+	}
+	if v.Limit != nil && *v.Limit < 1 {
+		return fmt.Errorf("limit must be at least 1")
+	}
+	if v.ExplorationPolicy != "" && !slices.Contains([]string{ExplorationNone, ExplorationThompson, ExplorationEpsilonGreedy}, v.ExplorationPolicy) {
+		return fmt.Errorf("unknown exploration policy %q", v.ExplorationPolicy)
+	}
+	if v.ExplorationBudget != nil && *v.ExplorationBudget < 0 {
+		return fmt.Errorf("exploration_budget must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+// Package database holds the versioned PostgreSQL schema migrations.
+package database
+
+import "embed"
+
+// Migrations contains the numbered migrations/NNNN_name.sql files, applied in order
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
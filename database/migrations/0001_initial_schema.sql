@@ -1,11 +1,12 @@
-CREATE TABLE playback_history (
+-- IF NOT EXISTS lets databases created from the original migrations.sql adopt versioning
+CREATE TABLE IF NOT EXISTS playback_history (
     id SERIAL PRIMARY KEY,
     user_id VARCHAR(50) NOT NULL,
     category VARCHAR(50) NOT NULL,
     timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
-CREATE TABLE ad_click_history (
+CREATE TABLE IF NOT EXISTS ad_click_history (
     id SERIAL PRIMARY KEY,
     user_id VARCHAR(50) NOT NULL,
     ad_id VARCHAR(50) NOT NULL,
@@ -13,7 +14,7 @@ CREATE TABLE ad_click_history (
     timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
-CREATE TABLE users (
+CREATE TABLE IF NOT EXISTS users (
     user_id VARCHAR(50) PRIMARY KEY,
     preferences TEXT[],
     playback_history JSONB,
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS ads (
+    ad_id VARCHAR(50) PRIMARY KEY,
+    category VARCHAR(50) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    keywords TEXT[] NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX IF NOT EXISTS ads_category_idx ON ads (category);
+
+CREATE TABLE IF NOT EXISTS category_mappings (
+    movie_category VARCHAR(50) PRIMARY KEY,
+    ad_categories TEXT[] NOT NULL DEFAULT '{}',
+    weight DOUBLE PRECISION NOT NULL DEFAULT 1.0
+);
+
+CREATE INDEX IF NOT EXISTS playback_history_user_time_idx ON playback_history (user_id, timestamp);
+CREATE INDEX IF NOT EXISTS ad_click_history_user_time_idx ON ad_click_history (user_id, timestamp);
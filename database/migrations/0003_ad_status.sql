@@ -0,0 +1,4 @@
+ALTER TABLE ads ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'active';
+
+DROP INDEX IF EXISTS ads_category_idx;
+CREATE INDEX IF NOT EXISTS ads_category_status_idx ON ads (category, status);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS ad_embeddings (
+    ad_id VARCHAR(50) NOT NULL,
+    model VARCHAR(100) NOT NULL,
+    model_version VARCHAR(50) NOT NULL,
+    content_hash VARCHAR(64) NOT NULL,
+    vector DOUBLE PRECISION[] NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (ad_id, model)
+);
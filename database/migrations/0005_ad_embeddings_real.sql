@@ -0,0 +1,2 @@
+-- Store ad embedding vectors as float32 to halve their size
+ALTER TABLE ad_embeddings ALTER COLUMN vector TYPE REAL[];
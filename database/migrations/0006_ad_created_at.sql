@@ -0,0 +1,3 @@
+ALTER TABLE ads ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS ad_click_history_ad_time_idx ON ad_click_history (ad_id, timestamp);
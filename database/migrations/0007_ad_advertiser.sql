@@ -0,0 +1 @@
+ALTER TABLE ads ADD COLUMN IF NOT EXISTS advertiser VARCHAR(100) NOT NULL DEFAULT '';
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS ad_impressions (
+    recommendation_id VARCHAR(64) NOT NULL,
+    ad_id VARCHAR(50) NOT NULL,
+    user_id VARCHAR(50) NOT NULL,
+    category VARCHAR(50) NOT NULL DEFAULT '',
+    segment VARCHAR(50) NOT NULL DEFAULT '',
+    position INTEGER NOT NULL,
+    timestamp TIMESTAMPTZ NOT NULL,
+    clicked_at TIMESTAMPTZ,
+    PRIMARY KEY (recommendation_id, ad_id)
+);
+
+CREATE INDEX IF NOT EXISTS ad_impressions_timestamp_idx ON ad_impressions (timestamp);
+
+ALTER TABLE ad_click_history ADD COLUMN IF NOT EXISTS recommendation_id VARCHAR(64);
@@ -0,0 +1,10 @@
+ALTER TABLE ad_impressions ADD COLUMN IF NOT EXISTS movie_category VARCHAR(50) NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS ctr_counts (
+    kind VARCHAR(10) NOT NULL,
+    key VARCHAR(110) NOT NULL,
+    impressions BIGINT NOT NULL DEFAULT 0,
+    clicks BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (kind, key)
+);
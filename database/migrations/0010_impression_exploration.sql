@@ -0,0 +1 @@
+ALTER TABLE ad_impressions ADD COLUMN IF NOT EXISTS explored BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,5 @@
+ALTER TABLE ad_impressions ADD COLUMN IF NOT EXISTS experiment VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE ad_impressions ADD COLUMN IF NOT EXISTS variant VARCHAR(100) NOT NULL DEFAULT '';
+
+ALTER TABLE ad_click_history ADD COLUMN IF NOT EXISTS experiment VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE ad_click_history ADD COLUMN IF NOT EXISTS variant VARCHAR(100) NOT NULL DEFAULT '';
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS cf_runs (
+    run_id VARCHAR(40) PRIMARY KEY,
+    trained_at TIMESTAMPTZ NOT NULL,
+    items INTEGER NOT NULL,
+    published BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS cf_items (
+    run_id VARCHAR(40) NOT NULL REFERENCES cf_runs (run_id) ON DELETE CASCADE,
+    kind VARCHAR(10) NOT NULL,
+    id VARCHAR(100) NOT NULL,
+    factors REAL[] NOT NULL,
+    similar JSONB NOT NULL DEFAULT '{}',
+    PRIMARY KEY (run_id, kind, id)
+);
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"log"
+	"sync"
+)
+
+// CapacityRecorder accumulates the DynamoDB capacity units consumed per operation
+type CapacityRecorder struct {
+	mu    sync.Mutex
+	units map[string]float64
+}
+
+// NewCapacityRecorder creates an empty CapacityRecorder
+func NewCapacityRecorder() *CapacityRecorder {
+	return &CapacityRecorder{units: make(map[string]float64)}
+}
+
+// Record logs the capacity consumed by one operation and adds it to the running total
+func (c *CapacityRecorder) Record(operation string, units float64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.units[operation] += units
+	total := c.units[operation]
+	c.mu.Unlock()
+
+	log.Printf("📈 DynamoDB %s consumed %.2f capacity units (total %.2f)", operation, units, total)
+}
+
+// Snapshot returns the total capacity units consumed per operation so far
+func (c *CapacityRecorder) Snapshot() map[string]float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	snapshot := make(map[string]float64, len(c.units))
+	for operation, units := range c.units {
+		snapshot[operation] = units
+	}
+	return snapshot
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
 	"time"
@@ -10,12 +11,11 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
-var DynamoClient *dynamodb.Client
-
-// InitDynamoDB initializes the DynamoDB client
-func InitDynamoDB() {
+// InitDynamoDB initializes the DynamoDB client and ensures the required tables exist
+func InitDynamoDB() (*dynamodb.Client, error) {
 	// Load the AWS configuration
 	cfg, err := config.LoadDefaultConfig(context.TODO(),
 		config.WithRegion(os.Getenv("AWS_REGION")),
@@ -26,30 +26,39 @@ func InitDynamoDB() {
 		)),
 	)
 	if err != nil {
-		log.Fatalf("Failed to load AWS configuration: %v", err)
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
 	}
 
 	// Create the DynamoDB client
-	DynamoClient = dynamodb.NewFromConfig(cfg)
+	client := dynamodb.NewFromConfig(cfg)
 	log.Println("DynamoDB client initialized")
 
 	// Ensure tables exist
-	if err := EnsureTables(); err != nil {
-		log.Fatalf("Failed to ensure tables exist: %v", err)
+	if err := EnsureTables(client); err != nil {
+		return nil, fmt.Errorf("failed to ensure tables exist: %w", err)
 	}
+
+	return client, nil
 }
 
-// WaitUntilTableActive waits until a DynamoDB table becomes active
-func WaitUntilTableActive(tableName string) error {
+// WaitUntilTableActive waits until a DynamoDB table and all of its global secondary indexes become active
+func WaitUntilTableActive(client *dynamodb.Client, tableName string) error {
 	for {
-		describeOutput, err := DynamoClient.DescribeTable(context.TODO(), &dynamodb.DescribeTableInput{
+		describeOutput, err := client.DescribeTable(context.TODO(), &dynamodb.DescribeTableInput{
 			TableName: aws.String(tableName),
 		})
 		if err != nil {
 			return err
 		}
 
-		if describeOutput.Table.TableStatus == "ACTIVE" {
+		indexesActive := true
+		for _, index := range describeOutput.Table.GlobalSecondaryIndexes {
+			if index.IndexStatus != types.IndexStatusActive {
+				indexesActive = false
+			}
+		}
+
+		if describeOutput.Table.TableStatus == types.TableStatusActive && indexesActive {
 			log.Printf("Table %s is active", tableName)
 			return nil
 		}
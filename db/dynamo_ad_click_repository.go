@@ -0,0 +1,171 @@
+package db
+
+import (
+	"Ad-Recommendations/models"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DynamoAdClickRepository stores ad clicks in the DynamoDB AdClickEvents table
+type DynamoAdClickRepository struct {
+	Client *dynamodb.Client
+}
+
+// LogAdClick appends an ad click event
+func (r *DynamoAdClickRepository) LogAdClick(ctx context.Context, click models.AdClick) error {
+	if click.EventID == "" {
+		click.EventID = NewEventID(click.Timestamp)
+	}
+
+	_, err := r.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(AdClickTableName),
+		Item:      adClickToItem(click),
+	})
+	return err
+}
+
+// AdClickHistory returns a user's ad clicks matching the query, oldest first
+func (r *DynamoAdClickRepository) AdClickHistory(ctx context.Context, userID string, query HistoryQuery) ([]models.AdClick, string, error) {
+	items, next, err := queryEventHistory(ctx, r.Client, AdClickTableName, userID, query)
+	if err != nil {
+		return nil, "", err
+	}
+
+	clicks := []models.AdClick{}
+	for _, item := range items {
+		clicks = append(clicks, adClickFromItem(item))
+	}
+	slices.Reverse(clicks)
+	return clicks, next, nil
+}
+
+// ClicksSince queries the day-event-index for the ad clicks of every user since a time, oldest first
+func (r *DynamoAdClickRepository) ClicksSince(ctx context.Context, since time.Time) ([]models.AdClick, error) {
+	items, err := adClickDayIndex.querySince(ctx, r.Client, since, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	clicks := make([]models.AdClick, len(items))
+	for i, item := range items {
+		clicks[i] = adClickFromItem(item)
+	}
+	return clicks, nil
+}
+
+// maxConcurrentClickCounts bounds the parallel per-ad count queries of one AdClickCounts call
+const maxConcurrentClickCounts = 8
+
+// AdClickCounts counts the clicks on each ad since a time through the ad-event-index
+func (r *DynamoAdClickRepository) AdClickCounts(ctx context.Context, adIDs []string, since time.Time) (map[string]int, error) {
+	counts := make([]int, len(adIDs))
+	errs := make(chan error, len(adIDs))
+	semaphore := make(chan struct{}, maxConcurrentClickCounts)
+	var wg sync.WaitGroup
+	for i, adID := range adIDs {
+		wg.Add(1)
+		go func(i int, adID string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			count, err := r.countClicks(ctx, adID, since)
+			if err != nil {
+				errs <- err
+				return
+			}
+			counts[i] = count
+		}(i, adID)
+	}
+	wg.Wait()
+	close(errs)
+
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+	result := make(map[string]int, len(adIDs))
+	for i, adID := range adIDs {
+		if counts[i] > 0 {
+			result[adID] = counts[i]
+		}
+	}
+	return result, nil
+}
+
+// countClicks counts one ad's clicks since a time, following every page of the count query
+func (r *DynamoAdClickRepository) countClicks(ctx context.Context, adID string, since time.Time) (int, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(AdClickTableName),
+		IndexName:              aws.String(AdClickAdIndexName),
+		KeyConditionExpression: aws.String("ad_id = :adID AND event_id >= :since"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":adID":  &types.AttributeValueMemberS{Value: adID},
+			":since": &types.AttributeValueMemberS{Value: eventSinceKey(since)},
+		},
+		Select: types.SelectCount,
+	}
+
+	total := 0
+	for {
+		output, err := r.Client.Query(ctx, input)
+		if err != nil {
+			return 0, err
+		}
+		total += int(output.Count)
+		if len(output.LastEvaluatedKey) == 0 {
+			return total, nil
+		}
+		input.ExclusiveStartKey = output.LastEvaluatedKey
+	}
+}
+
+// adClickToItem converts an AdClick to an AdClickEvents item
+func adClickToItem(click models.AdClick) map[string]types.AttributeValue {
+	item := map[string]types.AttributeValue{
+		"user_id":   &types.AttributeValueMemberS{Value: click.UserID},
+		"event_id":  &types.AttributeValueMemberS{Value: click.EventID},
+		"ad_id":     &types.AttributeValueMemberS{Value: click.AdID},
+		"timestamp": &types.AttributeValueMemberS{Value: click.Timestamp.UTC().Format(time.RFC3339Nano)},
+		"day":       &types.AttributeValueMemberS{Value: eventDay(click.Timestamp)},
+	}
+	if click.RecommendationID != "" {
+		item["recommendation_id"] = &types.AttributeValueMemberS{Value: click.RecommendationID}
+	}
+	if click.Experiment != "" {
+		item["experiment"] = &types.AttributeValueMemberS{Value: click.Experiment}
+		item["variant"] = &types.AttributeValueMemberS{Value: click.Variant}
+	}
+	return item
+}
+
+// adClickFromItem converts an AdClickEvents item to an AdClick
+func adClickFromItem(item map[string]types.AttributeValue) models.AdClick {
+	click := models.AdClick{}
+	if eventID, ok := item["event_id"].(*types.AttributeValueMemberS); ok {
+		click.EventID = eventID.Value
+	}
+	if userID, ok := item["user_id"].(*types.AttributeValueMemberS); ok {
+		click.UserID = userID.Value
+	}
+	if adID, ok := item["ad_id"].(*types.AttributeValueMemberS); ok {
+		click.AdID = adID.Value
+	}
+	if timestamp, ok := item["timestamp"].(*types.AttributeValueMemberS); ok {
+		click.Timestamp, _ = time.Parse(time.RFC3339Nano, timestamp.Value)
+	}
+	if recommendationID, ok := item["recommendation_id"].(*types.AttributeValueMemberS); ok {
+		click.RecommendationID = recommendationID.Value
+	}
+	if experiment, ok := item["experiment"].(*types.AttributeValueMemberS); ok {
+		click.Experiment = experiment.Value
+	}
+	if variant, ok := item["variant"].(*types.AttributeValueMemberS); ok {
+		click.Variant = variant.Value
+	}
+	return click
+}
@@ -0,0 +1,134 @@
+package db
+
+import (
+	"Ad-Recommendations/db/paginate"
+	"Ad-Recommendations/models"
+	"Ad-Recommendations/vector"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// batchGetLimit is the maximum number of keys DynamoDB accepts per BatchGetItem
+const batchGetLimit = 100
+
+// DynamoAdEmbeddingRepository stores ad embeddings in the DynamoDB AdEmbeddingTable,
+// with vectors packed as float32 by vector.Encode
+type DynamoAdEmbeddingRepository struct {
+	Client *dynamodb.Client
+}
+
+// PutAdEmbedding creates or replaces the embedding of an ad for its model
+func (r *DynamoAdEmbeddingRepository) PutAdEmbedding(ctx context.Context, embedding models.AdEmbedding) error {
+	_, err := r.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(AdEmbeddingTableName),
+		Item: map[string]types.AttributeValue{
+			"ad_id":         &types.AttributeValueMemberS{Value: embedding.AdID},
+			"model":         &types.AttributeValueMemberS{Value: embedding.Model},
+			"model_version": &types.AttributeValueMemberS{Value: embedding.ModelVersion},
+			"content_hash":  &types.AttributeValueMemberS{Value: embedding.ContentHash},
+			"vector":        &types.AttributeValueMemberB{Value: vector.Encode(embedding.Vector)},
+			"updated_at":    &types.AttributeValueMemberS{Value: embedding.UpdatedAt.UTC().Format(time.RFC3339)},
+		},
+	})
+	return err
+}
+
+// GetAdEmbeddings returns the stored embeddings of the given ads for a model
+func (r *DynamoAdEmbeddingRepository) GetAdEmbeddings(ctx context.Context, model string, adIDs []string) (map[string]models.AdEmbedding, error) {
+	found := make(map[string]models.AdEmbedding)
+
+	for start := 0; start < len(adIDs); start += batchGetLimit {
+		end := min(start+batchGetLimit, len(adIDs))
+
+		keys := []map[string]types.AttributeValue{}
+		seen := make(map[string]bool)
+		for _, adID := range adIDs[start:end] {
+			if seen[adID] {
+				continue
+			}
+			seen[adID] = true
+			keys = append(keys, map[string]types.AttributeValue{
+				"ad_id": &types.AttributeValueMemberS{Value: adID},
+				"model": &types.AttributeValueMemberS{Value: model},
+			})
+		}
+
+		request := map[string]types.KeysAndAttributes{AdEmbeddingTableName: {Keys: keys}}
+		for len(request) > 0 {
+			output, err := r.Client.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{RequestItems: request})
+			if err != nil {
+				return nil, err
+			}
+			for _, item := range output.Responses[AdEmbeddingTableName] {
+				embedding, err := adEmbeddingFromItem(item)
+				if err != nil {
+					return nil, err
+				}
+				found[embedding.AdID] = embedding
+			}
+			// Throttled keys come back unprocessed and are retried
+			request = output.UnprocessedKeys
+		}
+	}
+
+	return found, nil
+}
+
+// DeleteAdEmbeddings removes the embeddings of an ad for every model
+func (r *DynamoAdEmbeddingRepository) DeleteAdEmbeddings(ctx context.Context, adID string) error {
+	page, err := paginate.Query(ctx, r.Client, &dynamodb.QueryInput{
+		TableName:              aws.String(AdEmbeddingTableName),
+		KeyConditionExpression: aws.String("ad_id = :adID"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":adID": &types.AttributeValueMemberS{Value: adID},
+		},
+		ProjectionExpression: aws.String("ad_id, model"),
+	}, 0)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range page.Items {
+		_, err := r.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+			TableName: aws.String(AdEmbeddingTableName),
+			Key:       map[string]types.AttributeValue{"ad_id": item["ad_id"], "model": item["model"]},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// adEmbeddingFromItem converts an AdEmbeddingTable item to an AdEmbedding
+func adEmbeddingFromItem(item map[string]types.AttributeValue) (models.AdEmbedding, error) {
+	embedding := models.AdEmbedding{}
+	if adID, ok := item["ad_id"].(*types.AttributeValueMemberS); ok {
+		embedding.AdID = adID.Value
+	}
+	if model, ok := item["model"].(*types.AttributeValueMemberS); ok {
+		embedding.Model = model.Value
+	}
+	if version, ok := item["model_version"].(*types.AttributeValueMemberS); ok {
+		embedding.ModelVersion = version.Value
+	}
+	if hash, ok := item["content_hash"].(*types.AttributeValueMemberS); ok {
+		embedding.ContentHash = hash.Value
+	}
+	if updatedAt, ok := item["updated_at"].(*types.AttributeValueMemberS); ok {
+		embedding.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt.Value)
+	}
+	if encoded, ok := item["vector"].(*types.AttributeValueMemberB); ok {
+		decoded, err := vector.Decode(encoded.Value)
+		if err != nil {
+			return embedding, fmt.Errorf("invalid vector for ad %s: %w", embedding.AdID, err)
+		}
+		embedding.Vector = decoded
+	}
+	return embedding, nil
+}
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"Ad-Recommendations/db/paginate"
+	"Ad-Recommendations/models"
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DynamoAdRepository stores ads in the DynamoDB AdTable
+type DynamoAdRepository struct {
+	Client   *dynamodb.Client
+	Capacity *CapacityRecorder // Optional; records capacity used by category reads
+}
+
+// PutAd creates or fully replaces an ad
+func (r *DynamoAdRepository) PutAd(ctx context.Context, ad models.Ad) error {
+	_, err := r.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(AdTableName),
+		Item:      ad.ToDynamoDBItem(),
+	})
+	return err
+}
+
+// GetAd retrieves an ad by ID
+func (r *DynamoAdRepository) GetAd(ctx context.Context, adID string) (*models.Ad, error) {
+	output, err := r.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(AdTableName),
+		Key: map[string]types.AttributeValue{
+			"ad_id": &types.AttributeValueMemberS{Value: adID},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if output.Item == nil {
+		return nil, ErrNotFound
+	}
+
+	ad := models.AdFromDynamoDBItem(output.Item)
+	return &ad, nil
+}
+
+// DeleteAd removes an ad by ID
+func (r *DynamoAdRepository) DeleteAd(ctx context.Context, adID string) error {
+	_, err := r.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(AdTableName),
+		Key: map[string]types.AttributeValue{
+			"ad_id": &types.AttributeValueMemberS{Value: adID},
+		},
+	})
+	return err
+}
+
+// ListAds returns every ad in the table
+func (r *DynamoAdRepository) ListAds(ctx context.Context) ([]models.Ad, error) {
+	return models.FetchAllAds(ctx, r.Client, AdTableName)
+}
+
+// AdsByCategory returns the active ads of one category from the category-status index
+func (r *DynamoAdRepository) AdsByCategory(ctx context.Context, category string) ([]models.Ad, error) {
+	page, err := paginate.Query(ctx, r.Client, &dynamodb.QueryInput{
+		TableName:              aws.String(AdTableName),
+		IndexName:              aws.String(AdCategoryIndexName),
+		KeyConditionExpression: aws.String("category = :category AND #status = :status"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status", // STATUS is a DynamoDB reserved word
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":category": &types.AttributeValueMemberS{Value: category},
+			":status":   &types.AttributeValueMemberS{Value: models.AdStatusActive},
+		},
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+	r.Capacity.Record("Query "+AdCategoryIndexName, page.ConsumedCapacity)
+
+	ads := []models.Ad{}
+	for _, item := range page.Items {
+		ads = append(ads, models.AdFromDynamoDBItem(item))
+	}
+	return ads, nil
+}
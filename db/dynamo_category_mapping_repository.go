@@ -0,0 +1,69 @@
+package db
+
+import (
+	"Ad-Recommendations/models"
+	"context"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DynamoCategoryMappingRepository stores category mappings in the DynamoDB CategoryMappingTable
+type DynamoCategoryMappingRepository struct {
+	Client *dynamodb.Client
+}
+
+// PutCategoryMapping creates or fully replaces a category mapping
+func (r *DynamoCategoryMappingRepository) PutCategoryMapping(ctx context.Context, mapping models.CategoryMapping) error {
+	item := map[string]types.AttributeValue{
+		"movie_category": &types.AttributeValueMemberS{Value: mapping.MovieCategory},
+		"weight":         &types.AttributeValueMemberN{Value: strconv.FormatFloat(mapping.Weight, 'f', -1, 64)},
+	}
+	if len(mapping.AdCategories) > 0 {
+		item["ad_categories"] = &types.AttributeValueMemberSS{Value: mapping.AdCategories}
+	}
+
+	_, err := r.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(CategoryMappingTableName),
+		Item:      item,
+	})
+	return err
+}
+
+// GetCategoryMapping retrieves the mapping for a movie category
+func (r *DynamoCategoryMappingRepository) GetCategoryMapping(ctx context.Context, movieCategory string) (*models.CategoryMapping, error) {
+	output, err := r.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(CategoryMappingTableName),
+		Key: map[string]types.AttributeValue{
+			"movie_category": &types.AttributeValueMemberS{Value: movieCategory},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(output.Item) == 0 {
+		return nil, ErrNotFound
+	}
+
+	mapping := &models.CategoryMapping{
+		MovieCategory: movieCategory,
+		Weight:        models.DefaultCategoryWeight,
+	}
+
+	// Extract mapped categories
+	if categoriesAttr, ok := output.Item["ad_categories"].(*types.AttributeValueMemberSS); ok {
+		mapping.AdCategories = categoriesAttr.Value
+	}
+
+	// Extract weight if available
+	if weightAttr, ok := output.Item["weight"].(*types.AttributeValueMemberN); ok {
+		if weightValue, err := strconv.ParseFloat(weightAttr.Value, 64); err == nil {
+			mapping.Weight = weightValue
+		}
+	}
+
+	return mapping, nil
+}
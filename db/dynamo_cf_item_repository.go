@@ -0,0 +1,209 @@
+package db
+
+import (
+	"Ad-Recommendations/db/paginate"
+	"Ad-Recommendations/models"
+	"Ad-Recommendations/vector"
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DynamoCFItemRepository stores collaborative filtering runs in the DynamoDB
+// CFRuns table and their items in the CFRunItems table, with factors packed
+// as float32 by vector.Encode. The run published last is also copied to the
+// CFRuns item keyed cfCurrentRunKey, read by CurrentCFRun.
+type DynamoCFItemRepository struct {
+	Client *dynamodb.Client
+}
+
+// cfCurrentRunKey is the run_id of the pointer to the current run in CFRuns
+const cfCurrentRunKey = "current"
+
+// PutCFRun creates or replaces a run by ID, then points the current run at
+// it if it is published
+func (r *DynamoCFItemRepository) PutCFRun(ctx context.Context, run models.CFRun) error {
+	_, err := r.Client.PutItem(ctx, &dynamodb.PutItemInput{TableName: aws.String(CFRunTableName), Item: cfRunToItem(run.ID, run)})
+	if err != nil || !run.Published {
+		return err
+	}
+	_, err = r.Client.PutItem(ctx, &dynamodb.PutItemInput{TableName: aws.String(CFRunTableName), Item: cfRunToItem(cfCurrentRunKey, run)})
+	return err
+}
+
+// CurrentCFRun gets the run the current run points at
+func (r *DynamoCFItemRepository) CurrentCFRun(ctx context.Context) (*models.CFRun, error) {
+	output, err := r.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(CFRunTableName),
+		Key:            map[string]types.AttributeValue{"run_id": &types.AttributeValueMemberS{Value: cfCurrentRunKey}},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if output.Item == nil {
+		return nil, ErrNotFound
+	}
+	run := cfRunFromItem(output.Item)
+	return &run, nil
+}
+
+// ListCFRuns scans every stored run, leaving out the current run pointer
+func (r *DynamoCFItemRepository) ListCFRuns(ctx context.Context) ([]models.CFRun, error) {
+	page, err := paginate.Scan(ctx, r.Client, &dynamodb.ScanInput{TableName: aws.String(CFRunTableName)}, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	runs := make([]models.CFRun, 0, len(page.Items))
+	for _, item := range page.Items {
+		if key, ok := item["run_id"].(*types.AttributeValueMemberS); ok && key.Value == cfCurrentRunKey {
+			continue
+		}
+		runs = append(runs, cfRunFromItem(item))
+	}
+	return runs, nil
+}
+
+// cfRunToItem converts a CFRun to a CFRuns item keyed by key, the run ID or cfCurrentRunKey
+func cfRunToItem(key string, run models.CFRun) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"run_id":     &types.AttributeValueMemberS{Value: key},
+		"id":         &types.AttributeValueMemberS{Value: run.ID},
+		"trained_at": &types.AttributeValueMemberS{Value: run.TrainedAt.UTC().Format(time.RFC3339Nano)},
+		"items":      &types.AttributeValueMemberN{Value: strconv.Itoa(run.Items)},
+		"published":  &types.AttributeValueMemberBOOL{Value: run.Published},
+	}
+}
+
+// cfRunFromItem converts a CFRuns item to a CFRun
+func cfRunFromItem(item map[string]types.AttributeValue) models.CFRun {
+	run := models.CFRun{}
+	if id, ok := item["id"].(*types.AttributeValueMemberS); ok {
+		run.ID = id.Value
+	}
+	if trainedAt, ok := item["trained_at"].(*types.AttributeValueMemberS); ok {
+		run.TrainedAt, _ = time.Parse(time.RFC3339Nano, trainedAt.Value)
+	}
+	if items, ok := item["items"].(*types.AttributeValueMemberN); ok {
+		run.Items, _ = strconv.Atoi(items.Value)
+	}
+	if published, ok := item["published"].(*types.AttributeValueMemberBOOL); ok {
+		run.Published = published.Value
+	}
+	return run
+}
+
+// PutCFItems creates or replaces items of a run in batches
+func (r *DynamoCFItemRepository) PutCFItems(ctx context.Context, runID string, items []models.CFItem) error {
+	converted := make([]map[string]types.AttributeValue, len(items))
+	for i, item := range items {
+		item.RunID = runID
+		converted[i] = cfItemToItem(item)
+	}
+	return batchPutItems(ctx, r.Client, CFItemTableName, converted)
+}
+
+// ListCFItems queries the items of a run
+func (r *DynamoCFItemRepository) ListCFItems(ctx context.Context, runID string) ([]models.CFItem, error) {
+	page, err := r.queryRun(ctx, runID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]models.CFItem, 0, len(page.Items))
+	for _, item := range page.Items {
+		cfItem, err := cfItemFromItem(item)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, cfItem)
+	}
+	return items, nil
+}
+
+// DeleteCFRun deletes the items of a run in batches, then the run
+func (r *DynamoCFItemRepository) DeleteCFRun(ctx context.Context, runID string) error {
+	page, err := r.queryRun(ctx, runID, "run_id, #item")
+	if err != nil {
+		return err
+	}
+
+	deletes := make([]types.WriteRequest, len(page.Items))
+	for i, key := range page.Items {
+		deletes[i] = types.WriteRequest{DeleteRequest: &types.DeleteRequest{Key: key}}
+	}
+	if err := batchWriteItems(ctx, r.Client, CFItemTableName, deletes); err != nil {
+		return err
+	}
+
+	_, err = r.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(CFRunTableName),
+		Key:       map[string]types.AttributeValue{"run_id": &types.AttributeValueMemberS{Value: runID}},
+	})
+	return err
+}
+
+// queryRun reads the items of a run, only the projected attributes if a projection is given
+func (r *DynamoCFItemRepository) queryRun(ctx context.Context, runID, projection string) (paginate.Page, error) {
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(CFItemTableName),
+		KeyConditionExpression:    aws.String("run_id = :run"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{":run": &types.AttributeValueMemberS{Value: runID}},
+	}
+	if projection != "" {
+		input.ProjectionExpression = aws.String(projection)
+		input.ExpressionAttributeNames = map[string]string{"#item": "item"}
+	}
+	return paginate.Query(ctx, r.Client, input, 0)
+}
+
+// cfItemToItem converts a CFItem to a CFRunItems item, keyed by run and by kind and ID
+func cfItemToItem(item models.CFItem) map[string]types.AttributeValue {
+	similar := make(map[string]types.AttributeValue, len(item.Similar))
+	for adID, score := range item.Similar {
+		similar[adID] = &types.AttributeValueMemberN{Value: strconv.FormatFloat(score, 'g', -1, 64)}
+	}
+	return map[string]types.AttributeValue{
+		"run_id":  &types.AttributeValueMemberS{Value: item.RunID},
+		"item":    &types.AttributeValueMemberS{Value: item.Kind + "#" + item.ID},
+		"kind":    &types.AttributeValueMemberS{Value: item.Kind},
+		"id":      &types.AttributeValueMemberS{Value: item.ID},
+		"factors": &types.AttributeValueMemberB{Value: vector.Encode(item.Factors)},
+		"similar": &types.AttributeValueMemberM{Value: similar},
+	}
+}
+
+// cfItemFromItem converts a CFRunItems item to a CFItem
+func cfItemFromItem(item map[string]types.AttributeValue) (models.CFItem, error) {
+	cfItem := models.CFItem{}
+	if runID, ok := item["run_id"].(*types.AttributeValueMemberS); ok {
+		cfItem.RunID = runID.Value
+	}
+	if kind, ok := item["kind"].(*types.AttributeValueMemberS); ok {
+		cfItem.Kind = kind.Value
+	}
+	if id, ok := item["id"].(*types.AttributeValueMemberS); ok {
+		cfItem.ID = id.Value
+	}
+	if factors, ok := item["factors"].(*types.AttributeValueMemberB); ok {
+		decoded, err := vector.Decode(factors.Value)
+		if err != nil {
+			return cfItem, err
+		}
+		cfItem.Factors = decoded
+	}
+	if similar, ok := item["similar"].(*types.AttributeValueMemberM); ok {
+		cfItem.Similar = make(map[string]float64, len(similar.Value))
+		for adID, value := range similar.Value {
+			if score, ok := value.(*types.AttributeValueMemberN); ok {
+				cfItem.Similar[adID], _ = strconv.ParseFloat(score.Value, 64)
+			}
+		}
+	}
+	return cfItem, nil
+}
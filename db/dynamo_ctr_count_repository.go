@@ -0,0 +1,149 @@
+package db
+
+import (
+	"Ad-Recommendations/db/paginate"
+	"Ad-Recommendations/models"
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// batchWriteLimit is the maximum number of items DynamoDB accepts per BatchWriteItem
+const batchWriteLimit = 25
+
+// Unprocessed items of a batch write are resent after a jittered delay of up
+// to unprocessedBackoff * 2^(attempt-1), capped at maxUnprocessedBackoff, for
+// at most maxBatchWriteAttempts writes per batch
+const (
+	maxBatchWriteAttempts = 8
+	unprocessedBackoff    = 50 * time.Millisecond
+	maxUnprocessedBackoff = 5 * time.Second
+)
+
+// DynamoCTRCountRepository stores CTR counts in the DynamoDB CTRCounts table
+type DynamoCTRCountRepository struct {
+	Client *dynamodb.Client
+}
+
+// AddCTRCounts adds counts one at a time with an atomic ADD, stopping at the first failure
+func (r *DynamoCTRCountRepository) AddCTRCounts(ctx context.Context, counts []models.CTRCount) (int, error) {
+	for i, count := range counts {
+		_, err := r.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName: aws.String(CTRCountTableName),
+			Key: map[string]types.AttributeValue{
+				"kind": &types.AttributeValueMemberS{Value: count.Kind},
+				"key":  &types.AttributeValueMemberS{Value: count.Key},
+			},
+			UpdateExpression: aws.String("ADD impressions :impressions, clicks :clicks SET updated_at = :updated_at"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":impressions": &types.AttributeValueMemberN{Value: strconv.Itoa(count.Impressions)},
+				":clicks":      &types.AttributeValueMemberN{Value: strconv.Itoa(count.Clicks)},
+				":updated_at":  &types.AttributeValueMemberS{Value: count.UpdatedAt.UTC().Format(time.RFC3339)},
+			},
+		})
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(counts), nil
+}
+
+// batchPutItems creates or replaces items of a table in batches of batchWriteLimit.
+// It fails when DynamoDB keeps throttling a batch.
+func batchPutItems(ctx context.Context, client *dynamodb.Client, tableName string, items []map[string]types.AttributeValue) error {
+	writes := make([]types.WriteRequest, len(items))
+	for i, item := range items {
+		writes[i] = types.WriteRequest{PutRequest: &types.PutRequest{Item: item}}
+	}
+	return batchWriteItems(ctx, client, tableName, writes)
+}
+
+// batchWriteItems sends the put or delete requests of a table in batches of
+// batchWriteLimit, resending unprocessed requests with backoff
+func batchWriteItems(ctx context.Context, client *dynamodb.Client, tableName string, writes []types.WriteRequest) error {
+	for start := 0; start < len(writes); start += batchWriteLimit {
+		end := min(start+batchWriteLimit, len(writes))
+
+		request := map[string][]types.WriteRequest{tableName: writes[start:end]}
+		for attempt := 1; len(request) > 0; attempt++ {
+			if attempt > maxBatchWriteAttempts {
+				return fmt.Errorf("%d items of %s still unprocessed after %d batch writes", len(request[tableName]), tableName, maxBatchWriteAttempts)
+			}
+			if attempt > 1 {
+				if err := sleepContext(ctx, unprocessedDelay(attempt-1)); err != nil {
+					return err
+				}
+			}
+
+			output, err := client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{RequestItems: request})
+			if err != nil {
+				return err
+			}
+			// Throttled items come back unprocessed and are retried
+			request = output.UnprocessedItems
+		}
+	}
+	return nil
+}
+
+// unprocessedDelay returns a random delay before the retry-th resend of unprocessed items
+func unprocessedDelay(retry int) time.Duration {
+	limit := unprocessedBackoff << (retry - 1)
+	if limit <= 0 || limit > maxUnprocessedBackoff {
+		limit = maxUnprocessedBackoff
+	}
+	return time.Duration(rand.Int63n(int64(limit) + 1))
+}
+
+// sleepContext waits for the duration or until the context is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// ListCTRCounts scans every stored count
+func (r *DynamoCTRCountRepository) ListCTRCounts(ctx context.Context) ([]models.CTRCount, error) {
+	page, err := paginate.Scan(ctx, r.Client, &dynamodb.ScanInput{TableName: aws.String(CTRCountTableName)}, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make([]models.CTRCount, 0, len(page.Items))
+	for _, item := range page.Items {
+		counts = append(counts, ctrCountFromItem(item))
+	}
+	return counts, nil
+}
+
+// ctrCountFromItem converts a CTRCounts item to a CTRCount
+func ctrCountFromItem(item map[string]types.AttributeValue) models.CTRCount {
+	count := models.CTRCount{}
+	if kind, ok := item["kind"].(*types.AttributeValueMemberS); ok {
+		count.Kind = kind.Value
+	}
+	if key, ok := item["key"].(*types.AttributeValueMemberS); ok {
+		count.Key = key.Value
+	}
+	if impressions, ok := item["impressions"].(*types.AttributeValueMemberN); ok {
+		count.Impressions, _ = strconv.Atoi(impressions.Value)
+	}
+	if clicks, ok := item["clicks"].(*types.AttributeValueMemberN); ok {
+		count.Clicks, _ = strconv.Atoi(clicks.Value)
+	}
+	if updatedAt, ok := item["updated_at"].(*types.AttributeValueMemberS); ok {
+		count.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt.Value)
+	}
+	return count
+}
@@ -0,0 +1,193 @@
+package db
+
+import (
+	"Ad-Recommendations/db/paginate"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// dayLayout formats the UTC day an item falls in, the hash key of the day indexes
+const dayLayout = "2006-01-02"
+
+// maxConcurrentDayQueries bounds the parallel per-day queries of one read
+const maxConcurrentDayQueries = 8
+
+// maxDayQueries bounds how many days one read may query, about a year
+const maxDayQueries = 400
+
+// eventDay returns the day attribute of an item written at t
+func eventDay(t time.Time) string {
+	return t.UTC().Format(dayLayout)
+}
+
+// dayIndexTable is a table with a day index: day as hash key and a
+// time-sortable attribute as range key
+type dayIndexTable struct {
+	Table    string
+	Index    string
+	RangeKey string   // Starts with the eventTimeKey of the item
+	Key      []string // Primary key attributes, used by the backfill
+}
+
+// impressionDayIndex reads the impressions of recent days
+var impressionDayIndex = dayIndexTable{Table: ImpressionTableName, Index: ImpressionDayIndexName, RangeKey: "time_key", Key: []string{"recommendation_id", "ad_id"}}
+
+// Event tables are read by day for training
+var (
+	playbackDayIndex = dayIndexTable{Table: PlaybackTableName, Index: EventDayIndexName, RangeKey: "event_id", Key: []string{"user_id", "event_id"}}
+	adClickDayIndex  = dayIndexTable{Table: AdClickTableName, Index: EventDayIndexName, RangeKey: "event_id", Key: []string{"user_id", "event_id"}}
+)
+
+// dayIndexTables lists every table with a day index
+var dayIndexTables = []dayIndexTable{impressionDayIndex, playbackDayIndex, adClickDayIndex}
+
+// querySince reads the items written since a time with one query per UTC day,
+// from the day of since to the day after now, so items of a slightly fast
+// clock are not missed. Items are returned in order of the range key. Reads
+// of more than maxDayQueries days, such as from a zero since, are rejected.
+func (d dayIndexTable) querySince(ctx context.Context, client *dynamodb.Client, since, now time.Time) ([]map[string]types.AttributeValue, error) {
+	return d.queryConfiguredSince(ctx, client, since, now, nil)
+}
+
+// queryConfiguredSince is querySince with a filter or projection added to
+// every query by configure, when set
+func (d dayIndexTable) queryConfiguredSince(ctx context.Context, client *dynamodb.Client, since, now time.Time, configure func(*dynamodb.QueryInput)) ([]map[string]types.AttributeValue, error) {
+	first, last := since.UTC().Truncate(24*time.Hour), now.UTC().Add(24*time.Hour)
+	if last.Sub(first) > maxDayQueries*24*time.Hour {
+		return nil, fmt.Errorf("reading %s since %s queries more than %d days", d.Table, since.Format(time.RFC3339), maxDayQueries)
+	}
+
+	days := []string{}
+	for day := first; !day.After(last); day = day.Add(24 * time.Hour) {
+		days = append(days, eventDay(day))
+	}
+
+	results := make([][]map[string]types.AttributeValue, len(days))
+	errs := make(chan error, len(days))
+	semaphore := make(chan struct{}, maxConcurrentDayQueries)
+	var wg sync.WaitGroup
+	for i, day := range days {
+		wg.Add(1)
+		go func(i int, day string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			input := &dynamodb.QueryInput{
+				TableName:                aws.String(d.Table),
+				IndexName:                aws.String(d.Index),
+				KeyConditionExpression:   aws.String("#day = :day AND #range >= :since"),
+				ExpressionAttributeNames: map[string]string{"#day": "day", "#range": d.RangeKey},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":day":   &types.AttributeValueMemberS{Value: day},
+					":since": &types.AttributeValueMemberS{Value: eventSinceKey(since)},
+				},
+			}
+			if configure != nil {
+				configure(input)
+			}
+			page, err := paginate.Query(ctx, client, input, 0)
+			if err != nil {
+				errs <- err
+				return
+			}
+			results[i] = page.Items
+		}(i, day)
+	}
+	wg.Wait()
+	close(errs)
+
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+	items := []map[string]types.AttributeValue{}
+	for _, dayItems := range results {
+		items = append(items, dayItems...)
+	}
+	return items, nil
+}
+
+// BackfillEventDays sets the day attribute on items written before the day
+// indexes existed, which adds them to the indexes. The day comes from the
+// item's timestamp.
+func BackfillEventDays(ctx context.Context, client *dynamodb.Client) (int, error) {
+	updated := 0
+	for _, table := range dayIndexTables {
+		count, err := table.backfill(ctx, client)
+		updated += count
+		if err != nil {
+			return updated, err
+		}
+	}
+	return updated, nil
+}
+
+// backfill sets the day attribute on the items of one table that lack it
+func (d dayIndexTable) backfill(ctx context.Context, client *dynamodb.Client) (int, error) {
+	updated := 0
+	var startKey map[string]types.AttributeValue
+
+	for {
+		page, err := paginate.Scan(ctx, client, &dynamodb.ScanInput{
+			TableName:                aws.String(d.Table),
+			FilterExpression:         aws.String("attribute_not_exists(#day)"),
+			ExpressionAttributeNames: map[string]string{"#day": "day"},
+			ExclusiveStartKey:        startKey,
+			Limit:                    aws.Int32(migrationPageSize),
+		}, migrationPageSize)
+		if err != nil {
+			return updated, fmt.Errorf("failed to scan %s: %w", d.Table, err)
+		}
+
+		for _, item := range page.Items {
+			key := make(map[string]types.AttributeValue, len(d.Key))
+			for _, name := range d.Key {
+				key[name] = item[name]
+			}
+			_, err := client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+				TableName:                aws.String(d.Table),
+				Key:                      key,
+				UpdateExpression:         aws.String("SET #day = :day"),
+				ConditionExpression:      aws.String("attribute_not_exists(#day)"),
+				ExpressionAttributeNames: map[string]string{"#day": "day"},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":day": &types.AttributeValueMemberS{Value: eventDay(itemTimestamp(item))},
+				},
+			})
+			var exists *types.ConditionalCheckFailedException
+			if errors.As(err, &exists) {
+				continue
+			}
+			if err != nil {
+				return updated, fmt.Errorf("failed to backfill day in %s: %w", d.Table, err)
+			}
+			updated++
+		}
+
+		if page.NextKey == nil {
+			break
+		}
+		startKey = page.NextKey
+	}
+
+	log.Printf("Backfilled day on %d items in %s", updated, d.Table)
+	return updated, nil
+}
+
+// itemTimestamp parses the RFC 3339 timestamp attribute of an item, zero if missing
+func itemTimestamp(item map[string]types.AttributeValue) time.Time {
+	if value, ok := item["timestamp"].(*types.AttributeValueMemberS); ok {
+		if t, err := time.Parse(time.RFC3339Nano, value.Value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
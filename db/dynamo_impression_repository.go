@@ -0,0 +1,199 @@
+package db
+
+import (
+	"Ad-Recommendations/models"
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DynamoImpressionRepository stores impressions in the DynamoDB AdImpressions table
+type DynamoImpressionRepository struct {
+	Client *dynamodb.Client
+}
+
+// LogImpressions stores served ads, keeping impressions already stored so a
+// repeated report does not reset their click
+func (r *DynamoImpressionRepository) LogImpressions(ctx context.Context, impressions []models.Impression) ([]models.Impression, error) {
+	stored := []models.Impression{}
+	for _, impression := range impressions {
+		_, err := r.Client.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName:           aws.String(ImpressionTableName),
+			Item:                impressionToItem(impression),
+			ConditionExpression: aws.String("attribute_not_exists(ad_id)"),
+		})
+		var exists *types.ConditionalCheckFailedException
+		if errors.As(err, &exists) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		stored = append(stored, impression)
+	}
+	return stored, nil
+}
+
+// GetImpression retrieves the impression of an ad in a recommendation
+func (r *DynamoImpressionRepository) GetImpression(ctx context.Context, recommendationID, adID string) (*models.Impression, error) {
+	output, err := r.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(ImpressionTableName),
+		Key:       impressionKeyItem(recommendationID, adID),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if output.Item == nil {
+		return nil, ErrNotFound
+	}
+
+	impression := impressionFromItem(output.Item)
+	return &impression, nil
+}
+
+// MarkClicked records the first click on an impression with a conditional update
+func (r *DynamoImpressionRepository) MarkClicked(ctx context.Context, recommendationID, adID string, at time.Time) (bool, error) {
+	_, err := r.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(ImpressionTableName),
+		Key:                 impressionKeyItem(recommendationID, adID),
+		UpdateExpression:    aws.String("SET clicked_at = :at"),
+		ConditionExpression: aws.String("attribute_exists(ad_id) AND attribute_not_exists(clicked_at)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":at": &types.AttributeValueMemberS{Value: at.UTC().Format(time.RFC3339Nano)},
+		},
+	})
+	var failed *types.ConditionalCheckFailedException
+	if errors.As(err, &failed) {
+		// Either the impression does not exist or it was already clicked
+		if _, err := r.GetImpression(ctx, recommendationID, adID); err != nil {
+			return false, err
+		}
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ImpressionsSince queries the day-time-index for the impressions served
+// since a time, oldest first
+func (r *DynamoImpressionRepository) ImpressionsSince(ctx context.Context, since time.Time) ([]models.Impression, error) {
+	items, err := impressionDayIndex.querySince(ctx, r.Client, since, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	impressions := make([]models.Impression, len(items))
+	for i, item := range items {
+		impressions[i] = impressionFromItem(item)
+	}
+	return impressions, nil
+}
+
+// CountVariantsSince queries the day-time-index for the experiment, variant
+// and click of the impressions of an experiment served since a time, and
+// counts them per experiment and variant
+func (r *DynamoImpressionRepository) CountVariantsSince(ctx context.Context, since time.Time) ([]models.VariantCount, error) {
+	items, err := impressionDayIndex.queryConfiguredSince(ctx, r.Client, since, time.Now(), func(input *dynamodb.QueryInput) {
+		input.FilterExpression = aws.String("attribute_exists(#experiment)")
+		input.ProjectionExpression = aws.String("#experiment, #variant, #clicked_at")
+		input.ExpressionAttributeNames["#experiment"] = "experiment"
+		input.ExpressionAttributeNames["#variant"] = "variant"
+		input.ExpressionAttributeNames["#clicked_at"] = "clicked_at"
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[[2]string]*models.VariantCount{}
+	for _, item := range items {
+		impression := impressionFromItem(item)
+		countVariant(counts, impression.Experiment, impression.Variant, impression.Clicked())
+	}
+	return variantCountList(counts), nil
+}
+
+// impressionKeyItem is the primary key of an AdImpressions item
+func impressionKeyItem(recommendationID, adID string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"recommendation_id": &types.AttributeValueMemberS{Value: recommendationID},
+		"ad_id":             &types.AttributeValueMemberS{Value: adID},
+	}
+}
+
+// impressionToItem converts an Impression to an AdImpressions item. day and
+// time_key hold the serve time in the form of the day-time-index.
+func impressionToItem(impression models.Impression) map[string]types.AttributeValue {
+	item := impressionKeyItem(impression.RecommendationID, impression.AdID)
+	item["user_id"] = &types.AttributeValueMemberS{Value: impression.UserID}
+	item["category"] = &types.AttributeValueMemberS{Value: impression.Category}
+	item["position"] = &types.AttributeValueMemberN{Value: strconv.Itoa(impression.Position)}
+	item["timestamp"] = &types.AttributeValueMemberS{Value: impression.Timestamp.UTC().Format(time.RFC3339Nano)}
+	item["day"] = &types.AttributeValueMemberS{Value: eventDay(impression.Timestamp)}
+	item["time_key"] = &types.AttributeValueMemberS{Value: eventTimeKey(impression.Timestamp)}
+	if impression.Segment != "" {
+		item["segment"] = &types.AttributeValueMemberS{Value: impression.Segment}
+	}
+	if impression.MovieCategory != "" {
+		item["movie_category"] = &types.AttributeValueMemberS{Value: impression.MovieCategory}
+	}
+	if impression.Explored {
+		item["explored"] = &types.AttributeValueMemberBOOL{Value: true}
+	}
+	if impression.Experiment != "" {
+		item["experiment"] = &types.AttributeValueMemberS{Value: impression.Experiment}
+		item["variant"] = &types.AttributeValueMemberS{Value: impression.Variant}
+	}
+	if impression.Clicked() {
+		item["clicked_at"] = &types.AttributeValueMemberS{Value: impression.ClickedAt.UTC().Format(time.RFC3339Nano)}
+	}
+	return item
+}
+
+// impressionFromItem converts an AdImpressions item to an Impression
+func impressionFromItem(item map[string]types.AttributeValue) models.Impression {
+	impression := models.Impression{}
+	if recommendationID, ok := item["recommendation_id"].(*types.AttributeValueMemberS); ok {
+		impression.RecommendationID = recommendationID.Value
+	}
+	if adID, ok := item["ad_id"].(*types.AttributeValueMemberS); ok {
+		impression.AdID = adID.Value
+	}
+	if userID, ok := item["user_id"].(*types.AttributeValueMemberS); ok {
+		impression.UserID = userID.Value
+	}
+	if category, ok := item["category"].(*types.AttributeValueMemberS); ok {
+		impression.Category = category.Value
+	}
+	if segment, ok := item["segment"].(*types.AttributeValueMemberS); ok {
+		impression.Segment = segment.Value
+	}
+	if movieCategory, ok := item["movie_category"].(*types.AttributeValueMemberS); ok {
+		impression.MovieCategory = movieCategory.Value
+	}
+	if position, ok := item["position"].(*types.AttributeValueMemberN); ok {
+		impression.Position, _ = strconv.Atoi(position.Value)
+	}
+	if explored, ok := item["explored"].(*types.AttributeValueMemberBOOL); ok {
+		impression.Explored = explored.Value
+	}
+	if experiment, ok := item["experiment"].(*types.AttributeValueMemberS); ok {
+		impression.Experiment = experiment.Value
+	}
+	if variant, ok := item["variant"].(*types.AttributeValueMemberS); ok {
+		impression.Variant = variant.Value
+	}
+	if timestamp, ok := item["timestamp"].(*types.AttributeValueMemberS); ok {
+		impression.Timestamp, _ = time.Parse(time.RFC3339Nano, timestamp.Value)
+	}
+	if clickedAt, ok := item["clicked_at"].(*types.AttributeValueMemberS); ok {
+		impression.ClickedAt, _ = time.Parse(time.RFC3339Nano, clickedAt.Value)
+	}
+	return impression
+}
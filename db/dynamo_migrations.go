@@ -0,0 +1,160 @@
+package db
+
+import (
+	"Ad-Recommendations/db/paginate"
+	"Ad-Recommendations/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// MigrateLegacyPlaybackTable copies rows from the user_id-keyed PlaybackTable
+// into PlaybackEvents. Legacy rows get a deterministic event ID, so running the
+// migration again does not duplicate them.
+func MigrateLegacyPlaybackTable(ctx context.Context, client *dynamodb.Client) (int, error) {
+	return migrateLegacyEvents(ctx, client, LegacyPlaybackTableName, PlaybackTableName,
+		func(item map[string]types.AttributeValue) (map[string]types.AttributeValue, bool) {
+			event := playbackEventFromItem(item)
+			if event.UserID == "" {
+				return nil, false
+			}
+			event.Timestamp = legacyTimestamp(event.Timestamp)
+			event.EventID = eventTimeKey(event.Timestamp) + "#legacy"
+			return playbackEventToItem(event), true
+		})
+}
+
+// MigrateLegacyAdClickTable copies rows from the (user_id, ad_id)-keyed
+// AdClickTable into AdClickEvents. Each legacy row holds the latest click of a
+// user on an ad, which becomes one event with a deterministic ID.
+func MigrateLegacyAdClickTable(ctx context.Context, client *dynamodb.Client) (int, error) {
+	return migrateLegacyEvents(ctx, client, LegacyAdClickTableName, AdClickTableName,
+		func(item map[string]types.AttributeValue) (map[string]types.AttributeValue, bool) {
+			click := adClickFromItem(item)
+			if click.UserID == "" || click.AdID == "" {
+				return nil, false
+			}
+			click.Timestamp = legacyTimestamp(click.Timestamp)
+			click.EventID = eventTimeKey(click.Timestamp) + "#legacy-" + click.AdID
+			return adClickToItem(click), true
+		})
+}
+
+// BackfillAdStatus marks ads written before statuses existed as active, which
+// adds them to the category-status index used for recommendation
+func BackfillAdStatus(ctx context.Context, client *dynamodb.Client) (int, error) {
+	updated := 0
+	var startKey map[string]types.AttributeValue
+
+	for {
+		page, err := paginate.Scan(ctx, client, &dynamodb.ScanInput{
+			TableName:                aws.String(AdTableName),
+			FilterExpression:         aws.String("attribute_not_exists(#status)"),
+			ExpressionAttributeNames: map[string]string{"#status": "status"},
+			ExclusiveStartKey:        startKey,
+			Limit:                    aws.Int32(migrationPageSize),
+		}, migrationPageSize)
+		if err != nil {
+			return updated, fmt.Errorf("failed to scan %s: %w", AdTableName, err)
+		}
+
+		for _, item := range page.Items {
+			_, err := client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+				TableName:                aws.String(AdTableName),
+				Key:                      map[string]types.AttributeValue{"ad_id": item["ad_id"]},
+				UpdateExpression:         aws.String("SET #status = :status"),
+				ConditionExpression:      aws.String("attribute_not_exists(#status)"),
+				ExpressionAttributeNames: map[string]string{"#status": "status"},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":status": &types.AttributeValueMemberS{Value: models.AdStatusActive},
+				},
+			})
+			var exists *types.ConditionalCheckFailedException
+			if errors.As(err, &exists) {
+				continue
+			}
+			if err != nil {
+				return updated, fmt.Errorf("failed to backfill ad status: %w", err)
+			}
+			updated++
+		}
+
+		if page.NextKey == nil {
+			break
+		}
+		startKey = page.NextKey
+	}
+
+	log.Printf("Backfilled status on %d ads in %s", updated, AdTableName)
+	return updated, nil
+}
+
+// migrationPageSize is how many legacy rows are read before they are copied
+const migrationPageSize = 500
+
+// legacyTimestamp places rows without a parseable timestamp at the Unix epoch
+func legacyTimestamp(t time.Time) time.Time {
+	if t.IsZero() {
+		return time.Unix(0, 0).UTC()
+	}
+	return t
+}
+
+// migrateLegacyEvents scans a legacy table and writes each converted item to the
+// event table unless an item with the same event ID is already there
+func migrateLegacyEvents(ctx context.Context, client *dynamodb.Client, legacyTable, table string,
+	convert func(map[string]types.AttributeValue) (map[string]types.AttributeValue, bool)) (int, error) {
+	migrated := 0
+	var startKey map[string]types.AttributeValue
+
+	for {
+		page, err := paginate.Scan(ctx, client, &dynamodb.ScanInput{
+			TableName:         aws.String(legacyTable),
+			ExclusiveStartKey: startKey,
+			Limit:             aws.Int32(migrationPageSize),
+		}, migrationPageSize)
+		var notFound *types.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			log.Printf("Legacy table %s does not exist, nothing to migrate", legacyTable)
+			return 0, nil
+		}
+		if err != nil {
+			return migrated, fmt.Errorf("failed to scan %s: %w", legacyTable, err)
+		}
+
+		for _, item := range page.Items {
+			converted, ok := convert(item)
+			if !ok {
+				continue
+			}
+
+			_, err := client.PutItem(ctx, &dynamodb.PutItemInput{
+				TableName:           aws.String(table),
+				Item:                converted,
+				ConditionExpression: aws.String("attribute_not_exists(event_id)"),
+			})
+			var exists *types.ConditionalCheckFailedException
+			if errors.As(err, &exists) {
+				continue
+			}
+			if err != nil {
+				return migrated, fmt.Errorf("failed to copy item to %s: %w", table, err)
+			}
+			migrated++
+		}
+
+		if page.NextKey == nil {
+			break
+		}
+		startKey = page.NextKey
+	}
+
+	log.Printf("Migrated %d rows from %s to %s", migrated, legacyTable, table)
+	return migrated, nil
+}
@@ -0,0 +1,131 @@
+package db
+
+import (
+	"Ad-Recommendations/db/paginate"
+	"Ad-Recommendations/models"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DynamoPlaybackRepository stores playback events in the DynamoDB PlaybackEvents table
+type DynamoPlaybackRepository struct {
+	Client *dynamodb.Client
+}
+
+// LogPlayback appends a playback event
+func (r *DynamoPlaybackRepository) LogPlayback(ctx context.Context, event models.PlaybackEvent) error {
+	if event.EventID == "" {
+		event.EventID = NewEventID(event.Timestamp)
+	}
+
+	_, err := r.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(PlaybackTableName),
+		Item:      playbackEventToItem(event),
+	})
+	return err
+}
+
+// PlaybackHistory returns a user's playback events matching the query, oldest first
+func (r *DynamoPlaybackRepository) PlaybackHistory(ctx context.Context, userID string, query HistoryQuery) ([]models.PlaybackEvent, string, error) {
+	items, next, err := queryEventHistory(ctx, r.Client, PlaybackTableName, userID, query)
+	if err != nil {
+		return nil, "", err
+	}
+
+	events := []models.PlaybackEvent{}
+	for _, item := range items {
+		events = append(events, playbackEventFromItem(item))
+	}
+	slices.Reverse(events)
+	return events, next, nil
+}
+
+// PlaybackSince queries the day-event-index for the playback events of every user since a time, oldest first
+func (r *DynamoPlaybackRepository) PlaybackSince(ctx context.Context, since time.Time) ([]models.PlaybackEvent, error) {
+	items, err := playbackDayIndex.querySince(ctx, r.Client, since, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	events := make([]models.PlaybackEvent, len(items))
+	for i, item := range items {
+		events[i] = playbackEventFromItem(item)
+	}
+	return events, nil
+}
+
+// queryEventHistory reads an event table keyed by user_id and a time-sortable
+// event_id newest first, following pages until the query limit is reached
+func queryEventHistory(ctx context.Context, client *dynamodb.Client, tableName, userID string, query HistoryQuery) ([]map[string]types.AttributeValue, string, error) {
+	keyCondition := "user_id = :userID"
+	values := map[string]types.AttributeValue{
+		":userID": &types.AttributeValueMemberS{Value: userID},
+	}
+
+	// Event IDs start with the zero-padded event time, so bare time keys bound them:
+	// "<since>" sorts before every event at since, "<until>" before every event at until
+	lower, upper := eventSinceKey(query.Since), "~"
+	if !query.Until.IsZero() {
+		upper = eventTimeKey(query.Until)
+	}
+	if lower != "0" || upper != "~" {
+		keyCondition += " AND event_id BETWEEN :lower AND :upper"
+		values[":lower"] = &types.AttributeValueMemberS{Value: lower}
+		values[":upper"] = &types.AttributeValueMemberS{Value: upper}
+	}
+
+	startKey, err := paginate.DecodeCursor(query.Cursor)
+	if err != nil {
+		return nil, "", ErrInvalidCursor
+	}
+
+	page, err := paginate.Query(ctx, client, &dynamodb.QueryInput{
+		TableName:                 aws.String(tableName),
+		KeyConditionExpression:    aws.String(keyCondition),
+		ExpressionAttributeValues: values,
+		ScanIndexForward:          aws.Bool(false),
+		ExclusiveStartKey:         startKey,
+	}, query.Limit)
+	if err != nil {
+		return nil, "", err
+	}
+
+	next, err := paginate.EncodeCursor(page.NextKey)
+	if err != nil {
+		return nil, "", err
+	}
+	return page.Items, next, nil
+}
+
+// playbackEventToItem converts a PlaybackEvent to a PlaybackEvents item
+func playbackEventToItem(event models.PlaybackEvent) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"user_id":   &types.AttributeValueMemberS{Value: event.UserID},
+		"event_id":  &types.AttributeValueMemberS{Value: event.EventID},
+		"category":  &types.AttributeValueMemberS{Value: event.Category},
+		"timestamp": &types.AttributeValueMemberS{Value: event.Timestamp.UTC().Format(time.RFC3339Nano)},
+		"day":       &types.AttributeValueMemberS{Value: eventDay(event.Timestamp)},
+	}
+}
+
+// playbackEventFromItem converts a PlaybackEvents item to a PlaybackEvent
+func playbackEventFromItem(item map[string]types.AttributeValue) models.PlaybackEvent {
+	event := models.PlaybackEvent{}
+	if eventID, ok := item["event_id"].(*types.AttributeValueMemberS); ok {
+		event.EventID = eventID.Value
+	}
+	if userID, ok := item["user_id"].(*types.AttributeValueMemberS); ok {
+		event.UserID = userID.Value
+	}
+	if category, ok := item["category"].(*types.AttributeValueMemberS); ok {
+		event.Category = category.Value
+	}
+	if timestamp, ok := item["timestamp"].(*types.AttributeValueMemberS); ok {
+		event.Timestamp, _ = time.Parse(time.RFC3339Nano, timestamp.Value)
+	}
+	return event
+}
@@ -0,0 +1,20 @@
+package db
+
+import "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+
+// NewDynamoStore builds a Store backed by the DynamoDB tables from EnsureTables
+func NewDynamoStore(client *dynamodb.Client) *Store {
+	capacity := NewCapacityRecorder()
+	return &Store{
+		Users:       &DynamoUserRepository{Client: client},
+		Playback:    &DynamoPlaybackRepository{Client: client},
+		Clicks:      &DynamoAdClickRepository{Client: client},
+		Impressions: &DynamoImpressionRepository{Client: client},
+		CTRCounts:   &DynamoCTRCountRepository{Client: client},
+		CFItems:     &DynamoCFItemRepository{Client: client},
+		Ads:         &DynamoAdRepository{Client: client, Capacity: capacity},
+		Mappings:    &DynamoCategoryMappingRepository{Client: client},
+		Embeddings:  &DynamoAdEmbeddingRepository{Client: client},
+		Capacity:    capacity,
+	}
+}
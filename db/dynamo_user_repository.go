@@ -0,0 +1,54 @@
+package db
+
+import (
+	"Ad-Recommendations/models"
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DynamoUserRepository stores users in the DynamoDB Users table
+type DynamoUserRepository struct {
+	Client *dynamodb.Client
+}
+
+// PutUser creates or fully replaces a user
+func (r *DynamoUserRepository) PutUser(ctx context.Context, user models.User) error {
+	_, err := r.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(UserTableName),
+		Item:      user.ToDynamoDBItem(),
+	})
+	return err
+}
+
+// GetUser retrieves a user by ID
+func (r *DynamoUserRepository) GetUser(ctx context.Context, userID string) (*models.User, error) {
+	output, err := r.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(UserTableName),
+		Key: map[string]types.AttributeValue{
+			"user_id": &types.AttributeValueMemberS{Value: userID},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if output.Item == nil {
+		return nil, ErrNotFound
+	}
+
+	return models.UserFromDynamoDBItem(output.Item), nil
+}
+
+// DeleteUser removes a user by ID
+func (r *DynamoUserRepository) DeleteUser(ctx context.Context, userID string) error {
+	_, err := r.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(UserTableName),
+		Key: map[string]types.AttributeValue{
+			"user_id": &types.AttributeValueMemberS{Value: userID},
+		},
+	})
+	return err
+}
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// HistoryQuery narrows a per-user event history.
+// Results are always returned oldest first; with Limit set they are the newest
+// Limit events inside the window. Reads with a Limit also return a cursor that,
+// passed back as Cursor, continues with the next older events.
+type HistoryQuery struct {
+	Since  time.Time // Inclusive lower bound, zero for unbounded
+	Until  time.Time // Exclusive upper bound, zero for unbounded
+	Limit  int       // Maximum number of events, 0 for all
+	Cursor string    // Opaque cursor from a previous page, empty to start at the newest event
+}
+
+// Contains reports whether a timestamp falls inside the query window
+func (q HistoryQuery) Contains(t time.Time) bool {
+	if !q.Since.IsZero() && t.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !t.Before(q.Until) {
+		return false
+	}
+	return true
+}
+
+// NewEventID returns an ID that sorts lexicographically by event time.
+// The random suffix keeps events logged in the same nanosecond distinct.
+func NewEventID(t time.Time) string {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		panic(err)
+	}
+	return eventTimeKey(t) + "#" + hex.EncodeToString(suffix)
+}
+
+// eventTimeKey is the zero-padded time prefix of an event ID, also usable as a range bound
+func eventTimeKey(t time.Time) string {
+	return fmt.Sprintf("%020d", t.UnixNano())
+}
+
+// eventSinceKey is the lower range bound of the event IDs since a time. A
+// zero time is out of range of UnixNano, so it bounds nothing: "0" sorts
+// before every time key.
+func eventSinceKey(since time.Time) string {
+	if since.IsZero() {
+		return "0"
+	}
+	return eventTimeKey(since)
+}
+
+// pageHistory applies a HistoryQuery to events ordered oldest first by event ID.
+// The cursor it returns is the event ID of the oldest event on the page.
+func pageHistory[T any](events []T, query HistoryQuery, timestamp func(T) time.Time, eventID func(T) string) ([]T, string) {
+	matched := []T{}
+	for _, event := range events {
+		if !query.Contains(timestamp(event)) {
+			continue
+		}
+		if query.Cursor != "" && eventID(event) >= query.Cursor {
+			continue
+		}
+		matched = append(matched, event)
+	}
+
+	if query.Limit <= 0 || len(matched) <= query.Limit {
+		return matched, ""
+	}
+	page := matched[len(matched)-query.Limit:]
+	return page, eventID(page[0])
+}
@@ -0,0 +1,150 @@
+package db
+
+import (
+	"Ad-Recommendations/models"
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Fixture is the seed data accepted by LoadFixture
+type Fixture struct {
+	Users            []models.User            `json:"users"`
+	Playback         []models.PlaybackEvent   `json:"playback"`
+	Clicks           []models.AdClick         `json:"clicks"`
+	Ads              []models.Ad              `json:"ads"`
+	CategoryMappings []models.CategoryMapping `json:"category_mappings"`
+}
+
+// LoadFixture seeds a store from a fixture file.
+//
+// A .jsonl file holds one record per line, tagged with a "type" of user,
+// playback, click, ad or category_mapping. Any other file is read as a single
+// Fixture JSON document.
+func LoadFixture(ctx context.Context, store *Store, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open fixture %s: %w", path, err)
+	}
+	defer file.Close()
+
+	fixture := Fixture{}
+	if strings.EqualFold(filepath.Ext(path), ".jsonl") {
+		fixture, err = decodeFixtureLines(file)
+	} else {
+		err = json.NewDecoder(file).Decode(&fixture)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse fixture %s: %w", path, err)
+	}
+
+	if err := fixture.Apply(ctx, store); err != nil {
+		return err
+	}
+
+	log.Printf("Seeded store from %s: %d users, %d playback events, %d clicks, %d ads, %d category mappings",
+		path, len(fixture.Users), len(fixture.Playback), len(fixture.Clicks), len(fixture.Ads), len(fixture.CategoryMappings))
+	return nil
+}
+
+// Apply writes every fixture record to the store
+func (f *Fixture) Apply(ctx context.Context, store *Store) error {
+	now := time.Now().UTC()
+
+	for _, user := range f.Users {
+		if err := store.Users.PutUser(ctx, user); err != nil {
+			return fmt.Errorf("failed to seed user %s: %w", user.UserID, err)
+		}
+	}
+	for _, ad := range f.Ads {
+		if err := store.Ads.PutAd(ctx, ad); err != nil {
+			return fmt.Errorf("failed to seed ad %s: %w", ad.AdID, err)
+		}
+	}
+	for _, mapping := range f.CategoryMappings {
+		if mapping.Weight == 0 {
+			mapping.Weight = models.DefaultCategoryWeight
+		}
+		if err := store.Mappings.PutCategoryMapping(ctx, mapping); err != nil {
+			return fmt.Errorf("failed to seed category mapping %s: %w", mapping.MovieCategory, err)
+		}
+	}
+	for _, event := range f.Playback {
+		if event.Timestamp.IsZero() {
+			event.Timestamp = now
+		}
+		if err := store.Playback.LogPlayback(ctx, event); err != nil {
+			return fmt.Errorf("failed to seed playback for %s: %w", event.UserID, err)
+		}
+	}
+	for _, click := range f.Clicks {
+		if click.Timestamp.IsZero() {
+			click.Timestamp = now
+		}
+		if err := store.Clicks.LogAdClick(ctx, click); err != nil {
+			return fmt.Errorf("failed to seed click for %s: %w", click.UserID, err)
+		}
+	}
+
+	return nil
+}
+
+// decodeFixtureLines reads a JSONL fixture with one typed record per line
+func decodeFixtureLines(file *os.File) (Fixture, error) {
+	fixture := Fixture{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var header struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal([]byte(line), &header); err != nil {
+			return fixture, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
+		var err error
+		switch header.Type {
+		case "user":
+			var user models.User
+			err = json.Unmarshal([]byte(line), &user)
+			fixture.Users = append(fixture.Users, user)
+		case "playback":
+			var event models.PlaybackEvent
+			err = json.Unmarshal([]byte(line), &event)
+			fixture.Playback = append(fixture.Playback, event)
+		case "click":
+			var click models.AdClick
+			err = json.Unmarshal([]byte(line), &click)
+			fixture.Clicks = append(fixture.Clicks, click)
+		case "ad":
+			var ad models.Ad
+			err = json.Unmarshal([]byte(line), &ad)
+			fixture.Ads = append(fixture.Ads, ad)
+		case "category_mapping":
+			var mapping models.CategoryMapping
+			err = json.Unmarshal([]byte(line), &mapping)
+			fixture.CategoryMappings = append(fixture.CategoryMappings, mapping)
+		default:
+			err = fmt.Errorf("unknown record type %q", header.Type)
+		}
+		if err != nil {
+			return fixture, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+	}
+
+	return fixture, scanner.Err()
+}
@@ -0,0 +1,529 @@
+package db
+
+import (
+	"Ad-Recommendations/models"
+	"Ad-Recommendations/vector"
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// NewMemoryStore builds a Store that keeps everything in process memory
+func NewMemoryStore() *Store {
+	return &Store{
+		Users:       &MemoryUserRepository{users: map[string]models.User{}},
+		Playback:    &MemoryPlaybackRepository{events: map[string][]models.PlaybackEvent{}},
+		Clicks:      &MemoryAdClickRepository{clicks: map[string][]models.AdClick{}},
+		Impressions: &MemoryImpressionRepository{impressions: map[impressionKey]models.Impression{}},
+		CTRCounts:   &MemoryCTRCountRepository{counts: map[[2]string]models.CTRCount{}},
+		CFItems:     &MemoryCFItemRepository{runs: map[string]models.CFRun{}, items: map[string]map[[2]string]models.CFItem{}},
+		Ads:         &MemoryAdRepository{ads: map[string]models.Ad{}},
+		Mappings:    &MemoryCategoryMappingRepository{mappings: map[string]models.CategoryMapping{}},
+		Embeddings:  &MemoryAdEmbeddingRepository{embeddings: map[string]map[string]memoryAdEmbedding{}},
+	}
+}
+
+// MemoryUserRepository keeps users in a map keyed by user ID
+type MemoryUserRepository struct {
+	mu    sync.RWMutex
+	users map[string]models.User
+}
+
+// PutUser creates or fully replaces a user
+func (r *MemoryUserRepository) PutUser(ctx context.Context, user models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user.History = append([]string(nil), user.History...)
+	r.users[user.UserID] = user
+	return nil
+}
+
+// GetUser retrieves a user by ID
+func (r *MemoryUserRepository) GetUser(ctx context.Context, userID string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	user, ok := r.users[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	user.History = append([]string{}, user.History...)
+	return &user, nil
+}
+
+// DeleteUser removes a user by ID
+func (r *MemoryUserRepository) DeleteUser(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.users, userID)
+	return nil
+}
+
+// MemoryPlaybackRepository keeps playback events per user, ordered by event ID (and so by time)
+type MemoryPlaybackRepository struct {
+	mu     sync.RWMutex
+	events map[string][]models.PlaybackEvent
+}
+
+// LogPlayback appends a playback event
+func (r *MemoryPlaybackRepository) LogPlayback(ctx context.Context, event models.PlaybackEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if event.EventID == "" {
+		event.EventID = NewEventID(event.Timestamp)
+	}
+	events := append(r.events[event.UserID], event)
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].EventID < events[j].EventID
+	})
+	r.events[event.UserID] = events
+	return nil
+}
+
+// PlaybackHistory returns a user's playback events matching the query, oldest first
+func (r *MemoryPlaybackRepository) PlaybackHistory(ctx context.Context, userID string, query HistoryQuery) ([]models.PlaybackEvent, string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	events, next := pageHistory(r.events[userID], query,
+		func(event models.PlaybackEvent) time.Time { return event.Timestamp },
+		func(event models.PlaybackEvent) string { return event.EventID })
+	return events, next, nil
+}
+
+// PlaybackSince returns the playback events of every user since a time, oldest first
+func (r *MemoryPlaybackRepository) PlaybackSince(ctx context.Context, since time.Time) ([]models.PlaybackEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	events := []models.PlaybackEvent{}
+	for _, userEvents := range r.events {
+		for _, event := range userEvents {
+			if !event.Timestamp.Before(since) {
+				events = append(events, event)
+			}
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].EventID < events[j].EventID })
+	return events, nil
+}
+
+// MemoryAdClickRepository keeps ad clicks per user, ordered by event ID (and so by time)
+type MemoryAdClickRepository struct {
+	mu     sync.RWMutex
+	clicks map[string][]models.AdClick
+}
+
+// LogAdClick appends an ad click event
+func (r *MemoryAdClickRepository) LogAdClick(ctx context.Context, click models.AdClick) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if click.EventID == "" {
+		click.EventID = NewEventID(click.Timestamp)
+	}
+	clicks := append(r.clicks[click.UserID], click)
+	sort.SliceStable(clicks, func(i, j int) bool {
+		return clicks[i].EventID < clicks[j].EventID
+	})
+	r.clicks[click.UserID] = clicks
+	return nil
+}
+
+// AdClickHistory returns a user's ad clicks matching the query, oldest first
+func (r *MemoryAdClickRepository) AdClickHistory(ctx context.Context, userID string, query HistoryQuery) ([]models.AdClick, string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	clicks, next := pageHistory(r.clicks[userID], query,
+		func(click models.AdClick) time.Time { return click.Timestamp },
+		func(click models.AdClick) string { return click.EventID })
+	return clicks, next, nil
+}
+
+// AdClickCounts returns the number of clicks on each ad since a time
+func (r *MemoryAdClickRepository) AdClickCounts(ctx context.Context, adIDs []string, since time.Time) (map[string]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	wanted := make(map[string]bool, len(adIDs))
+	for _, adID := range adIDs {
+		wanted[adID] = true
+	}
+	counts := map[string]int{}
+	for _, clicks := range r.clicks {
+		for _, click := range clicks {
+			if wanted[click.AdID] && !click.Timestamp.Before(since) {
+				counts[click.AdID]++
+			}
+		}
+	}
+	return counts, nil
+}
+
+// ClicksSince returns the ad clicks of every user since a time, oldest first
+func (r *MemoryAdClickRepository) ClicksSince(ctx context.Context, since time.Time) ([]models.AdClick, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	clicks := []models.AdClick{}
+	for _, userClicks := range r.clicks {
+		for _, click := range userClicks {
+			if !click.Timestamp.Before(since) {
+				clicks = append(clicks, click)
+			}
+		}
+	}
+	sort.Slice(clicks, func(i, j int) bool { return clicks[i].EventID < clicks[j].EventID })
+	return clicks, nil
+}
+
+// MemoryImpressionRepository keeps impressions keyed by recommendation and ad ID
+type MemoryImpressionRepository struct {
+	mu          sync.RWMutex
+	impressions map[impressionKey]models.Impression
+}
+
+// impressionKey identifies one ad of one served recommendation list
+type impressionKey struct {
+	recommendationID string
+	adID             string
+}
+
+// LogImpressions stores served ads, keeping impressions already stored
+func (r *MemoryImpressionRepository) LogImpressions(ctx context.Context, impressions []models.Impression) ([]models.Impression, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := []models.Impression{}
+	for _, impression := range impressions {
+		key := impressionKey{impression.RecommendationID, impression.AdID}
+		if _, ok := r.impressions[key]; !ok {
+			r.impressions[key] = impression
+			stored = append(stored, impression)
+		}
+	}
+	return stored, nil
+}
+
+// GetImpression retrieves the impression of an ad in a recommendation
+func (r *MemoryImpressionRepository) GetImpression(ctx context.Context, recommendationID, adID string) (*models.Impression, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	impression, ok := r.impressions[impressionKey{recommendationID, adID}]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &impression, nil
+}
+
+// MarkClicked records the first click on an impression
+func (r *MemoryImpressionRepository) MarkClicked(ctx context.Context, recommendationID, adID string, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := impressionKey{recommendationID, adID}
+	impression, ok := r.impressions[key]
+	if !ok {
+		return false, ErrNotFound
+	}
+	if impression.Clicked() {
+		return false, nil
+	}
+	impression.ClickedAt = at
+	r.impressions[key] = impression
+	return true, nil
+}
+
+// ImpressionsSince returns the impressions served since a time, oldest first
+func (r *MemoryImpressionRepository) ImpressionsSince(ctx context.Context, since time.Time) ([]models.Impression, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	impressions := []models.Impression{}
+	for _, impression := range r.impressions {
+		if !impression.Timestamp.Before(since) {
+			impressions = append(impressions, impression)
+		}
+	}
+	sort.Slice(impressions, func(i, j int) bool { return impressions[i].Timestamp.Before(impressions[j].Timestamp) })
+	return impressions, nil
+}
+
+// CountVariantsSince counts the impressions served since a time per experiment and variant
+func (r *MemoryImpressionRepository) CountVariantsSince(ctx context.Context, since time.Time) ([]models.VariantCount, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	counts := map[[2]string]*models.VariantCount{}
+	for _, impression := range r.impressions {
+		if impression.Experiment == "" || impression.Timestamp.Before(since) {
+			continue
+		}
+		countVariant(counts, impression.Experiment, impression.Variant, impression.Clicked())
+	}
+	return variantCountList(counts), nil
+}
+
+// countVariant counts an impression in the count of its experiment and variant
+func countVariant(counts map[[2]string]*models.VariantCount, experiment, variant string, clicked bool) {
+	key := [2]string{experiment, variant}
+	if counts[key] == nil {
+		counts[key] = &models.VariantCount{Experiment: experiment, Variant: variant}
+	}
+	counts[key].Impressions++
+	if clicked {
+		counts[key].Clicks++
+	}
+}
+
+// variantCountList returns the values of a variant count map
+func variantCountList(counts map[[2]string]*models.VariantCount) []models.VariantCount {
+	list := make([]models.VariantCount, 0, len(counts))
+	for _, count := range counts {
+		list = append(list, *count)
+	}
+	return list
+}
+
+// MemoryCTRCountRepository keeps CTR counts keyed by kind and key
+type MemoryCTRCountRepository struct {
+	mu     sync.RWMutex
+	counts map[[2]string]models.CTRCount
+}
+
+// AddCTRCounts adds counts to the stored ones by kind and key
+func (r *MemoryCTRCountRepository) AddCTRCounts(ctx context.Context, counts []models.CTRCount) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, count := range counts {
+		key := [2]string{count.Kind, count.Key}
+		stored := r.counts[key]
+		count.Impressions += stored.Impressions
+		count.Clicks += stored.Clicks
+		r.counts[key] = count
+	}
+	return len(counts), nil
+}
+
+// ListCTRCounts returns every stored count
+func (r *MemoryCTRCountRepository) ListCTRCounts(ctx context.Context) ([]models.CTRCount, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	counts := make([]models.CTRCount, 0, len(r.counts))
+	for _, count := range r.counts {
+		counts = append(counts, count)
+	}
+	return counts, nil
+}
+
+// MemoryCFItemRepository keeps collaborative filtering runs keyed by run ID
+// and their items keyed by run, kind and ID
+type MemoryCFItemRepository struct {
+	mu    sync.RWMutex
+	runs  map[string]models.CFRun
+	items map[string]map[[2]string]models.CFItem
+}
+
+// PutCFRun creates or replaces a run by ID
+func (r *MemoryCFItemRepository) PutCFRun(ctx context.Context, run models.CFRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs[run.ID] = run
+	return nil
+}
+
+// CurrentCFRun returns the published run trained last
+func (r *MemoryCFItemRepository) CurrentCFRun(ctx context.Context) (*models.CFRun, error) {
+	runs, _ := r.ListCFRuns(ctx)
+	return currentCFRun(runs)
+}
+
+// ListCFRuns returns every stored run
+func (r *MemoryCFItemRepository) ListCFRuns(ctx context.Context) ([]models.CFRun, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	runs := make([]models.CFRun, 0, len(r.runs))
+	for _, run := range r.runs {
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// PutCFItems creates or replaces items of a run by kind and ID
+func (r *MemoryCFItemRepository) PutCFItems(ctx context.Context, runID string, items []models.CFItem) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.items[runID] == nil {
+		r.items[runID] = map[[2]string]models.CFItem{}
+	}
+	for _, item := range items {
+		item.RunID = runID
+		r.items[runID][[2]string{item.Kind, item.ID}] = item
+	}
+	return nil
+}
+
+// ListCFItems returns the items of a run
+func (r *MemoryCFItemRepository) ListCFItems(ctx context.Context, runID string) ([]models.CFItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	items := make([]models.CFItem, 0, len(r.items[runID]))
+	for _, item := range r.items[runID] {
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// DeleteCFRun removes a run and its items
+func (r *MemoryCFItemRepository) DeleteCFRun(ctx context.Context, runID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.runs, runID)
+	delete(r.items, runID)
+	return nil
+}
+
+// currentCFRun returns the published run trained last among runs, ErrNotFound if none is published
+func currentCFRun(runs []models.CFRun) (*models.CFRun, error) {
+	var current *models.CFRun
+	for i := range runs {
+		if runs[i].Published && (current == nil || runs[i].TrainedAt.After(current.TrainedAt)) {
+			current = &runs[i]
+		}
+	}
+	if current == nil {
+		return nil, ErrNotFound
+	}
+	return current, nil
+}
+
+// MemoryAdRepository keeps ads in a map keyed by ad ID
+type MemoryAdRepository struct {
+	mu  sync.RWMutex
+	ads map[string]models.Ad
+}
+
+// PutAd creates or fully replaces an ad
+func (r *MemoryAdRepository) PutAd(ctx context.Context, ad models.Ad) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ad.Keywords = append([]string(nil), ad.Keywords...)
+	r.ads[ad.AdID] = ad
+	return nil
+}
+
+// GetAd retrieves an ad by ID
+func (r *MemoryAdRepository) GetAd(ctx context.Context, adID string) (*models.Ad, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ad, ok := r.ads[adID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	ad.Keywords = append([]string(nil), ad.Keywords...)
+	return &ad, nil
+}
+
+// DeleteAd removes an ad by ID
+func (r *MemoryAdRepository) DeleteAd(ctx context.Context, adID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.ads, adID)
+	return nil
+}
+
+// ListAds returns every ad, ordered by ad ID
+func (r *MemoryAdRepository) ListAds(ctx context.Context) ([]models.Ad, error) {
+	return r.filter(func(models.Ad) bool { return true }), nil
+}
+
+// AdsByCategory returns the active ads of one category, ordered by ad ID
+func (r *MemoryAdRepository) AdsByCategory(ctx context.Context, category string) ([]models.Ad, error) {
+	return r.filter(func(ad models.Ad) bool {
+		return ad.Category == category && ad.StatusOrDefault() == models.AdStatusActive
+	}), nil
+}
+
+// filter returns the ads matching keep, ordered by ad ID
+func (r *MemoryAdRepository) filter(keep func(models.Ad) bool) []models.Ad {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ads := []models.Ad{}
+	for _, ad := range r.ads {
+		if keep(ad) {
+			ads = append(ads, ad)
+		}
+	}
+	sort.Slice(ads, func(i, j int) bool { return ads[i].AdID < ads[j].AdID })
+	return ads
+}
+
+// MemoryCategoryMappingRepository keeps category mappings keyed by movie category
+type MemoryCategoryMappingRepository struct {
+	mu       sync.RWMutex
+	mappings map[string]models.CategoryMapping
+}
+
+// PutCategoryMapping creates or fully replaces a category mapping
+func (r *MemoryCategoryMappingRepository) PutCategoryMapping(ctx context.Context, mapping models.CategoryMapping) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	mapping.AdCategories = append([]string(nil), mapping.AdCategories...)
+	r.mappings[mapping.MovieCategory] = mapping
+	return nil
+}
+
+// GetCategoryMapping retrieves the mapping for a movie category
+func (r *MemoryCategoryMappingRepository) GetCategoryMapping(ctx context.Context, movieCategory string) (*models.CategoryMapping, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	mapping, ok := r.mappings[movieCategory]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	mapping.AdCategories = append([]string(nil), mapping.AdCategories...)
+	return &mapping, nil
+}
+
+// MemoryAdEmbeddingRepository keeps ad embeddings keyed by model, then ad ID,
+// with vectors held as float32
+type MemoryAdEmbeddingRepository struct {
+	mu         sync.RWMutex
+	embeddings map[string]map[string]memoryAdEmbedding
+}
+
+// memoryAdEmbedding is an AdEmbedding whose vector is stored compactly
+type memoryAdEmbedding struct {
+	models.AdEmbedding
+	compact vector.Vector
+}
+
+// PutAdEmbedding creates or replaces the embedding of an ad for its model
+func (r *MemoryAdEmbeddingRepository) PutAdEmbedding(ctx context.Context, embedding models.AdEmbedding) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.embeddings[embedding.Model] == nil {
+		r.embeddings[embedding.Model] = make(map[string]memoryAdEmbedding)
+	}
+	stored := memoryAdEmbedding{AdEmbedding: embedding, compact: vector.FromFloat64(embedding.Vector)}
+	stored.Vector = nil
+	r.embeddings[embedding.Model][embedding.AdID] = stored
+	return nil
+}
+
+// GetAdEmbeddings returns the stored embeddings of the given ads for a model
+func (r *MemoryAdEmbeddingRepository) GetAdEmbeddings(ctx context.Context, model string, adIDs []string) (map[string]models.AdEmbedding, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	found := make(map[string]models.AdEmbedding)
+	for _, adID := range adIDs {
+		if stored, ok := r.embeddings[model][adID]; ok {
+			embedding := stored.AdEmbedding
+			embedding.Vector = stored.compact.Float64()
+			found[adID] = embedding
+		}
+	}
+	return found, nil
+}
+
+// DeleteAdEmbeddings removes the embeddings of an ad for every model
+func (r *MemoryAdEmbeddingRepository) DeleteAdEmbeddings(ctx context.Context, adID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, byAd := range r.embeddings {
+		delete(byAd, adID)
+	}
+	return nil
+}
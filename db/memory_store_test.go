@@ -0,0 +1,107 @@
+package db
+
+import (
+	"Ad-Recommendations/models"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryPlaybackHistoryOrderedByTimestamp(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.NoError(t, store.Playback.LogPlayback(ctx, models.PlaybackEvent{UserID: "u1", Category: "Drama", Timestamp: base.Add(2 * time.Hour)}))
+	assert.NoError(t, store.Playback.LogPlayback(ctx, models.PlaybackEvent{UserID: "u1", Category: "Action", Timestamp: base}))
+	assert.NoError(t, store.Playback.LogPlayback(ctx, models.PlaybackEvent{UserID: "u2", Category: "Comedy", Timestamp: base}))
+
+	history, _, err := store.Playback.PlaybackHistory(ctx, "u1", HistoryQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+	assert.Equal(t, "Action", history[0].Category)
+	assert.Equal(t, "Drama", history[1].Category)
+}
+
+func TestLoadFixtureJSONL(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "seed.jsonl")
+	lines := `{"type":"ad","ad_id":"a1","category":"Cars","description":"Fast car"}
+{"type":"category_mapping","movie_category":"Action","ad_categories":["Cars"]}
+{"type":"playback","user_id":"u1","category":"Action","timestamp":"2025-01-01T00:00:00Z"}
+`
+	assert.NoError(t, os.WriteFile(path, []byte(lines), 0o644))
+
+	store := NewMemoryStore()
+	assert.NoError(t, LoadFixture(ctx, store, path))
+
+	ads, err := store.Ads.AdsByCategory(ctx, "Cars")
+	assert.NoError(t, err)
+	assert.Len(t, ads, 1)
+
+	mapping, err := store.Mappings.GetCategoryMapping(ctx, "Action")
+	assert.NoError(t, err)
+	assert.Equal(t, models.DefaultCategoryWeight, mapping.Weight)
+
+	_, err = store.Mappings.GetCategoryMapping(ctx, "Horror")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestMemoryPlaybackHistoryQuery(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, category := range []string{"Action", "Action", "Drama", "Comedy"} {
+		assert.NoError(t, store.Playback.LogPlayback(ctx, models.PlaybackEvent{UserID: "u1", Category: category, Timestamp: base.Add(time.Duration(i) * time.Hour)}))
+	}
+
+	newest, _, err := store.Playback.PlaybackHistory(ctx, "u1", HistoryQuery{Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Drama", "Comedy"}, []string{newest[0].Category, newest[1].Category})
+
+	window, _, err := store.Playback.PlaybackHistory(ctx, "u1", HistoryQuery{Since: base.Add(time.Hour), Until: base.Add(3 * time.Hour)})
+	assert.NoError(t, err)
+	assert.Len(t, window, 2)
+	assert.Equal(t, "Action", window[0].Category)
+	assert.Equal(t, "Drama", window[1].Category)
+	assert.NotEqual(t, window[0].EventID, window[1].EventID)
+}
+
+func TestEventIDsSortByTime(t *testing.T) {
+	earlier := NewEventID(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	later := NewEventID(time.Date(2025, 1, 1, 0, 0, 0, 1, time.UTC))
+
+	assert.Less(t, earlier, later)
+	assert.Less(t, eventTimeKey(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)), earlier)
+	assert.Less(t, eventSinceKey(time.Time{}), eventTimeKey(time.Unix(0, 0)))
+	assert.Equal(t, eventTimeKey(time.Unix(0, 0)), eventSinceKey(time.Unix(0, 0)))
+}
+
+func TestMemoryAdClickHistoryCursor(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, store.Clicks.LogAdClick(ctx, models.AdClick{UserID: "u1", AdID: "ad1", Timestamp: base.Add(time.Duration(i) * time.Minute)}))
+	}
+
+	first, next, err := store.Clicks.AdClickHistory(ctx, "u1", HistoryQuery{Limit: 2})
+	assert.NoError(t, err)
+	assert.Len(t, first, 2)
+	assert.Equal(t, base.Add(3*time.Minute), first[0].Timestamp)
+	assert.NotEmpty(t, next)
+
+	second, next, err := store.Clicks.AdClickHistory(ctx, "u1", HistoryQuery{Limit: 2, Cursor: next})
+	assert.NoError(t, err)
+	assert.Equal(t, base.Add(1*time.Minute), second[0].Timestamp)
+	assert.NotEmpty(t, next)
+
+	last, next, err := store.Clicks.AdClickHistory(ctx, "u1", HistoryQuery{Limit: 2, Cursor: next})
+	assert.NoError(t, err)
+	assert.Len(t, last, 1)
+	assert.Empty(t, next)
+}
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"Ad-Recommendations/config"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+)
+
+// OpenStore builds the storage backend selected in the configuration
+func OpenStore(ctx context.Context, cfg config.Config) (*Store, error) {
+	switch cfg.StorageBackend {
+	case config.StorageMemory:
+		log.Println("Using in-memory storage")
+		return NewMemoryStore(), nil
+
+	case config.StorageDynamoDB:
+		// Load environment variables (e.g., AWS credentials)
+		awsAccessKey := os.Getenv("AWS_ACCESS_KEY_ID")
+		awsSecretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+		awsRegion := os.Getenv("AWS_REGION")
+
+		if awsAccessKey == "" || awsSecretKey == "" || awsRegion == "" {
+			return nil, errors.New("AWS credentials or region not set. Please configure them using environment variables")
+		}
+
+		// Initialize the DynamoDB client and ensure required tables are available
+		dynamoClient, err := InitDynamoDB()
+		if err != nil {
+			return nil, err
+		}
+		return NewDynamoStore(dynamoClient), nil
+
+	case config.StoragePostgres:
+		pool, err := InitPostgres(ctx, cfg.Postgres)
+		if err != nil {
+			return nil, err
+		}
+		return NewPostgresStore(pool), nil
+
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.StorageBackend)
+	}
+}
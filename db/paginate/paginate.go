@@ -0,0 +1,142 @@
+// Package paginate follows DynamoDB LastEvaluatedKey across pages and encodes
+// the resume key as an opaque cursor for API callers.
+package paginate
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Page holds the items read by Query or Scan and the key to resume from
+type Page struct {
+	Items            []map[string]types.AttributeValue
+	NextKey          map[string]types.AttributeValue // nil when the read is exhausted
+	ConsumedCapacity float64                         // Summed over pages when the input asks for ReturnConsumedCapacity
+}
+
+// Query runs a Query and follows LastEvaluatedKey until limit items are read or
+// no pages remain. A limit of 0 reads every page. The input's ExclusiveStartKey
+// is used as the starting point, and input.Limit caps the size of each page.
+func Query(ctx context.Context, client dynamodb.QueryAPIClient, input *dynamodb.QueryInput, limit int) (Page, error) {
+	request := *input
+	page := Page{}
+
+	for {
+		request.Limit = pageSize(input.Limit, limit, len(page.Items))
+		output, err := client.Query(ctx, &request)
+		if err != nil {
+			return page, err
+		}
+
+		page.Items = append(page.Items, output.Items...)
+		page.NextKey = output.LastEvaluatedKey
+		if output.ConsumedCapacity != nil && output.ConsumedCapacity.CapacityUnits != nil {
+			page.ConsumedCapacity += *output.ConsumedCapacity.CapacityUnits
+		}
+		if len(output.LastEvaluatedKey) == 0 || (limit > 0 && len(page.Items) >= limit) {
+			return page, nil
+		}
+		request.ExclusiveStartKey = output.LastEvaluatedKey
+	}
+}
+
+// Scan runs a Scan and follows LastEvaluatedKey until limit items are read or
+// no pages remain. A limit of 0 reads every page. The input's ExclusiveStartKey
+// is used as the starting point, and input.Limit caps the size of each page.
+func Scan(ctx context.Context, client dynamodb.ScanAPIClient, input *dynamodb.ScanInput, limit int) (Page, error) {
+	request := *input
+	page := Page{}
+
+	for {
+		request.Limit = pageSize(input.Limit, limit, len(page.Items))
+		output, err := client.Scan(ctx, &request)
+		if err != nil {
+			return page, err
+		}
+
+		page.Items = append(page.Items, output.Items...)
+		page.NextKey = output.LastEvaluatedKey
+		if output.ConsumedCapacity != nil && output.ConsumedCapacity.CapacityUnits != nil {
+			page.ConsumedCapacity += *output.ConsumedCapacity.CapacityUnits
+		}
+		if len(output.LastEvaluatedKey) == 0 || (limit > 0 && len(page.Items) >= limit) {
+			return page, nil
+		}
+		request.ExclusiveStartKey = output.LastEvaluatedKey
+	}
+}
+
+// pageSize bounds a request so a page never returns more items than are still
+// wanted. DynamoDB applies Limit before filters, so the LastEvaluatedKey of a
+// bounded page is always a safe place to resume.
+func pageSize(requested *int32, limit, read int) *int32 {
+	if limit <= 0 {
+		return requested
+	}
+	remaining := int32(limit - read)
+	if requested != nil && *requested < remaining {
+		return requested
+	}
+	return aws.Int32(remaining)
+}
+
+// EncodeCursor turns a LastEvaluatedKey into an opaque URL-safe cursor.
+// Only string and number key attributes are supported.
+func EncodeCursor(key map[string]types.AttributeValue) (string, error) {
+	if len(key) == 0 {
+		return "", nil
+	}
+
+	encoded := make(map[string]map[string]string, len(key))
+	for name, value := range key {
+		switch v := value.(type) {
+		case *types.AttributeValueMemberS:
+			encoded[name] = map[string]string{"S": v.Value}
+		case *types.AttributeValueMemberN:
+			encoded[name] = map[string]string{"N": v.Value}
+		default:
+			return "", fmt.Errorf("unsupported key attribute type for %s", name)
+		}
+	}
+
+	body, err := json.Marshal(encoded)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(body), nil
+}
+
+// DecodeCursor turns a cursor from EncodeCursor back into an ExclusiveStartKey
+func DecodeCursor(cursor string) (map[string]types.AttributeValue, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	body, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+
+	encoded := map[string]map[string]string{}
+	if err := json.Unmarshal(body, &encoded); err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+
+	key := make(map[string]types.AttributeValue, len(encoded))
+	for name, value := range encoded {
+		if s, ok := value["S"]; ok {
+			key[name] = &types.AttributeValueMemberS{Value: s}
+		} else if n, ok := value["N"]; ok {
+			key[name] = &types.AttributeValueMemberN{Value: n}
+		} else {
+			return nil, fmt.Errorf("invalid cursor: unsupported attribute %s", name)
+		}
+	}
+	return key, nil
+}
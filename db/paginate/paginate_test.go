@@ -0,0 +1,87 @@
+package paginate
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTable serves Query and Scan over numbered items in pages of pageSize
+type fakeTable struct {
+	items    int
+	pageSize int32
+	calls    int
+}
+
+func (f *fakeTable) page(startKey map[string]types.AttributeValue, limit *int32) ([]map[string]types.AttributeValue, map[string]types.AttributeValue) {
+	f.calls++
+	start := 0
+	if key, ok := startKey["id"].(*types.AttributeValueMemberN); ok {
+		start, _ = strconv.Atoi(key.Value)
+		start++
+	}
+	size := f.pageSize
+	if limit != nil && *limit < size {
+		size = *limit
+	}
+
+	items := []map[string]types.AttributeValue{}
+	for i := start; i < f.items && len(items) < int(size); i++ {
+		items = append(items, map[string]types.AttributeValue{"id": &types.AttributeValueMemberN{Value: strconv.Itoa(i)}})
+	}
+	if start+len(items) >= f.items {
+		return items, nil
+	}
+	return items, items[len(items)-1]
+}
+
+func (f *fakeTable) Query(ctx context.Context, input *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	items, next := f.page(input.ExclusiveStartKey, input.Limit)
+	return &dynamodb.QueryOutput{Items: items, LastEvaluatedKey: next}, nil
+}
+
+func (f *fakeTable) Scan(ctx context.Context, input *dynamodb.ScanInput, _ ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	items, next := f.page(input.ExclusiveStartKey, input.Limit)
+	return &dynamodb.ScanOutput{Items: items, LastEvaluatedKey: next}, nil
+}
+
+func TestScanFollowsEveryPage(t *testing.T) {
+	table := &fakeTable{items: 25, pageSize: 10}
+
+	page, err := Scan(context.Background(), table, &dynamodb.ScanInput{TableName: aws.String("t")}, 0)
+
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 25)
+	assert.Nil(t, page.NextKey)
+	assert.Equal(t, 3, table.calls)
+}
+
+func TestQueryStopsAtLimitAndResumes(t *testing.T) {
+	ctx := context.Background()
+	table := &fakeTable{items: 25, pageSize: 10}
+
+	first, err := Query(ctx, table, &dynamodb.QueryInput{TableName: aws.String("t")}, 12)
+	assert.NoError(t, err)
+	assert.Len(t, first.Items, 12)
+	assert.NotNil(t, first.NextKey)
+
+	cursor, err := EncodeCursor(first.NextKey)
+	assert.NoError(t, err)
+	startKey, err := DecodeCursor(cursor)
+	assert.NoError(t, err)
+
+	rest, err := Query(ctx, table, &dynamodb.QueryInput{TableName: aws.String("t"), ExclusiveStartKey: startKey}, 0)
+	assert.NoError(t, err)
+	assert.Len(t, rest.Items, 13)
+	assert.Equal(t, "12", rest.Items[0]["id"].(*types.AttributeValueMemberN).Value)
+}
+
+func TestDecodeCursorRejectsGarbage(t *testing.T) {
+	_, err := DecodeCursor("not a cursor")
+	assert.Error(t, err)
+}
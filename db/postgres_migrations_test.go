@@ -0,0 +1,47 @@
+package db
+
+import (
+	"Ad-Recommendations/database"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadMigrationsOrdersByVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0010_later.sql":   {Data: []byte("SELECT 10;")},
+		"0002_second.sql":  {Data: []byte("SELECT 2;")},
+		"0001_initial.sql": {Data: []byte("SELECT 1;")},
+	}
+
+	migrations, err := LoadMigrations(fsys)
+
+	assert.NoError(t, err)
+	assert.Len(t, migrations, 3)
+	assert.Equal(t, []int{1, 2, 10}, []int{migrations[0].Version, migrations[1].Version, migrations[2].Version})
+	assert.Equal(t, "0002_second", migrations[1].Name)
+}
+
+func TestLoadMigrationsRejectsDuplicateVersions(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0001_a.sql": {Data: []byte("SELECT 1;")},
+		"0001_b.sql": {Data: []byte("SELECT 1;")},
+	}
+
+	_, err := LoadMigrations(fsys)
+
+	assert.Error(t, err)
+}
+
+func TestEmbeddedMigrationsLoad(t *testing.T) {
+	migrationFiles, err := fs.Sub(database.Migrations, "migrations")
+	assert.NoError(t, err)
+
+	migrations, err := LoadMigrations(migrationFiles)
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, migrations)
+	assert.Equal(t, 1, migrations[0].Version)
+}
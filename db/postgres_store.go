@@ -0,0 +1,690 @@
+package db
+
+import (
+	"Ad-Recommendations/config"
+	"Ad-Recommendations/database"
+	"Ad-Recommendations/models"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// InitPostgres opens a PostgreSQL connection pool and applies pending migrations
+func InitPostgres(ctx context.Context, cfg config.PostgresConfig) (*pgxpool.Pool, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("DATABASE_URL is not set")
+	}
+
+	poolConfig, err := pgxpool.ParseConfig(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DATABASE_URL: %w", err)
+	}
+	poolConfig.MaxConns = cfg.MaxConns
+	poolConfig.MinConns = cfg.MinConns
+	poolConfig.MaxConnLifetime = cfg.MaxConnLifetime
+	poolConfig.MaxConnIdleTime = cfg.MaxConnIdleTime
+	poolConfig.HealthCheckPeriod = cfg.HealthCheckPeriod
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create PostgreSQL pool: %w", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+	log.Println("PostgreSQL connection pool initialized")
+
+	migrationFiles, err := fs.Sub(database.Migrations, "migrations")
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+	migrations, err := LoadMigrations(migrationFiles)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+	if err := ApplyMigrations(ctx, pool, migrations); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	return pool, nil
+}
+
+// NewPostgresStore builds a Store backed by the PostgreSQL schema in database/migrations
+func NewPostgresStore(pool *pgxpool.Pool) *Store {
+	return &Store{
+		Users:       &PostgresUserRepository{Pool: pool},
+		Playback:    &PostgresPlaybackRepository{Pool: pool},
+		Clicks:      &PostgresAdClickRepository{Pool: pool},
+		Impressions: &PostgresImpressionRepository{Pool: pool},
+		CTRCounts:   &PostgresCTRCountRepository{Pool: pool},
+		CFItems:     &PostgresCFItemRepository{Pool: pool},
+		Ads:         &PostgresAdRepository{Pool: pool},
+		Mappings:    &PostgresCategoryMappingRepository{Pool: pool},
+		Embeddings:  &PostgresAdEmbeddingRepository{Pool: pool},
+	}
+}
+
+// PostgresUserRepository stores users in the users table
+type PostgresUserRepository struct {
+	Pool *pgxpool.Pool
+}
+
+// PutUser creates or fully replaces a user
+func (r *PostgresUserRepository) PutUser(ctx context.Context, user models.User) error {
+	history, err := json.Marshal(user.History)
+	if err != nil {
+		return err
+	}
+	_, err = r.Pool.Exec(ctx, `INSERT INTO users (user_id, playback_history) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET playback_history = EXCLUDED.playback_history`,
+		user.UserID, history)
+	return err
+}
+
+// GetUser retrieves a user by ID
+func (r *PostgresUserRepository) GetUser(ctx context.Context, userID string) (*models.User, error) {
+	var history []byte
+	err := r.Pool.QueryRow(ctx, "SELECT playback_history FROM users WHERE user_id = $1", userID).Scan(&history)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{UserID: userID, History: []string{}}
+	if len(history) > 0 {
+		if err := json.Unmarshal(history, &user.History); err != nil {
+			return nil, fmt.Errorf("invalid playback_history for %s: %w", userID, err)
+		}
+	}
+	return user, nil
+}
+
+// DeleteUser removes a user by ID
+func (r *PostgresUserRepository) DeleteUser(ctx context.Context, userID string) error {
+	_, err := r.Pool.Exec(ctx, "DELETE FROM users WHERE user_id = $1", userID)
+	return err
+}
+
+// PostgresPlaybackRepository stores playback events in the playback_history table
+type PostgresPlaybackRepository struct {
+	Pool *pgxpool.Pool
+}
+
+// LogPlayback appends a playback event
+func (r *PostgresPlaybackRepository) LogPlayback(ctx context.Context, event models.PlaybackEvent) error {
+	_, err := r.Pool.Exec(ctx, "INSERT INTO playback_history (user_id, category, timestamp) VALUES ($1, $2, $3)",
+		event.UserID, event.Category, event.Timestamp.UTC())
+	return err
+}
+
+// PlaybackHistory returns a user's playback events matching the query, oldest first
+func (r *PostgresPlaybackRepository) PlaybackHistory(ctx context.Context, userID string, query HistoryQuery) ([]models.PlaybackEvent, string, error) {
+	return queryHistory(ctx, r.Pool, "SELECT id, user_id, category, timestamp FROM playback_history", userID, query,
+		func(row pgx.CollectableRow) (models.PlaybackEvent, historyKey, error) {
+			key := historyKey{}
+			event := models.PlaybackEvent{}
+			err := row.Scan(&key.ID, &event.UserID, &event.Category, &event.Timestamp)
+			event.EventID = strconv.FormatInt(key.ID, 10)
+			key.Timestamp = event.Timestamp
+			return event, key, err
+		})
+}
+
+// PlaybackSince returns the playback events of every user since a time, oldest first
+func (r *PostgresPlaybackRepository) PlaybackSince(ctx context.Context, since time.Time) ([]models.PlaybackEvent, error) {
+	rows, err := r.Pool.Query(ctx, `SELECT id, user_id, category, timestamp FROM playback_history
+		WHERE timestamp >= $1 ORDER BY timestamp, id`, since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.PlaybackEvent, error) {
+		var id int64
+		event := models.PlaybackEvent{}
+		err := row.Scan(&id, &event.UserID, &event.Category, &event.Timestamp)
+		event.EventID = strconv.FormatInt(id, 10)
+		return event, err
+	})
+}
+
+// historyKey is the (timestamp, id) position of a row in an event table; cursors encode the oldest key of a page
+type historyKey struct {
+	Timestamp time.Time
+	ID        int64
+}
+
+// encodeHistoryCursor turns a row position into an opaque cursor
+func encodeHistoryCursor(key historyKey) string {
+	raw := fmt.Sprintf("%d:%d", key.Timestamp.UnixNano(), key.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeHistoryCursor turns a cursor from encodeHistoryCursor back into a row position
+func decodeHistoryCursor(cursor string) (historyKey, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return historyKey{}, ErrInvalidCursor
+	}
+	nanos, id, found := strings.Cut(string(raw), ":")
+	if !found {
+		return historyKey{}, ErrInvalidCursor
+	}
+	unixNano, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return historyKey{}, ErrInvalidCursor
+	}
+	key := historyKey{Timestamp: time.Unix(0, unixNano).UTC()}
+	if key.ID, err = strconv.ParseInt(id, 10, 64); err != nil {
+		return historyKey{}, ErrInvalidCursor
+	}
+	return key, nil
+}
+
+// queryHistory applies a HistoryQuery to a SELECT over an event table with id,
+// user_id and timestamp columns. Rows are read newest first, one past the limit
+// to tell whether an older page exists, and returned oldest first.
+func queryHistory[T any](ctx context.Context, pool *pgxpool.Pool, selectSQL, userID string, query HistoryQuery,
+	scan func(pgx.CollectableRow) (T, historyKey, error)) ([]T, string, error) {
+	sql := selectSQL + " WHERE user_id = $1"
+	args := []any{userID}
+	if !query.Since.IsZero() {
+		args = append(args, query.Since.UTC())
+		sql += fmt.Sprintf(" AND timestamp >= $%d", len(args))
+	}
+	if !query.Until.IsZero() {
+		args = append(args, query.Until.UTC())
+		sql += fmt.Sprintf(" AND timestamp < $%d", len(args))
+	}
+	if query.Cursor != "" {
+		key, err := decodeHistoryCursor(query.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		args = append(args, key.Timestamp, key.ID)
+		sql += fmt.Sprintf(" AND (timestamp, id) < ($%d, $%d)", len(args)-1, len(args))
+	}
+	sql += " ORDER BY timestamp DESC, id DESC"
+	if query.Limit > 0 {
+		args = append(args, query.Limit+1)
+		sql += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	type keyedRow struct {
+		value T
+		key   historyKey
+	}
+	keyed, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (keyedRow, error) {
+		value, key, err := scan(row)
+		return keyedRow{value: value, key: key}, err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if query.Limit > 0 && len(keyed) > query.Limit {
+		keyed = keyed[:query.Limit]
+		next = encodeHistoryCursor(keyed[len(keyed)-1].key)
+	}
+
+	events := make([]T, len(keyed))
+	for i, row := range keyed {
+		events[len(keyed)-1-i] = row.value
+	}
+	return events, next, nil
+}
+
+// PostgresAdClickRepository stores ad clicks in the ad_click_history table
+type PostgresAdClickRepository struct {
+	Pool *pgxpool.Pool
+}
+
+// LogAdClick appends an ad click event
+func (r *PostgresAdClickRepository) LogAdClick(ctx context.Context, click models.AdClick) error {
+	var recommendationID *string
+	if click.RecommendationID != "" {
+		recommendationID = &click.RecommendationID
+	}
+	_, err := r.Pool.Exec(ctx, `INSERT INTO ad_click_history (user_id, ad_id, category, timestamp, recommendation_id, experiment, variant)
+		VALUES ($1, $2, (SELECT category FROM ads WHERE ad_id = $2), $3, $4, $5, $6)`,
+		click.UserID, click.AdID, click.Timestamp.UTC(), recommendationID, click.Experiment, click.Variant)
+	return err
+}
+
+// AdClickHistory returns a user's ad clicks matching the query, oldest first
+func (r *PostgresAdClickRepository) AdClickHistory(ctx context.Context, userID string, query HistoryQuery) ([]models.AdClick, string, error) {
+	return queryHistory(ctx, r.Pool, "SELECT id, user_id, ad_id, timestamp, COALESCE(recommendation_id, ''), experiment, variant FROM ad_click_history", userID, query,
+		func(row pgx.CollectableRow) (models.AdClick, historyKey, error) {
+			key := historyKey{}
+			click := models.AdClick{}
+			err := row.Scan(&key.ID, &click.UserID, &click.AdID, &click.Timestamp, &click.RecommendationID, &click.Experiment, &click.Variant)
+			click.EventID = strconv.FormatInt(key.ID, 10)
+			key.Timestamp = click.Timestamp
+			return click, key, err
+		})
+}
+
+// AdClickCounts returns the number of clicks on each ad since a time
+func (r *PostgresAdClickRepository) AdClickCounts(ctx context.Context, adIDs []string, since time.Time) (map[string]int, error) {
+	rows, err := r.Pool.Query(ctx, `SELECT ad_id, COUNT(*) FROM ad_click_history
+		WHERE ad_id = ANY($1) AND timestamp >= $2 GROUP BY ad_id`, adIDs, since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int, len(adIDs))
+	for rows.Next() {
+		var adID string
+		var count int
+		if err := rows.Scan(&adID, &count); err != nil {
+			return nil, err
+		}
+		counts[adID] = count
+	}
+	return counts, rows.Err()
+}
+
+// ClicksSince returns the ad clicks of every user since a time, oldest first
+func (r *PostgresAdClickRepository) ClicksSince(ctx context.Context, since time.Time) ([]models.AdClick, error) {
+	rows, err := r.Pool.Query(ctx, `SELECT id, user_id, ad_id, timestamp, COALESCE(recommendation_id, ''), experiment, variant
+		FROM ad_click_history WHERE timestamp >= $1 ORDER BY timestamp, id`, since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.AdClick, error) {
+		var id int64
+		click := models.AdClick{}
+		err := row.Scan(&id, &click.UserID, &click.AdID, &click.Timestamp, &click.RecommendationID, &click.Experiment, &click.Variant)
+		click.EventID = strconv.FormatInt(id, 10)
+		return click, err
+	})
+}
+
+// PostgresImpressionRepository stores impressions in the ad_impressions table
+type PostgresImpressionRepository struct {
+	Pool *pgxpool.Pool
+}
+
+// impressionColumns are the ad_impressions columns read by scanImpression
+const impressionColumns = "recommendation_id, ad_id, user_id, category, segment, movie_category, position, explored, experiment, variant, timestamp, clicked_at"
+
+// LogImpressions stores served ads in one batch, keeping impressions already stored
+func (r *PostgresImpressionRepository) LogImpressions(ctx context.Context, impressions []models.Impression) ([]models.Impression, error) {
+	batch := &pgx.Batch{}
+	for _, impression := range impressions {
+		batch.Queue(`INSERT INTO ad_impressions (recommendation_id, ad_id, user_id, category, segment, movie_category, position, explored, experiment, variant, timestamp)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) ON CONFLICT (recommendation_id, ad_id) DO NOTHING`,
+			impression.RecommendationID, impression.AdID, impression.UserID, impression.Category,
+			impression.Segment, impression.MovieCategory, impression.Position, impression.Explored,
+			impression.Experiment, impression.Variant, impression.Timestamp.UTC())
+	}
+
+	results := r.Pool.SendBatch(ctx, batch)
+	defer results.Close()
+	stored := []models.Impression{}
+	for _, impression := range impressions {
+		tag, err := results.Exec()
+		if err != nil {
+			return nil, err
+		}
+		if tag.RowsAffected() == 1 {
+			stored = append(stored, impression)
+		}
+	}
+	return stored, results.Close()
+}
+
+// GetImpression retrieves the impression of an ad in a recommendation
+func (r *PostgresImpressionRepository) GetImpression(ctx context.Context, recommendationID, adID string) (*models.Impression, error) {
+	rows, err := r.Pool.Query(ctx, "SELECT "+impressionColumns+" FROM ad_impressions WHERE recommendation_id = $1 AND ad_id = $2",
+		recommendationID, adID)
+	if err != nil {
+		return nil, err
+	}
+	impression, err := pgx.CollectExactlyOneRow(rows, scanImpression)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &impression, nil
+}
+
+// MarkClicked records the first click on an impression
+func (r *PostgresImpressionRepository) MarkClicked(ctx context.Context, recommendationID, adID string, at time.Time) (bool, error) {
+	tag, err := r.Pool.Exec(ctx, `UPDATE ad_impressions SET clicked_at = $3
+		WHERE recommendation_id = $1 AND ad_id = $2 AND clicked_at IS NULL`, recommendationID, adID, at.UTC())
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 1 {
+		return true, nil
+	}
+	if _, err := r.GetImpression(ctx, recommendationID, adID); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+// ImpressionsSince returns the impressions served since a time, oldest first
+func (r *PostgresImpressionRepository) ImpressionsSince(ctx context.Context, since time.Time) ([]models.Impression, error) {
+	rows, err := r.Pool.Query(ctx, "SELECT "+impressionColumns+" FROM ad_impressions WHERE timestamp >= $1 ORDER BY timestamp",
+		since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, scanImpression)
+}
+
+// CountVariantsSince counts the impressions served since a time per experiment and variant
+func (r *PostgresImpressionRepository) CountVariantsSince(ctx context.Context, since time.Time) ([]models.VariantCount, error) {
+	rows, err := r.Pool.Query(ctx, `SELECT experiment, variant, COUNT(*), COUNT(clicked_at) FROM ad_impressions
+		WHERE timestamp >= $1 AND experiment <> '' GROUP BY experiment, variant`, since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.VariantCount, error) {
+		count := models.VariantCount{}
+		err := row.Scan(&count.Experiment, &count.Variant, &count.Impressions, &count.Clicks)
+		return count, err
+	})
+}
+
+// scanImpression reads an ad_impressions row selected as impressionColumns
+func scanImpression(row pgx.CollectableRow) (models.Impression, error) {
+	impression := models.Impression{}
+	var clickedAt *time.Time
+	err := row.Scan(&impression.RecommendationID, &impression.AdID, &impression.UserID, &impression.Category,
+		&impression.Segment, &impression.MovieCategory, &impression.Position, &impression.Explored,
+		&impression.Experiment, &impression.Variant, &impression.Timestamp, &clickedAt)
+	if clickedAt != nil {
+		impression.ClickedAt = *clickedAt
+	}
+	return impression, err
+}
+
+// PostgresCTRCountRepository stores CTR counts in the ctr_counts table
+type PostgresCTRCountRepository struct {
+	Pool *pgxpool.Pool
+}
+
+// AddCTRCounts adds counts in one transaction, so either all or none are added
+func (r *PostgresCTRCountRepository) AddCTRCounts(ctx context.Context, counts []models.CTRCount) (int, error) {
+	batch := &pgx.Batch{}
+	for _, count := range counts {
+		batch.Queue(`INSERT INTO ctr_counts (kind, key, impressions, clicks, updated_at) VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (kind, key) DO UPDATE SET impressions = ctr_counts.impressions + EXCLUDED.impressions,
+				clicks = ctr_counts.clicks + EXCLUDED.clicks, updated_at = EXCLUDED.updated_at`,
+			count.Kind, count.Key, count.Impressions, count.Clicks, count.UpdatedAt.UTC())
+	}
+
+	err := pgx.BeginFunc(ctx, r.Pool, func(tx pgx.Tx) error {
+		return tx.SendBatch(ctx, batch).Close()
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(counts), nil
+}
+
+// ListCTRCounts returns every stored count
+func (r *PostgresCTRCountRepository) ListCTRCounts(ctx context.Context) ([]models.CTRCount, error) {
+	rows, err := r.Pool.Query(ctx, "SELECT kind, key, impressions, clicks, updated_at FROM ctr_counts")
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.CTRCount, error) {
+		count := models.CTRCount{}
+		err := row.Scan(&count.Kind, &count.Key, &count.Impressions, &count.Clicks, &count.UpdatedAt)
+		return count, err
+	})
+}
+
+// PostgresCFItemRepository stores collaborative filtering runs in the cf_runs
+// table and their items in the cf_items table
+type PostgresCFItemRepository struct {
+	Pool *pgxpool.Pool
+}
+
+// PutCFRun creates or replaces a run by ID
+func (r *PostgresCFItemRepository) PutCFRun(ctx context.Context, run models.CFRun) error {
+	_, err := r.Pool.Exec(ctx, `INSERT INTO cf_runs (run_id, trained_at, items, published) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (run_id) DO UPDATE SET trained_at = EXCLUDED.trained_at,
+			items = EXCLUDED.items, published = EXCLUDED.published`,
+		run.ID, run.TrainedAt.UTC(), run.Items, run.Published)
+	return err
+}
+
+// CurrentCFRun returns the published run trained last
+func (r *PostgresCFItemRepository) CurrentCFRun(ctx context.Context) (*models.CFRun, error) {
+	run := &models.CFRun{}
+	err := r.Pool.QueryRow(ctx, `SELECT run_id, trained_at, items, published FROM cf_runs
+		WHERE published ORDER BY trained_at DESC LIMIT 1`).Scan(&run.ID, &run.TrainedAt, &run.Items, &run.Published)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// ListCFRuns returns every stored run
+func (r *PostgresCFItemRepository) ListCFRuns(ctx context.Context) ([]models.CFRun, error) {
+	rows, err := r.Pool.Query(ctx, "SELECT run_id, trained_at, items, published FROM cf_runs")
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.CFRun, error) {
+		run := models.CFRun{}
+		err := row.Scan(&run.ID, &run.TrainedAt, &run.Items, &run.Published)
+		return run, err
+	})
+}
+
+// PutCFItems upserts items of a run in one batch
+func (r *PostgresCFItemRepository) PutCFItems(ctx context.Context, runID string, items []models.CFItem) error {
+	batch := &pgx.Batch{}
+	for _, item := range items {
+		similar := item.Similar
+		if similar == nil {
+			similar = map[string]float64{}
+		}
+		batch.Queue(`INSERT INTO cf_items (run_id, kind, id, factors, similar) VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (run_id, kind, id) DO UPDATE SET factors = EXCLUDED.factors, similar = EXCLUDED.similar`,
+			runID, item.Kind, item.ID, item.Factors, similar)
+	}
+	return r.Pool.SendBatch(ctx, batch).Close()
+}
+
+// ListCFItems returns the items of a run
+func (r *PostgresCFItemRepository) ListCFItems(ctx context.Context, runID string) ([]models.CFItem, error) {
+	rows, err := r.Pool.Query(ctx, "SELECT run_id, kind, id, factors, similar FROM cf_items WHERE run_id = $1", runID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.CFItem, error) {
+		item := models.CFItem{}
+		err := row.Scan(&item.RunID, &item.Kind, &item.ID, &item.Factors, &item.Similar)
+		return item, err
+	})
+}
+
+// DeleteCFRun removes a run, its items going with it by cascade
+func (r *PostgresCFItemRepository) DeleteCFRun(ctx context.Context, runID string) error {
+	_, err := r.Pool.Exec(ctx, "DELETE FROM cf_runs WHERE run_id = $1", runID)
+	return err
+}
+
+// PostgresAdRepository stores ads in the ads table
+type PostgresAdRepository struct {
+	Pool *pgxpool.Pool
+}
+
+// PutAd creates or fully replaces an ad
+func (r *PostgresAdRepository) PutAd(ctx context.Context, ad models.Ad) error {
+	keywords := ad.Keywords
+	if keywords == nil {
+		keywords = []string{}
+	}
+	var createdAt *time.Time
+	if !ad.CreatedAt.IsZero() {
+		createdAt = &ad.CreatedAt
+	}
+	_, err := r.Pool.Exec(ctx, `INSERT INTO ads (ad_id, category, description, keywords, advertiser, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (ad_id) DO UPDATE SET category = EXCLUDED.category,
+			description = EXCLUDED.description, keywords = EXCLUDED.keywords, advertiser = EXCLUDED.advertiser,
+			status = EXCLUDED.status, created_at = COALESCE(EXCLUDED.created_at, ads.created_at)`,
+		ad.AdID, ad.Category, ad.Description, keywords, ad.Advertiser, ad.StatusOrDefault(), createdAt)
+	return err
+}
+
+// GetAd retrieves an ad by ID
+func (r *PostgresAdRepository) GetAd(ctx context.Context, adID string) (*models.Ad, error) {
+	rows, err := r.Pool.Query(ctx, "SELECT ad_id, category, description, keywords, advertiser, status, created_at FROM ads WHERE ad_id = $1", adID)
+	if err != nil {
+		return nil, err
+	}
+	ad, err := pgx.CollectExactlyOneRow(rows, scanAd)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ad, nil
+}
+
+// DeleteAd removes an ad by ID
+func (r *PostgresAdRepository) DeleteAd(ctx context.Context, adID string) error {
+	_, err := r.Pool.Exec(ctx, "DELETE FROM ads WHERE ad_id = $1", adID)
+	return err
+}
+
+// ListAds returns every ad, ordered by ad ID
+func (r *PostgresAdRepository) ListAds(ctx context.Context) ([]models.Ad, error) {
+	rows, err := r.Pool.Query(ctx, "SELECT ad_id, category, description, keywords, advertiser, status, created_at FROM ads ORDER BY ad_id")
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, scanAd)
+}
+
+// AdsByCategory returns the active ads of one category, ordered by ad ID
+func (r *PostgresAdRepository) AdsByCategory(ctx context.Context, category string) ([]models.Ad, error) {
+	rows, err := r.Pool.Query(ctx, `SELECT ad_id, category, description, keywords, advertiser, status, created_at FROM ads
+		WHERE category = $1 AND status = $2 ORDER BY ad_id`, category, models.AdStatusActive)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, scanAd)
+}
+
+// scanAd reads an ads row selected as (ad_id, category, description, keywords, advertiser, status, created_at)
+func scanAd(row pgx.CollectableRow) (models.Ad, error) {
+	ad := models.Ad{}
+	var createdAt *time.Time
+	err := row.Scan(&ad.AdID, &ad.Category, &ad.Description, &ad.Keywords, &ad.Advertiser, &ad.Status, &createdAt)
+	if createdAt != nil {
+		ad.CreatedAt = *createdAt
+	}
+	return ad, err
+}
+
+// PostgresCategoryMappingRepository stores category mappings in the category_mappings table
+type PostgresCategoryMappingRepository struct {
+	Pool *pgxpool.Pool
+}
+
+// PutCategoryMapping creates or fully replaces a category mapping
+func (r *PostgresCategoryMappingRepository) PutCategoryMapping(ctx context.Context, mapping models.CategoryMapping) error {
+	adCategories := mapping.AdCategories
+	if adCategories == nil {
+		adCategories = []string{}
+	}
+	_, err := r.Pool.Exec(ctx, `INSERT INTO category_mappings (movie_category, ad_categories, weight) VALUES ($1, $2, $3)
+		ON CONFLICT (movie_category) DO UPDATE SET ad_categories = EXCLUDED.ad_categories, weight = EXCLUDED.weight`,
+		mapping.MovieCategory, adCategories, mapping.Weight)
+	return err
+}
+
+// GetCategoryMapping retrieves the mapping for a movie category
+func (r *PostgresCategoryMappingRepository) GetCategoryMapping(ctx context.Context, movieCategory string) (*models.CategoryMapping, error) {
+	mapping := &models.CategoryMapping{MovieCategory: movieCategory}
+	err := r.Pool.QueryRow(ctx, "SELECT ad_categories, weight FROM category_mappings WHERE movie_category = $1", movieCategory).
+		Scan(&mapping.AdCategories, &mapping.Weight)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mapping, nil
+}
+
+// PostgresAdEmbeddingRepository stores ad embeddings in the ad_embeddings table
+type PostgresAdEmbeddingRepository struct {
+	Pool *pgxpool.Pool
+}
+
+// PutAdEmbedding creates or replaces the embedding of an ad for its model
+func (r *PostgresAdEmbeddingRepository) PutAdEmbedding(ctx context.Context, embedding models.AdEmbedding) error {
+	_, err := r.Pool.Exec(ctx, `INSERT INTO ad_embeddings (ad_id, model, model_version, content_hash, vector, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (ad_id, model) DO UPDATE SET model_version = EXCLUDED.model_version,
+			content_hash = EXCLUDED.content_hash, vector = EXCLUDED.vector, updated_at = EXCLUDED.updated_at`,
+		embedding.AdID, embedding.Model, embedding.ModelVersion, embedding.ContentHash, embedding.Vector, embedding.UpdatedAt)
+	return err
+}
+
+// GetAdEmbeddings returns the stored embeddings of the given ads for a model
+func (r *PostgresAdEmbeddingRepository) GetAdEmbeddings(ctx context.Context, model string, adIDs []string) (map[string]models.AdEmbedding, error) {
+	rows, err := r.Pool.Query(ctx, `SELECT ad_id, model, model_version, content_hash, vector, updated_at
+		FROM ad_embeddings WHERE model = $1 AND ad_id = ANY($2)`, model, adIDs)
+	if err != nil {
+		return nil, err
+	}
+	embeddings, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.AdEmbedding, error) {
+		embedding := models.AdEmbedding{}
+		err := row.Scan(&embedding.AdID, &embedding.Model, &embedding.ModelVersion, &embedding.ContentHash, &embedding.Vector, &embedding.UpdatedAt)
+		return embedding, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]models.AdEmbedding, len(embeddings))
+	for _, embedding := range embeddings {
+		found[embedding.AdID] = embedding
+	}
+	return found, nil
+}
+
+// DeleteAdEmbeddings removes the embeddings of an ad for every model
+func (r *PostgresAdEmbeddingRepository) DeleteAdEmbeddings(ctx context.Context, adID string) error {
+	_, err := r.Pool.Exec(ctx, "DELETE FROM ad_embeddings WHERE ad_id = $1", adID)
+	return err
+}
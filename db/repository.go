@@ -0,0 +1,123 @@
+package db
+
+import (
+	"Ad-Recommendations/models"
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned when a requested item does not exist
+var ErrNotFound = errors.New("not found")
+
+// UserRepository stores user profiles
+type UserRepository interface {
+	PutUser(ctx context.Context, user models.User) error
+	GetUser(ctx context.Context, userID string) (*models.User, error)
+	DeleteUser(ctx context.Context, userID string) error
+}
+
+// PlaybackRepository stores playback events
+type PlaybackRepository interface {
+	LogPlayback(ctx context.Context, event models.PlaybackEvent) error
+	// PlaybackHistory returns the events and the cursor of the next older page, if any
+	PlaybackHistory(ctx context.Context, userID string, query HistoryQuery) ([]models.PlaybackEvent, string, error)
+	// PlaybackSince returns the playback events since a time, across all users, oldest first
+	PlaybackSince(ctx context.Context, since time.Time) ([]models.PlaybackEvent, error)
+}
+
+// AdClickRepository stores ad click events
+type AdClickRepository interface {
+	LogAdClick(ctx context.Context, click models.AdClick) error
+	// AdClickHistory returns the clicks and the cursor of the next older page, if any
+	AdClickHistory(ctx context.Context, userID string, query HistoryQuery) ([]models.AdClick, string, error)
+	// AdClickCounts returns the number of clicks on each ad since a time, across all users; ads without clicks are omitted
+	AdClickCounts(ctx context.Context, adIDs []string, since time.Time) (map[string]int, error)
+	// ClicksSince returns the ad clicks since a time, across all users, oldest first
+	ClicksSince(ctx context.Context, since time.Time) ([]models.AdClick, error)
+}
+
+// ImpressionRepository stores served ads and the clicks attributed to them
+type ImpressionRepository interface {
+	// LogImpressions stores served ads and returns those newly stored; an ad
+	// already stored for its recommendation is kept as is and not returned
+	LogImpressions(ctx context.Context, impressions []models.Impression) ([]models.Impression, error)
+	// GetImpression returns ErrNotFound when the ad was not served in the recommendation
+	GetImpression(ctx context.Context, recommendationID, adID string) (*models.Impression, error)
+	// MarkClicked records the first click on an impression. It reports false when
+	// the impression was already clicked and ErrNotFound when it does not exist.
+	MarkClicked(ctx context.Context, recommendationID, adID string, at time.Time) (bool, error)
+	// ImpressionsSince returns the impressions served since a time, across all users
+	ImpressionsSince(ctx context.Context, since time.Time) ([]models.Impression, error)
+	// CountVariantsSince counts the impressions served since a time and their
+	// clicks per experiment and variant, leaving out those of no experiment
+	CountVariantsSince(ctx context.Context, since time.Time) ([]models.VariantCount, error)
+}
+
+// CTRCountRepository stores the counts snapshotted by the CTR estimator
+type CTRCountRepository interface {
+	// AddCTRCounts adds the impressions and clicks of counts to the stored
+	// ones by kind and key, creating missing counts, so that several
+	// estimators can save to the same store. It returns how many of the
+	// counts, in order, were added when it fails.
+	AddCTRCounts(ctx context.Context, counts []models.CTRCount) (int, error)
+	ListCTRCounts(ctx context.Context) ([]models.CTRCount, error)
+}
+
+// CFItemRepository stores the collaborative filtering model written by the
+// cf-train job, one run per training
+type CFItemRepository interface {
+	// PutCFRun creates or replaces a run by ID
+	PutCFRun(ctx context.Context, run models.CFRun) error
+	// CurrentCFRun returns the published run trained last, ErrNotFound if none is published
+	CurrentCFRun(ctx context.Context) (*models.CFRun, error)
+	// ListCFRuns returns every stored run, published or not
+	ListCFRuns(ctx context.Context) ([]models.CFRun, error)
+	// PutCFItems creates or replaces items of a run by kind and ID
+	PutCFItems(ctx context.Context, runID string, items []models.CFItem) error
+	// ListCFItems returns the items of a run
+	ListCFItems(ctx context.Context, runID string) ([]models.CFItem, error)
+	// DeleteCFRun removes a run and its items
+	DeleteCFRun(ctx context.Context, runID string) error
+}
+
+// AdRepository stores the ad inventory
+type AdRepository interface {
+	PutAd(ctx context.Context, ad models.Ad) error
+	GetAd(ctx context.Context, adID string) (*models.Ad, error)
+	DeleteAd(ctx context.Context, adID string) error
+	ListAds(ctx context.Context) ([]models.Ad, error)
+	// AdsByCategory returns only the active ads of a category
+	AdsByCategory(ctx context.Context, category string) ([]models.Ad, error)
+}
+
+// CategoryMappingRepository stores movie category to ad category mappings
+type CategoryMappingRepository interface {
+	PutCategoryMapping(ctx context.Context, mapping models.CategoryMapping) error
+	// GetCategoryMapping returns ErrNotFound when the movie category has no mapping
+	GetCategoryMapping(ctx context.Context, movieCategory string) (*models.CategoryMapping, error)
+}
+
+// AdEmbeddingRepository stores precomputed ad embeddings, one per ad and model
+type AdEmbeddingRepository interface {
+	PutAdEmbedding(ctx context.Context, embedding models.AdEmbedding) error
+	// GetAdEmbeddings returns the stored embeddings of the given ads for a model, keyed by ad ID; missing ads are omitted
+	GetAdEmbeddings(ctx context.Context, model string, adIDs []string) (map[string]models.AdEmbedding, error)
+	// DeleteAdEmbeddings removes the embeddings of an ad for every model
+	DeleteAdEmbeddings(ctx context.Context, adID string) error
+}
+
+// Store groups the repositories of one storage backend
+type Store struct {
+	Users       UserRepository
+	Playback    PlaybackRepository
+	Clicks      AdClickRepository
+	Impressions ImpressionRepository
+	CTRCounts   CTRCountRepository
+	CFItems     CFItemRepository
+	Ads         AdRepository
+	Mappings    CategoryMappingRepository
+	Embeddings  AdEmbeddingRepository
+
+	Capacity *CapacityRecorder // DynamoDB capacity consumed by the ad reads; nil on other backends
+}
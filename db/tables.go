@@ -11,18 +11,32 @@ import (
 
 var (
 	UserTableName            = "Users"
-	PlaybackTableName        = "PlaybackTable"
-	AdClickTableName         = "AdClickTable"
+	PlaybackTableName        = "PlaybackEvents"       // user_id + event_id, one row per playback
+	LegacyPlaybackTableName  = "PlaybackTable"        // user_id only; migrate with cmd/dynamo-migrate
+	AdClickTableName         = "AdClickEvents"        // user_id + event_id, one row per click
+	LegacyAdClickTableName   = "AdClickTable"         // user_id + ad_id; migrate with cmd/dynamo-migrate
 	CategoryMappingTableName = "CategoryMappingTable" // ✅ Define Category Mapping Table
 	AdTableName              = "AdTable"              // ✅ Define Ad Table
+	AdEmbeddingTableName     = "AdEmbeddingTable"     // ad_id + model, precomputed ad embeddings
+	ImpressionTableName      = "AdImpressions"        // recommendation_id + ad_id, one row per served ad
+	CTRCountTableName        = "CTRCounts"            // kind + key, CTR estimator snapshots
+	CFRunTableName           = "CFRuns"               // run_id, collaborative filtering training runs
+	CFItemTableName          = "CFRunItems"           // run_id + item, collaborative filtering factors per run
+
+	AdCategoryIndexName = "category-status-index" // GSI on AdTable: category + status
+	AdClickAdIndexName  = "ad-event-index"        // GSI on AdClickEvents: ad_id + event_id
+
+	ImpressionDayIndexName = "day-time-index"  // GSI on AdImpressions: day + time_key
+	EventDayIndexName      = "day-event-index" // GSI on PlaybackEvents and AdClickEvents: day + event_id
 )
 
 // EnsureTables ensures the existence of required tables in DynamoDB
-func EnsureTables() error {
+func EnsureTables(client *dynamodb.Client) error {
 	tables := []struct {
 		Name          string
 		KeySchema     []types.KeySchemaElement
 		AttributeDefs []types.AttributeDefinition
+		Indexes       []types.GlobalSecondaryIndex
 	}{
 		{
 			Name: UserTableName,
@@ -37,20 +51,93 @@ func EnsureTables() error {
 			Name: PlaybackTableName,
 			KeySchema: []types.KeySchemaElement{
 				{AttributeName: aws.String("user_id"), KeyType: types.KeyTypeHash},
+				{AttributeName: aws.String("event_id"), KeyType: types.KeyTypeRange},
 			},
 			AttributeDefs: []types.AttributeDefinition{
 				{AttributeName: aws.String("user_id"), AttributeType: types.ScalarAttributeTypeS},
+				{AttributeName: aws.String("event_id"), AttributeType: types.ScalarAttributeTypeS},
+				{AttributeName: aws.String("day"), AttributeType: types.ScalarAttributeTypeS},
 			},
+			Indexes: []types.GlobalSecondaryIndex{eventDayIndex()},
 		},
 		{
 			Name: AdClickTableName,
 			KeySchema: []types.KeySchemaElement{
 				{AttributeName: aws.String("user_id"), KeyType: types.KeyTypeHash},
-				{AttributeName: aws.String("ad_id"), KeyType: types.KeyTypeRange},
+				{AttributeName: aws.String("event_id"), KeyType: types.KeyTypeRange},
 			},
 			AttributeDefs: []types.AttributeDefinition{
 				{AttributeName: aws.String("user_id"), AttributeType: types.ScalarAttributeTypeS},
+				{AttributeName: aws.String("event_id"), AttributeType: types.ScalarAttributeTypeS},
+				{AttributeName: aws.String("ad_id"), AttributeType: types.ScalarAttributeTypeS},
+				{AttributeName: aws.String("day"), AttributeType: types.ScalarAttributeTypeS},
+			},
+			Indexes: []types.GlobalSecondaryIndex{
+				{
+					// Counts clicks per ad for the popularity signal
+					IndexName: aws.String(AdClickAdIndexName),
+					KeySchema: []types.KeySchemaElement{
+						{AttributeName: aws.String("ad_id"), KeyType: types.KeyTypeHash},
+						{AttributeName: aws.String("event_id"), KeyType: types.KeyTypeRange},
+					},
+					Projection: &types.Projection{ProjectionType: types.ProjectionTypeKeysOnly},
+				},
+				eventDayIndex(),
+			},
+		},
+		{
+			Name: ImpressionTableName,
+			KeySchema: []types.KeySchemaElement{
+				{AttributeName: aws.String("recommendation_id"), KeyType: types.KeyTypeHash},
+				{AttributeName: aws.String("ad_id"), KeyType: types.KeyTypeRange},
+			},
+			AttributeDefs: []types.AttributeDefinition{
+				{AttributeName: aws.String("recommendation_id"), AttributeType: types.ScalarAttributeTypeS},
 				{AttributeName: aws.String("ad_id"), AttributeType: types.ScalarAttributeTypeS},
+				{AttributeName: aws.String("day"), AttributeType: types.ScalarAttributeTypeS},
+				{AttributeName: aws.String("time_key"), AttributeType: types.ScalarAttributeTypeS},
+			},
+			Indexes: []types.GlobalSecondaryIndex{
+				{
+					// Reads the impressions of recent days; cmd/dynamo-migrate backfills the day of older ones
+					IndexName: aws.String(ImpressionDayIndexName),
+					KeySchema: []types.KeySchemaElement{
+						{AttributeName: aws.String("day"), KeyType: types.KeyTypeHash},
+						{AttributeName: aws.String("time_key"), KeyType: types.KeyTypeRange},
+					},
+					Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+				},
+			},
+		},
+		{
+			Name: CTRCountTableName,
+			KeySchema: []types.KeySchemaElement{
+				{AttributeName: aws.String("kind"), KeyType: types.KeyTypeHash},
+				{AttributeName: aws.String("key"), KeyType: types.KeyTypeRange},
+			},
+			AttributeDefs: []types.AttributeDefinition{
+				{AttributeName: aws.String("kind"), AttributeType: types.ScalarAttributeTypeS},
+				{AttributeName: aws.String("key"), AttributeType: types.ScalarAttributeTypeS},
+			},
+		},
+		{
+			Name: CFRunTableName,
+			KeySchema: []types.KeySchemaElement{
+				{AttributeName: aws.String("run_id"), KeyType: types.KeyTypeHash},
+			},
+			AttributeDefs: []types.AttributeDefinition{
+				{AttributeName: aws.String("run_id"), AttributeType: types.ScalarAttributeTypeS},
+			},
+		},
+		{
+			Name: CFItemTableName,
+			KeySchema: []types.KeySchemaElement{
+				{AttributeName: aws.String("run_id"), KeyType: types.KeyTypeHash},
+				{AttributeName: aws.String("item"), KeyType: types.KeyTypeRange},
+			},
+			AttributeDefs: []types.AttributeDefinition{
+				{AttributeName: aws.String("run_id"), AttributeType: types.ScalarAttributeTypeS},
+				{AttributeName: aws.String("item"), AttributeType: types.ScalarAttributeTypeS},
 			},
 		},
 		{
@@ -69,6 +156,30 @@ func EnsureTables() error {
 			},
 			AttributeDefs: []types.AttributeDefinition{
 				{AttributeName: aws.String("ad_id"), AttributeType: types.ScalarAttributeTypeS},
+				{AttributeName: aws.String("category"), AttributeType: types.ScalarAttributeTypeS},
+				{AttributeName: aws.String("status"), AttributeType: types.ScalarAttributeTypeS},
+			},
+			Indexes: []types.GlobalSecondaryIndex{
+				{
+					// Ads without a status are left out of the index; cmd/dynamo-migrate backfills them
+					IndexName: aws.String(AdCategoryIndexName),
+					KeySchema: []types.KeySchemaElement{
+						{AttributeName: aws.String("category"), KeyType: types.KeyTypeHash},
+						{AttributeName: aws.String("status"), KeyType: types.KeyTypeRange},
+					},
+					Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+				},
+			},
+		},
+		{
+			Name: AdEmbeddingTableName,
+			KeySchema: []types.KeySchemaElement{
+				{AttributeName: aws.String("ad_id"), KeyType: types.KeyTypeHash},
+				{AttributeName: aws.String("model"), KeyType: types.KeyTypeRange},
+			},
+			AttributeDefs: []types.AttributeDefinition{
+				{AttributeName: aws.String("ad_id"), AttributeType: types.ScalarAttributeTypeS},
+				{AttributeName: aws.String("model"), AttributeType: types.ScalarAttributeTypeS},
 			},
 		},
 	}
@@ -76,23 +187,94 @@ func EnsureTables() error {
 	for _, table := range tables {
 		log.Printf("Ensuring table %s exists", table.Name)
 
-		_, err := DynamoClient.CreateTable(context.TODO(), &dynamodb.CreateTableInput{
+		input := &dynamodb.CreateTableInput{
 			TableName:            aws.String(table.Name),
 			KeySchema:            table.KeySchema,
 			AttributeDefinitions: table.AttributeDefs,
 			BillingMode:          types.BillingModePayPerRequest,
-		})
+		}
+		if len(table.Indexes) > 0 {
+			input.GlobalSecondaryIndexes = table.Indexes
+		}
 
+		_, err := client.CreateTable(context.TODO(), input)
 		if err != nil {
 			log.Printf("Table %s might already exist: %v", table.Name, err)
 		} else {
 			log.Printf("Table %s created successfully", table.Name)
 		}
 
-		if err := WaitUntilTableActive(table.Name); err != nil {
+		if err := WaitUntilTableActive(client, table.Name); err != nil {
 			log.Printf("Error waiting for table %s to become active: %v", table.Name, err)
 			return err
 		}
+
+		// Tables created before an index was introduced get it added in place
+		if err := ensureGlobalSecondaryIndexes(client, table.Name, table.AttributeDefs, table.Indexes); err != nil {
+			log.Printf("Error ensuring indexes on table %s: %v", table.Name, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// eventDayIndex reads the events of every user by day, for training on recent
+// events; cmd/dynamo-migrate backfills the day of older events
+func eventDayIndex() types.GlobalSecondaryIndex {
+	return types.GlobalSecondaryIndex{
+		IndexName: aws.String(EventDayIndexName),
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("day"), KeyType: types.KeyTypeHash},
+			{AttributeName: aws.String("event_id"), KeyType: types.KeyTypeRange},
+		},
+		Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+	}
+}
+
+// ensureGlobalSecondaryIndexes adds any of the given indexes missing from an existing table
+func ensureGlobalSecondaryIndexes(client *dynamodb.Client, tableName string, attributeDefs []types.AttributeDefinition, indexes []types.GlobalSecondaryIndex) error {
+	if len(indexes) == 0 {
+		return nil
+	}
+
+	describeOutput, err := client.DescribeTable(context.TODO(), &dynamodb.DescribeTableInput{
+		TableName: aws.String(tableName),
+	})
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]bool)
+	for _, index := range describeOutput.Table.GlobalSecondaryIndexes {
+		existing[aws.ToString(index.IndexName)] = true
+	}
+
+	for _, index := range indexes {
+		if existing[aws.ToString(index.IndexName)] {
+			continue
+		}
+
+		log.Printf("Adding index %s to table %s", aws.ToString(index.IndexName), tableName)
+		_, err := client.UpdateTable(context.TODO(), &dynamodb.UpdateTableInput{
+			TableName:            aws.String(tableName),
+			AttributeDefinitions: attributeDefs,
+			GlobalSecondaryIndexUpdates: []types.GlobalSecondaryIndexUpdate{
+				{Create: &types.CreateGlobalSecondaryIndexAction{
+					IndexName:  index.IndexName,
+					KeySchema:  index.KeySchema,
+					Projection: index.Projection,
+				}},
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		// DynamoDB allows one index creation at a time per table
+		if err := WaitUntilTableActive(client, tableName); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -1,45 +1,95 @@
 package handlers
 
 import (
+	"Ad-Recommendations/db"
+	"Ad-Recommendations/models"
 	"Ad-Recommendations/services"
 	"Ad-Recommendations/utils"
 	"encoding/json"
+	"errors"
 	"net/http"
 )
 
 // AdClickHandler handles ad click events
-func AdClickHandler(w http.ResponseWriter, r *http.Request) {
-	var input struct {
-		UserID string `json:"userID"`
-		AdID   string `json:"adID"`
-	}
+func AdClickHandler(adClickService *services.AdClickService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var input struct {
+			UserID           string `json:"userID"`
+			AdID             string `json:"adID"`
+			RecommendationID string `json:"recommendationID"` // Optional, from /recommend or /impression
+		}
 
-	// Parse the request body
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		utils.LogError("Failed to decode request body: " + err.Error())
-		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
-		return
-	}
+		// Parse the request body
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			utils.LogError("Failed to decode request body: " + err.Error())
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
 
-	// Validate input
-	if input.UserID == "" {
-		utils.LogError("userID is missing in the request")
-		utils.RespondWithError(w, http.StatusBadRequest, "userID is required")
-		return
-	}
-	if input.AdID == "" {
-		utils.LogError("adID is missing in the request")
-		utils.RespondWithError(w, http.StatusBadRequest, "adID is required")
-		return
-	}
+		// Validate input
+		if input.UserID == "" {
+			utils.LogError("userID is missing in the request")
+			utils.RespondWithError(w, http.StatusBadRequest, "userID is required")
+			return
+		}
+		if input.AdID == "" {
+			utils.LogError("adID is missing in the request")
+			utils.RespondWithError(w, http.StatusBadRequest, "adID is required")
+			return
+		}
+
+		// Log the ad click
+		if err := adClickService.LogAdClick(r.Context(), input.UserID, input.AdID, input.RecommendationID); err != nil {
+			utils.LogError("Failed to log ad click: " + err.Error())
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to log ad click")
+			return
+		}
 
-	// Log the ad click
-	if err := services.LogAdClick(input.UserID, input.AdID); err != nil {
-		utils.LogError("Failed to log ad click: " + err.Error())
-		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to log ad click")
-		return
+		utils.LogInfo("Ad click logged successfully: UserID=" + input.UserID + ", AdID=" + input.AdID)
+		utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Ad click logged successfully"})
 	}
+}
 
-	utils.LogInfo("Ad click logged successfully: UserID=" + input.UserID + ", AdID=" + input.AdID)
-	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Ad click logged successfully"})
+// AdClickHistoryHandler returns a page of a user's ad clicks, newest pages first,
+// optionally limited to a time window (since, until). With counts=true it
+// returns the number of clicks per ad in the window instead.
+func AdClickHistoryHandler(adClickService *services.AdClickService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.URL.Query().Get("user_id")
+		if userID == "" {
+			utils.RespondWithError(w, http.StatusBadRequest, "Missing user_id")
+			return
+		}
+
+		query, err := parseHistoryQuery(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if r.URL.Query().Get("counts") == "true" {
+			counts, err := adClickService.CountAdClicks(r.Context(), userID, query)
+			if err != nil {
+				utils.RespondWithError(w, http.StatusInternalServerError, "Failed to count ad clicks")
+				return
+			}
+			utils.RespondWithJSON(w, http.StatusOK, counts)
+			return
+		}
+
+		clicks, next, err := adClickService.GetAdClickHistory(r.Context(), userID, query)
+		if errors.Is(err, db.ErrInvalidCursor) {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch ad click history")
+			return
+		}
+		if clicks == nil {
+			clicks = []models.AdClick{}
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, ListResponse{Items: clicks, NextCursor: next})
+	}
 }
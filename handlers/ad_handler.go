@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"Ad-Recommendations/models"
+	"Ad-Recommendations/services"
+	"Ad-Recommendations/utils"
+	"encoding/json"
+	"net/http"
+)
+
+// AddAdHandler handles creating a new ad
+func AddAdHandler(adService *services.AdService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var ad models.Ad
+		if err := json.NewDecoder(r.Body).Decode(&ad); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+
+		if ad.AdID == "" {
+			utils.RespondWithError(w, http.StatusBadRequest, "adID cannot be empty")
+			return
+		}
+
+		if err := adService.AddAd(r.Context(), ad); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to add ad: "+err.Error())
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusCreated, map[string]string{"message": "Ad added successfully"})
+	}
+}
+
+// UpdateAdHandler handles replacing an existing ad
+func UpdateAdHandler(adService *services.AdService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var ad models.Ad
+		if err := json.NewDecoder(r.Body).Decode(&ad); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+
+		if err := adService.UpdateAd(r.Context(), ad); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update ad: "+err.Error())
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Ad updated successfully"})
+	}
+}
+
+// DeleteAdHandler handles deleting an ad
+func DeleteAdHandler(adService *services.AdService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		adID := r.URL.Query().Get("adID")
+		if adID == "" {
+			utils.RespondWithError(w, http.StatusBadRequest, "Missing adID")
+			return
+		}
+
+		if err := adService.DeleteAd(r.Context(), adID); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to delete ad: "+err.Error())
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Ad deleted successfully"})
+	}
+}
@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"Ad-Recommendations/db"
+	"Ad-Recommendations/utils"
+	"net/http"
+)
+
+// DynamoCapacityHandler returns the DynamoDB capacity units consumed per operation since startup
+func DynamoCapacityHandler(capacity *db.CapacityRecorder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		utils.RespondWithJSON(w, http.StatusOK, capacity.Snapshot())
+	}
+}
@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"Ad-Recommendations/services"
+	"Ad-Recommendations/utils"
+	"net/http"
+)
+
+// EmbeddingCacheStatsHandler returns the hit and miss counters of the embedding cache
+func EmbeddingCacheStatsHandler(cache *services.CachingEmbedder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		utils.RespondWithJSON(w, http.StatusOK, cache.Stats())
+	}
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"Ad-Recommendations/services"
+	"Ad-Recommendations/utils"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ExperimentReportHandler compares the click-through rates of the variants of
+// every experiment over the impressions served since the optional RFC3339
+// since parameter, or over the last window. since must be in the past and
+// within maxWindow.
+func ExperimentReportHandler(experimentService *services.ExperimentService, window, maxWindow time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now().UTC()
+		since := now.Add(-window)
+		if value := r.URL.Query().Get("since"); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				utils.RespondWithError(w, http.StatusBadRequest, "Invalid since")
+				return
+			}
+			since = parsed
+		}
+		if since.After(now) {
+			utils.RespondWithError(w, http.StatusBadRequest, "since must not be in the future")
+			return
+		}
+		if now.Sub(since) > maxWindow {
+			utils.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("since must be within the last %s", maxWindow))
+			return
+		}
+
+		reports, err := experimentService.Report(r.Context(), since)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to build experiment report")
+			return
+		}
+		utils.RespondWithJSON(w, http.StatusOK, reports)
+	}
+}
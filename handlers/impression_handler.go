@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"Ad-Recommendations/services"
+	"Ad-Recommendations/utils"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ImpressionRequest reports ads shown to a user, in the order shown
+type ImpressionRequest struct {
+	UserID           string   `json:"user_id"`
+	RecommendationID string   `json:"recommendation_id"` // Optional; a new ID is returned when empty
+	Segment          string   `json:"segment"`           // Optional user segment for click-through rates
+	AdIDs            []string `json:"ad_ids"`
+}
+
+// ImpressionHandler records impressions and returns the recommendation ID that
+// clicks on the shown ads should carry
+func ImpressionHandler(impressionService *services.ImpressionService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req ImpressionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if req.UserID == "" || len(req.AdIDs) == 0 {
+			utils.RespondWithError(w, http.StatusBadRequest, "Missing user_id or ad_ids")
+			return
+		}
+
+		recommendationID, err := impressionService.LogImpressions(r.Context(), req.UserID, req.RecommendationID, req.Segment, req.AdIDs)
+		if errors.Is(err, services.ErrUnknownAd) {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to log impressions")
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, map[string]string{"recommendation_id": recommendationID})
+	}
+}
+
+// CTRHandler returns the rolling click-through rates per ad, category and segment
+func CTRHandler(ctr *services.CTRTracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		utils.RespondWithJSON(w, http.StatusOK, ctr.Report())
+	}
+}
@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"Ad-Recommendations/db"
 	"Ad-Recommendations/services"
+	"Ad-Recommendations/utils"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"time"
@@ -14,36 +17,76 @@ type PlaybackEvent struct {
 	Timestamp     string `json:"timestamp"`
 }
 
-func PlaybackHandler(w http.ResponseWriter, r *http.Request) {
-	var playbackEvent PlaybackEvent
+// PlaybackHandler records playback events
+func PlaybackHandler(playbackService *services.PlaybackService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var playbackEvent PlaybackEvent
 
-	err := json.NewDecoder(r.Body).Decode(&playbackEvent)
-	if err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
-		log.Println("❌ Failed to decode request body:", err)
-		return
-	}
+		err := json.NewDecoder(r.Body).Decode(&playbackEvent)
+		if err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			log.Println("❌ Failed to decode request body:", err)
+			return
+		}
 
-	if playbackEvent.UserID == "" || playbackEvent.MovieCategory == "" {
-		http.Error(w, "Missing user_id or movie_category", http.StatusBadRequest)
-		log.Println("❌ Missing required fields: user_id or movie_category")
-		return
-	}
+		if playbackEvent.UserID == "" || playbackEvent.MovieCategory == "" {
+			http.Error(w, "Missing user_id or movie_category", http.StatusBadRequest)
+			log.Println("❌ Missing required fields: user_id or movie_category")
+			return
+		}
 
-	if playbackEvent.Timestamp == "" {
-		playbackEvent.Timestamp = time.Now().UTC().Format(time.RFC3339)
-	}
+		if playbackEvent.Timestamp == "" {
+			playbackEvent.Timestamp = time.Now().UTC().Format(time.RFC3339)
+		}
+
+		timestamp, err := time.Parse(time.RFC3339, playbackEvent.Timestamp)
+		if err != nil {
+			http.Error(w, "Invalid timestamp, expected RFC3339", http.StatusBadRequest)
+			log.Println("❌ Invalid playback timestamp:", playbackEvent.Timestamp)
+			return
+		}
+
+		log.Println("🟢 Logging playback:", playbackEvent)
 
-	log.Println("🟢 Logging playback to DynamoDB:", playbackEvent)
+		err = playbackService.LogPlayback(r.Context(), playbackEvent.UserID, playbackEvent.MovieCategory, timestamp)
+		if err != nil {
+			http.Error(w, "Failed to record playback event", http.StatusInternalServerError)
+			log.Println("❌ Error saving playback:", err)
+			return
+		}
 
-	err = services.LogPlayback(playbackEvent.UserID, playbackEvent.MovieCategory)
-	if err != nil {
-		http.Error(w, "Failed to record playback event", http.StatusInternalServerError)
-		log.Println("❌ Error saving playback:", err)
-		return
+		log.Println("✅ Playback recorded successfully:", playbackEvent)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"message": "Playback recorded successfully"}`))
 	}
+}
+
+// PlaybackHistoryHandler returns a page of a user's playback events, newest
+// pages first, optionally limited to a time window (since, until)
+func PlaybackHistoryHandler(playbackService *services.PlaybackService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.URL.Query().Get("user_id")
+		if userID == "" {
+			utils.RespondWithError(w, http.StatusBadRequest, "Missing user_id")
+			return
+		}
+
+		query, err := parseHistoryQuery(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 
-	log.Println("✅ Playback recorded successfully:", playbackEvent)
-	w.WriteHeader(http.StatusCreated)
-	w.Write([]byte(`{"message": "Playback recorded successfully"}`))
+		events, next, err := playbackService.GetPlaybackHistory(r.Context(), userID, query)
+		if errors.Is(err, db.ErrInvalidCursor) {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch playback history")
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, ListResponse{Items: events, NextCursor: next})
+	}
 }
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"Ad-Recommendations/db"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Page sizes for endpoints that list data
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// ListResponse is the body of endpoints that list data with cursor pagination
+type ListResponse struct {
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"next_cursor,omitempty"` // Pass back as ?cursor= for the next page
+}
+
+// parseHistoryQuery reads the optional limit, cursor, since and until query
+// parameters. since and until are RFC3339 timestamps; limit is the page size.
+func parseHistoryQuery(r *http.Request) (db.HistoryQuery, error) {
+	query := db.HistoryQuery{Limit: defaultPageSize}
+	params := r.URL.Query()
+
+	if value := params.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return query, fmt.Errorf("invalid limit %q", value)
+		}
+		if limit > maxPageSize {
+			limit = maxPageSize
+		}
+		query.Limit = limit
+	}
+
+	query.Cursor = params.Get("cursor")
+
+	if value := params.Get("since"); value != "" {
+		since, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return query, fmt.Errorf("invalid since %q", value)
+		}
+		query.Since = since
+	}
+
+	if value := params.Get("until"); value != "" {
+		until, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return query, fmt.Errorf("invalid until %q", value)
+		}
+		query.Until = until
+	}
+
+	return query, nil
+}
@@ -1,45 +1,120 @@
 package handlers
 
 import (
+	"Ad-Recommendations/models"
 	"Ad-Recommendations/services"
 	"encoding/json"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 // RecommendationRequest represents the incoming recommendation request data
+// with optional "limit", "weights" and "min_score" overrides of the configured ranking
 type RecommendationRequest struct {
-	UserID string `json:"user_id"`
+	UserID  string `json:"user_id"`
+	Explain bool   `json:"explain"` // Also enabled by ?explain=true
+	Segment string `json:"segment"` // Optional user segment recorded with logged impressions
+	services.RankingOverrides
 }
 
-// RecommendationHandler handles HTTP requests to generate ad recommendations
-func RecommendationHandler(w http.ResponseWriter, r *http.Request) {
-	log.Println("🟢 RecommendationHandler triggered")
-	// Parse the JSON body
-	var req RecommendationRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
-		return
-	}
+// RecommendationIDHeader carries the ID of a served list logged as impressions
+const RecommendationIDHeader = "X-Recommendation-ID"
+
+// Experiment headers name the experiment and variant the user was ranked
+// for, when the user takes part in one
+const (
+	ExperimentHeader = "X-Experiment"
+	VariantHeader    = "X-Experiment-Variant"
+)
 
-	// Validate input
-	if req.UserID == "" {
-		http.Error(w, "Missing user_id", http.StatusBadRequest)
-		return
+// ExplorationSlotsHeader lists the comma-separated 1-based positions of the
+// served ads placed by exploration rather than by their rank
+const ExplorationSlotsHeader = "X-Exploration-Slots"
+
+// RecommendationHandler handles HTTP requests to generate ad recommendations.
+// When impression logging is configured, the served list is logged and its
+// recommendation ID returned in the X-Recommendation-ID header. Exploration
+// slots are returned in the X-Exploration-Slots header. Users in an A/B
+// experiment are ranked by their variant, named in the experiment headers.
+func RecommendationHandler(recommendationService *services.RecommendationService, impressionService *services.ImpressionService, experimentService *services.ExperimentService) http.HandlerFunc {
+	// logServed logs the served ads as impressions and sets the recommendation ID header
+	logServed := func(w http.ResponseWriter, r *http.Request, req RecommendationRequest, recommendations []services.Recommendation) {
+		if !recommendationService.Config.LogImpressions || impressionService == nil || len(recommendations) == 0 {
+			return
+		}
+		recommendationID, err := impressionService.LogServed(r.Context(), req.UserID, req.Segment, recommendations)
+		if err != nil {
+			log.Printf("⚠️ Serving recommendations without impression logging: %v", err)
+			return
+		}
+		w.Header().Set(RecommendationIDHeader, recommendationID)
 	}
 
-	log.Printf("Processing recommendation request for user: %s", req.UserID)
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("🟢 RecommendationHandler triggered")
+		// Parse the JSON body
+		var req RecommendationRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
 
-	// Generate recommendations (this function now fetches user history internally)
-	recommendations := services.GenerateRecommendations(req.UserID)
+		// Validate input
+		if req.UserID == "" {
+			http.Error(w, "Missing user_id", http.StatusBadRequest)
+			return
+		}
 
-	// Check if recommendations were generated
-	if recommendations == nil {
-		http.Error(w, "Failed to generate recommendations", http.StatusInternalServerError)
-		return
-	}
+		recommender := recommendationService
+		if experimentService != nil {
+			var assignment services.Assignment
+			recommender, assignment = experimentService.Recommender(req.UserID)
+			if assignment.Experiment != "" {
+				w.Header().Set(ExperimentHeader, assignment.Experiment)
+				w.Header().Set(VariantHeader, assignment.Variant)
+			}
+		}
+
+		opts, err := services.ResolveRankingOptions(recommender.Config, req.RankingOverrides)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-	// Respond with recommendations
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(recommendations)
+		log.Printf("Processing recommendation request for user: %s", req.UserID)
+
+		// Generate recommendations (this function now fetches user history internally)
+		recommendations := recommender.ExplainRecommendations(r.Context(), req.UserID, opts)
+
+		// Check if recommendations were generated
+		if recommendations == nil {
+			http.Error(w, "Failed to generate recommendations", http.StatusInternalServerError)
+			return
+		}
+
+		logServed(w, r, req, recommendations)
+		slots := []string{}
+		for i, recommendation := range recommendations {
+			if recommendation.Explanation.Explored {
+				slots = append(slots, strconv.Itoa(i+1))
+			}
+		}
+		if len(slots) > 0 {
+			w.Header().Set(ExplorationSlotsHeader, strings.Join(slots, ","))
+		}
+		w.Header().Set("Content-Type", "application/json")
+
+		// Explained recommendations carry per-ad score breakdowns instead of bare ads
+		if req.Explain || r.URL.Query().Get("explain") == "true" {
+			json.NewEncoder(w).Encode(recommendations)
+			return
+		}
+		ads := make([]models.Ad, len(recommendations))
+		for i, recommendation := range recommendations {
+			ads[i] = recommendation.Ad
+		}
+		json.NewEncoder(w).Encode(ads)
+	}
 }
@@ -1,46 +1,162 @@
 package main
 
 import (
+	"Ad-Recommendations/config"
 	"Ad-Recommendations/db"
 	"Ad-Recommendations/handlers"
+	"Ad-Recommendations/services"
+	"Ad-Recommendations/services/ann"
 	"Ad-Recommendations/utils"
+	"context"
+	"fmt"
 	"log"
 	"net/http"
-	"os"
+
+	"github.com/go-redis/redis/v8"
 )
 
 func main() {
 	// Log server start
 	utils.LogInfo("Initializing Ad Recommendation System")
 
-	// Load environment variables (e.g., AWS credentials)
-	awsAccessKey := os.Getenv("AWS_ACCESS_KEY_ID")
-	awsSecretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
-	awsRegion := os.Getenv("AWS_REGION")
+	cfg, err := config.Load()
+	if err != nil {
+		utils.LogError("Invalid configuration: " + err.Error())
+		log.Fatal("Failed to start application: invalid configuration.")
+	}
+
+	store, err := db.OpenStore(context.Background(), cfg)
+	if err != nil {
+		utils.LogError("Failed to initialize storage: " + err.Error())
+		log.Fatal("Failed to start application: unable to initialize storage.")
+	}
+
+	// Optionally seed the store from a fixture file
+	if cfg.FixtureFile != "" {
+		if err := db.LoadFixture(context.Background(), store, cfg.FixtureFile); err != nil {
+			utils.LogError("Failed to load fixture: " + err.Error())
+			log.Fatal("Failed to start application: unable to load fixture.")
+		}
+	}
+
+	embedder, err := services.NewEmbedder(cfg.Embedding)
+	if err != nil {
+		utils.LogError("Failed to initialize embedder: " + err.Error())
+		log.Fatal("Failed to start application: unable to initialize embedder.")
+	}
+
+	// Cache embeddings by model and text hash, optionally shared through Redis
+	var embeddingCache *services.CachingEmbedder
+	if cfg.EmbeddingCache.Size > 0 {
+		var remote services.RemoteEmbeddingCache
+		if cfg.EmbeddingCache.RedisAddr != "" {
+			remote = &services.RedisEmbeddingCache{Client: redis.NewClient(&redis.Options{
+				Addr:     cfg.EmbeddingCache.RedisAddr,
+				Password: cfg.EmbeddingCache.RedisPassword,
+				DB:       cfg.EmbeddingCache.RedisDB,
+			})}
+		}
+		model := cfg.Embedding.Model + "@" + cfg.Embedding.ModelVersion
+		embeddingCache = services.NewCachingEmbedder(embedder, model, cfg.EmbeddingCache.Size, cfg.EmbeddingCache.TTL, remote)
+		embedder = embeddingCache
+	}
 
-	if awsAccessKey == "" || awsSecretKey == "" || awsRegion == "" {
-		utils.LogError("AWS credentials or region not set. Please configure them using environment variables.")
-		log.Fatal("Failed to start application: missing AWS configuration.")
+	// Wire services to the storage backend
+	userService := services.NewUserService(store.Users)
+	playbackService := services.NewPlaybackService(store.Playback)
+	adClickService := services.NewAdClickService(store.Clicks)
+	adEmbeddingService := services.NewAdEmbeddingService(store, embedder, cfg.Embedding)
+	if cfg.Recommendation.ANNCandidates > 0 {
+		adEmbeddingService.Index = ann.New(ann.Config{M: cfg.ANN.M, EfConstruction: cfg.ANN.EfConstruction, EfSearch: cfg.ANN.EfSearch})
 	}
+	textIndex := services.NewTextIndex()
+	adService := services.NewAdService(store.Ads, adEmbeddingService, textIndex)
+	ctrTracker := services.NewCTRTracker(cfg.CTR.Window, cfg.CTR.Buckets)
+	impressionService := services.NewImpressionService(store, ctrTracker)
+	adClickService.Impressions = impressionService
 
-	// Initialize the DynamoDB client
-	db.InitDynamoDB()
+	// Smoothed CTR estimates are updated by the impression service, scored by the pipeline and sampled by Thompson exploration
+	ctrEstimator := services.NewCTREstimator(store.CTRCounts, cfg.CTR.PriorStrength)
+	if _, err := ctrEstimator.Load(context.Background()); err != nil {
+		utils.LogError("Failed to load CTR estimates: " + err.Error())
+	}
+	go ctrEstimator.RunSnapshots(context.Background(), cfg.CTR.SnapshotInterval)
+	impressionService.Estimator = ctrEstimator
 
-	// Ensure required DynamoDB tables are available
-	if err := db.EnsureTables(); err != nil {
-		utils.LogError("Failed to ensure DynamoDB tables: " + err.Error())
-		log.Fatal("Failed to start application: unable to initialize DynamoDB tables.")
+	// Collaborative filtering serves the model last stored by cmd/cf-train, reloaded as it is retrained
+	cfModel := services.NewCFModel(store.CFItems, cfg.CF)
+	if _, err := cfModel.Load(context.Background()); err != nil {
+		utils.LogError("Failed to load collaborative filtering model: " + err.Error())
+	}
+	go cfModel.RunReloads(context.Background(), cfg.CF.ReloadInterval)
+
+	// Every pipeline, of the configuration or of an experiment variant, also scores and explores by CTR
+	newPipeline := func(recommendationConfig config.RecommendationConfig) *services.Pipeline {
+		pipeline := services.NewPipeline(recommendationConfig, store, adEmbeddingService, textIndex, cfModel)
+		pipeline.Scorers = append(pipeline.Scorers, &services.CTRScorer{Estimator: ctrEstimator})
+		pipeline.Explorer = services.NewExplorer(recommendationConfig, ctrEstimator)
+		return pipeline
+	}
+	recommendationService := services.NewRecommendationService(store, cfg.Recommendation, adEmbeddingService, textIndex, newPipeline)
+	impressionService.Profiles = recommendationService
+
+	// Users in an A/B experiment are ranked, and their impressions and clicks tagged, by variant
+	experimentService := services.NewExperimentService(cfg.Experiments, store, recommendationService, newPipeline)
+	impressionService.Experiments = experimentService
+	adClickService.Experiments = experimentService
+
+	// Restore the rolling click-through rates from the stored impressions
+	if _, err := impressionService.LoadCTR(context.Background()); err != nil {
+		utils.LogError("Failed to load click-through rates: " + err.Error())
+	}
+
+	// Build the text index up front; ads missing from it are indexed on first use
+	if ads, err := store.Ads.ListAds(context.Background()); err != nil {
+		utils.LogError("Failed to build text index: " + err.Error())
+	} else {
+		textIndex.Rebuild(ads)
+		utils.LogInfo(fmt.Sprintf("Indexed %d ads for text scoring", textIndex.Len()))
+	}
+
+	// Rebuild the nearest-neighbour index from the stored ad embeddings
+	if _, err := adEmbeddingService.BuildIndex(context.Background()); err != nil {
+		utils.LogError("Failed to build nearest-neighbour index: " + err.Error())
+	}
+
+	// Warm the embedding cache with the stored ad embeddings
+	if embeddingCache != nil && cfg.EmbeddingCache.WarmUp {
+		if _, err := adEmbeddingService.WarmCache(context.Background(), embeddingCache); err != nil {
+			utils.LogError("Failed to warm embedding cache: " + err.Error())
+		}
 	}
 
 	// Setup HTTP handlers
-	http.Handle("/recommend", utils.CorsMiddleware(http.HandlerFunc(handlers.RecommendationHandler)))
-	http.Handle("/playback", utils.CorsMiddleware(http.HandlerFunc(handlers.PlaybackHandler)))
-	http.Handle("/ad-click", utils.CorsMiddleware(http.HandlerFunc(handlers.AdClickHandler)))
+	http.Handle("/recommend", utils.CorsMiddleware(handlers.RecommendationHandler(recommendationService, impressionService, experimentService)))
+	http.Handle("/playback", utils.CorsMiddleware(handlers.PlaybackHandler(playbackService)))
+	http.Handle("/playback-history", utils.CorsMiddleware(handlers.PlaybackHistoryHandler(playbackService)))
+	http.Handle("/ad-click", utils.CorsMiddleware(handlers.AdClickHandler(adClickService)))
+	http.Handle("/ad-click-history", utils.CorsMiddleware(handlers.AdClickHistoryHandler(adClickService)))
+	http.Handle("/impression", utils.CorsMiddleware(handlers.ImpressionHandler(impressionService)))
+	http.Handle("/ctr", utils.CorsMiddleware(handlers.CTRHandler(ctrTracker)))
+	http.Handle("/experiments/report", utils.CorsMiddleware(handlers.ExperimentReportHandler(experimentService, cfg.Experiments.ReportWindow, cfg.Experiments.ReportMaxWindow)))
+
+	// Ad management; ads are embedded as they are written
+	http.Handle("/add-ad", utils.CorsMiddleware(handlers.AddAdHandler(adService)))
+	http.Handle("/update-ad", utils.CorsMiddleware(handlers.UpdateAdHandler(adService)))
+	http.Handle("/delete-ad", utils.CorsMiddleware(handlers.DeleteAdHandler(adService)))
+
+	if embeddingCache != nil {
+		http.Handle("/embedding-cache-stats", utils.CorsMiddleware(handlers.EmbeddingCacheStatsHandler(embeddingCache)))
+	}
+
+	if store.Capacity != nil {
+		http.Handle("/dynamo-capacity", utils.CorsMiddleware(handlers.DynamoCapacityHandler(store.Capacity)))
+	}
 
 	// User management (optional, for dynamic user management)
-	http.Handle("/add-user", utils.CorsMiddleware(http.HandlerFunc(handlers.AddUserHandler)))
-	http.Handle("/update-user", utils.CorsMiddleware(http.HandlerFunc(handlers.UpdateUserHandler)))
-	http.Handle("/delete-user", utils.CorsMiddleware(http.HandlerFunc(handlers.DeleteUserHandler)))
+	http.Handle("/add-user", utils.CorsMiddleware(handlers.AddUserHandler(userService)))
+	http.Handle("/update-user", utils.CorsMiddleware(handlers.UpdateUserHandler(userService)))
+	http.Handle("/delete-user", utils.CorsMiddleware(handlers.DeleteUserHandler(userService)))
 
 	// Start the server
 	port := ":8082"
@@ -1,20 +1,105 @@
 package models
 
 import (
+	"Ad-Recommendations/db/paginate"
 	"context"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
+// Ad statuses; only active ads are candidates for recommendation
+const (
+	AdStatusActive = "active"
+	AdStatusPaused = "paused"
+)
+
 // Ad represents an advertisement
 type Ad struct {
-	AdID        string   `json:"ad_id"`
-	Category    string   `json:"category"`
-	Description string   `json:"description"`
-	Keywords    []string `json:"keywords"`
+	AdID        string    `json:"ad_id"`
+	Category    string    `json:"category"`
+	Description string    `json:"description"`
+	Keywords    []string  `json:"keywords"`
+	Advertiser  string    `json:"advertiser,omitempty"`
+	Status      string    `json:"status,omitempty"` // Empty means active
+	CreatedAt   time.Time `json:"created_at"`       // Zero for ads created before it was recorded
+}
+
+// StatusOrDefault returns the ad status, treating an unset status as active
+func (a *Ad) StatusOrDefault() string {
+	if a.Status == "" {
+		return AdStatusActive
+	}
+	return a.Status
+}
+
+// IsActive reports whether the ad can be recommended
+func (a *Ad) IsActive() bool {
+	return a.StatusOrDefault() == AdStatusActive
+}
+
+// ToDynamoDBItem converts an Ad object to a DynamoDB item
+func (a *Ad) ToDynamoDBItem() map[string]types.AttributeValue {
+	item := map[string]types.AttributeValue{
+		"ad_id":       &types.AttributeValueMemberS{Value: a.AdID},
+		"category":    &types.AttributeValueMemberS{Value: a.Category},
+		"description": &types.AttributeValueMemberS{Value: a.Description},
+		"status":      &types.AttributeValueMemberS{Value: a.StatusOrDefault()},
+	}
+
+	// DynamoDB rejects empty string sets
+	if len(a.Keywords) > 0 {
+		item["keywords"] = &types.AttributeValueMemberSS{Value: a.Keywords}
+	}
+
+	if a.Advertiser != "" {
+		item["advertiser"] = &types.AttributeValueMemberS{Value: a.Advertiser}
+	}
+
+	if !a.CreatedAt.IsZero() {
+		item["created_at"] = &types.AttributeValueMemberS{Value: a.CreatedAt.UTC().Format(time.RFC3339Nano)}
+	}
+
+	return item
+}
+
+// AdFromDynamoDBItem converts a DynamoDB item to an Ad struct
+func AdFromDynamoDBItem(item map[string]types.AttributeValue) Ad {
+	ad := Ad{}
+
+	// Safely extract attributes
+	if adID, ok := item["ad_id"].(*types.AttributeValueMemberS); ok {
+		ad.AdID = adID.Value
+	}
+
+	if category, ok := item["category"].(*types.AttributeValueMemberS); ok {
+		ad.Category = category.Value
+	}
+
+	if description, ok := item["description"].(*types.AttributeValueMemberS); ok {
+		ad.Description = description.Value
+	}
+
+	if keywords, ok := item["keywords"].(*types.AttributeValueMemberSS); ok {
+		ad.Keywords = keywords.Value
+	}
+
+	if advertiser, ok := item["advertiser"].(*types.AttributeValueMemberS); ok {
+		ad.Advertiser = advertiser.Value
+	}
+
+	if status, ok := item["status"].(*types.AttributeValueMemberS); ok {
+		ad.Status = status.Value
+	}
+
+	if createdAt, ok := item["created_at"].(*types.AttributeValueMemberS); ok {
+		ad.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdAt.Value)
+	}
+
+	return ad
 }
 
 // FetchAllAds retrieves all ads from the DynamoDB Ads table
@@ -24,10 +109,10 @@ func FetchAllAds(ctx context.Context, dynamoClient *dynamodb.Client, tableName s
 		return nil, fmt.Errorf("tableName cannot be empty")
 	}
 
-	// Scan the Ads table
-	output, err := dynamoClient.Scan(ctx, &dynamodb.ScanInput{
+	// Scan every page of the Ads table
+	page, err := paginate.Scan(ctx, dynamoClient, &dynamodb.ScanInput{
 		TableName: &tableName,
-	})
+	}, 0)
 	if err != nil {
 		log.Printf("Failed to scan Ads table: %v", err)
 		return nil, fmt.Errorf("failed to scan Ads table: %w", err)
@@ -36,27 +121,8 @@ func FetchAllAds(ctx context.Context, dynamoClient *dynamodb.Client, tableName s
 	ads := []Ad{}
 
 	// Parse the results
-	for _, item := range output.Items {
-		ad := Ad{}
-
-		// Safely extract attributes
-		if adID, ok := item["ad_id"].(*types.AttributeValueMemberS); ok {
-			ad.AdID = adID.Value
-		}
-
-		if category, ok := item["category"].(*types.AttributeValueMemberS); ok {
-			ad.Category = category.Value
-		}
-
-		if description, ok := item["description"].(*types.AttributeValueMemberS); ok {
-			ad.Description = description.Value
-		}
-
-		if keywords, ok := item["keywords"].(*types.AttributeValueMemberSS); ok {
-			ad.Keywords = keywords.Value
-		}
-
-		ads = append(ads, ad)
+	for _, item := range page.Items {
+		ads = append(ads, AdFromDynamoDBItem(item))
 	}
 
 	return ads, nil
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// AdClick represents an ad click event
+type AdClick struct {
+	EventID   string    `json:"event_id,omitempty"` // Assigned by the store, sortable by time
+	UserID    string    `json:"user_id"`
+	AdID      string    `json:"ad_id"`
+	Timestamp time.Time `json:"timestamp"`
+
+	RecommendationID string `json:"recommendation_id,omitempty"` // Recommendation the ad was served in, if known
+	Experiment       string `json:"experiment,omitempty"`        // Experiment the user took part in when clicking
+	Variant          string `json:"variant,omitempty"`           // Variant of the experiment the user was assigned to
+}
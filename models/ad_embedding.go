@@ -0,0 +1,33 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// AdEmbedding is a stored embedding of an ad description for one model
+type AdEmbedding struct {
+	AdID         string    `json:"ad_id"`
+	Model        string    `json:"model"`
+	ModelVersion string    `json:"model_version"`
+	ContentHash  string    `json:"content_hash"` // ContentHash of the embedded text
+	Vector       []float64 `json:"vector"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// EmbeddingText returns the text of an ad that is embedded for ranking
+func (a *Ad) EmbeddingText() string {
+	return a.Description
+}
+
+// ContentHash returns a short stable hash of a text, used to detect changed descriptions
+func ContentHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:16])
+}
+
+// IsCurrent reports whether the embedding was computed by the given model version from the ad's current text
+func (e *AdEmbedding) IsCurrent(ad Ad, modelVersion string) bool {
+	return e.ModelVersion == modelVersion && e.ContentHash == ContentHash(ad.EmbeddingText()) && len(e.Vector) > 0
+}
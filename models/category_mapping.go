@@ -0,0 +1,20 @@
+package models
+
+// CategoryMapping maps a movie category to the ad categories it should surface
+type CategoryMapping struct {
+	MovieCategory string   `json:"movie_category"`
+	AdCategories  []string `json:"ad_categories"`
+	Weight        float64  `json:"weight"` // Applied to every mapped ad category
+}
+
+// DefaultCategoryWeight is used when a mapping does not carry an explicit weight
+const DefaultCategoryWeight = 1.0
+
+// Weights returns the mapped ad categories with their weights
+func (m *CategoryMapping) Weights() map[string]float64 {
+	weights := make(map[string]float64, len(m.AdCategories))
+	for _, category := range m.AdCategories {
+		weights[category] = m.Weight
+	}
+	return weights
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Kinds of CFItem
+const (
+	CFItemAd    = "ad"    // ID is the ad ID
+	CFItemMovie = "movie" // ID is a movie category
+)
+
+// CFItem is the collaborative filtering model of one ad or movie category, as
+// written by the cf-train job under the ID of its training run
+type CFItem struct {
+	RunID   string             `json:"run_id"`
+	Kind    string             `json:"kind"` // One of the CFItem* constants
+	ID      string             `json:"id"`
+	Factors []float64          `json:"factors"`           // Latent factors from matrix factorisation
+	Similar map[string]float64 `json:"similar,omitempty"` // Ads most co-clicked with an ad, by cosine of their click vectors
+}
+
+// CFRun is one training run of the collaborative filtering model. Its items
+// are written before it is published, so only a published run is complete.
+type CFRun struct {
+	ID        string    `json:"run_id"`
+	TrainedAt time.Time `json:"trained_at"`
+	Items     int       `json:"items"` // Number of items written by the run
+	Published bool      `json:"published"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Kinds of CTRCount
+const (
+	CTRCountAd   = "ad"   // Key is the ad ID
+	CTRCountPair = "pair" // Key is the ad category and movie category joined by "|"
+)
+
+// CTRCount is the impressions and clicks counted for one ad or one pair of ad
+// category and movie category, as snapshotted by the CTR estimator
+type CTRCount struct {
+	Kind        string    `json:"kind"` // One of the CTRCount* constants
+	Key         string    `json:"key"`
+	Impressions int       `json:"impressions"`
+	Clicks      int       `json:"clicks"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Impression records one ad served to a user in a recommendation list
+type Impression struct {
+	RecommendationID string    `json:"recommendation_id"` // Shared by the ads of one served list
+	UserID           string    `json:"user_id"`
+	AdID             string    `json:"ad_id"`
+	Category         string    `json:"category"`                 // Ad category when served
+	Segment          string    `json:"segment,omitempty"`        // User segment reported by the client
+	MovieCategory    string    `json:"movie_category,omitempty"` // User's favourite movie category when served
+	Position         int       `json:"position"`                 // 1-based rank in the served list
+	Explored         bool      `json:"explored"`                 // Served in an exploration slot rather than for its rank
+	Experiment       string    `json:"experiment,omitempty"`     // Experiment the user took part in when served
+	Variant          string    `json:"variant,omitempty"`        // Variant of the experiment the user was assigned to
+	Timestamp        time.Time `json:"timestamp"`
+	ClickedAt        time.Time `json:"clicked_at"` // Zero until the first click attributed to the impression
+}
+
+// VariantCount is the impressions served to the users of an experiment
+// variant and the clicks attributed to them
+type VariantCount struct {
+	Experiment  string `json:"experiment"`
+	Variant     string `json:"variant"`
+	Impressions int    `json:"impressions"`
+	Clicks      int    `json:"clicks"`
+}
+
+// Clicked reports whether a click was attributed to the impression
+func (i Impression) Clicked() bool {
+	return !i.ClickedAt.IsZero()
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// PlaybackEvent represents a single playback of a movie category by a user
+type PlaybackEvent struct {
+	EventID   string    `json:"event_id,omitempty"` // Assigned by the store, sortable by time
+	UserID    string    `json:"user_id"`
+	Category  string    `json:"category"`
+	Timestamp time.Time `json:"timestamp"`
+}
@@ -2,25 +2,29 @@ package services
 
 import (
 	"Ad-Recommendations/db"
+	"Ad-Recommendations/models"
 	"context"
 	"fmt"
 	"log"
 	"time"
-
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
-// AdClickEntry represents an ad click event
-type AdClickEntry struct {
-	UserID    string `json:"user_id"`
-	AdID      string `json:"ad_id"`
-	Timestamp string `json:"timestamp"`
+// AdClickService records and reads ad click events
+type AdClickService struct {
+	Clicks      db.AdClickRepository
+	Impressions *ImpressionService // Optional; attributes clicks to the impressions they came from
+	Experiments *ExperimentService // Optional; tags clicks with the user's experiment variant
+}
+
+// NewAdClickService creates an AdClickService backed by the given repository
+func NewAdClickService(clicks db.AdClickRepository) *AdClickService {
+	return &AdClickService{Clicks: clicks}
 }
 
-// LogAdClick logs an ad click event to the DynamoDB AdClickTable
-func LogAdClick(userID, adID string) error {
+// LogAdClick logs an ad click event to the click store. A click carrying the
+// recommendation ID the ad was served in is also attributed to its impression;
+// the click is kept even when that fails.
+func (s *AdClickService) LogAdClick(ctx context.Context, userID, adID, recommendationID string) error {
 	// Validate inputs before logging
 	if userID == "" {
 		return fmt.Errorf("user_id cannot be empty")
@@ -29,68 +33,70 @@ func LogAdClick(userID, adID string) error {
 		return fmt.Errorf("ad_id cannot be empty")
 	}
 
-	// Get the current timestamp
-	timestamp := time.Now().Format(time.RFC3339)
+	click := models.AdClick{
+		UserID:    userID,
+		AdID:      adID,
+		Timestamp: time.Now().UTC(),
 
-	// Create the entry to insert
-	entry := map[string]types.AttributeValue{
-		"user_id":   &types.AttributeValueMemberS{Value: userID},
-		"ad_id":     &types.AttributeValueMemberS{Value: adID},
-		"timestamp": &types.AttributeValueMemberS{Value: timestamp},
+		RecommendationID: recommendationID,
+	}
+	if s.Experiments != nil {
+		assignment := s.Experiments.Assign(userID)
+		click.Experiment, click.Variant = assignment.Experiment, assignment.Variant
 	}
 
 	log.Printf("Logging Ad Click: user_id=%s, ad_id=%s", userID, adID)
 
-	// Perform the PutItem operation
-	_, err := db.DynamoClient.PutItem(context.TODO(), &dynamodb.PutItemInput{
-		TableName: aws.String(db.AdClickTableName),
-		Item:      entry,
-	})
-	if err != nil {
+	if err := s.Clicks.LogAdClick(ctx, click); err != nil {
 		log.Printf("Failed to log ad click event: %v", err)
 		return err
 	}
 
-	log.Printf("Ad click event logged: UserID=%s, AdID=%s, Timestamp=%s", userID, adID, timestamp)
+	log.Printf("Ad click event logged: UserID=%s, AdID=%s, Timestamp=%s", userID, adID, click.Timestamp.Format(time.RFC3339))
+
+	if recommendationID != "" && s.Impressions != nil {
+		if err := s.Impressions.RecordClick(ctx, click); err != nil {
+			log.Printf("⚠️ Click on ad %s not attributed to recommendation %s: %v", adID, recommendationID, err)
+		}
+	}
 	return nil
 }
 
-// GetAdClickHistory retrieves the ad click history for a given user
-func GetAdClickHistory(userID string) ([]AdClickEntry, error) {
+// GetAdClickHistory retrieves a user's ad clicks matching the query, oldest first,
+// and the cursor of the next older page. Use db.HistoryQuery{} for every click.
+func (s *AdClickService) GetAdClickHistory(ctx context.Context, userID string, query db.HistoryQuery) ([]models.AdClick, string, error) {
 	// Validate input
 	if userID == "" {
-		return nil, fmt.Errorf("user_id cannot be empty")
+		return nil, "", fmt.Errorf("user_id cannot be empty")
 	}
 
-	// Query the AdClickTable for entries matching the user_id
-	output, err := db.DynamoClient.Query(context.TODO(), &dynamodb.QueryInput{
-		TableName:              aws.String(db.AdClickTableName),
-		KeyConditionExpression: aws.String("user_id = :userID"),
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":userID": &types.AttributeValueMemberS{Value: userID},
-		},
-	})
+	adClickHistory, next, err := s.Clicks.AdClickHistory(ctx, userID, query)
 	if err != nil {
 		log.Printf("Failed to query ad click history: %v", err)
-		return nil, err
+		return nil, "", err
 	}
 
 	// Handle empty results
-	if len(output.Items) == 0 {
+	if len(adClickHistory) == 0 {
 		log.Printf("No ad click history found for user_id=%s", userID)
-		return nil, nil
+		return nil, "", nil
 	}
 
-	// Parse the query results into a list of AdClickEntry
-	adClickHistory := []AdClickEntry{}
-	for _, item := range output.Items {
-		entry := AdClickEntry{
-			UserID:    item["user_id"].(*types.AttributeValueMemberS).Value,
-			AdID:      item["ad_id"].(*types.AttributeValueMemberS).Value,
-			Timestamp: item["timestamp"].(*types.AttributeValueMemberS).Value,
-		}
-		adClickHistory = append(adClickHistory, entry)
+	return adClickHistory, next, nil
+}
+
+// CountAdClicks returns how many times a user clicked each ad within the query
+// window. Limit and cursor are ignored so every click in the window is counted.
+func (s *AdClickService) CountAdClicks(ctx context.Context, userID string, query db.HistoryQuery) (map[string]int, error) {
+	query.Limit, query.Cursor = 0, ""
+	adClickHistory, _, err := s.GetAdClickHistory(ctx, userID, query)
+	if err != nil {
+		return nil, err
 	}
 
-	return adClickHistory, nil
+	counts := make(map[string]int)
+	for _, click := range adClickHistory {
+		counts[click.AdID]++
+	}
+	return counts, nil
 }
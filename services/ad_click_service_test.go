@@ -0,0 +1,31 @@
+package services
+
+import (
+	"Ad-Recommendations/db"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepeatedClicksAreKept(t *testing.T) {
+	ctx := context.Background()
+	service := NewAdClickService(db.NewMemoryStore().Clicks)
+
+	assert.NoError(t, service.LogAdClick(ctx, "u1", "ad1", ""))
+	assert.NoError(t, service.LogAdClick(ctx, "u1", "ad1", ""))
+	assert.NoError(t, service.LogAdClick(ctx, "u1", "ad2", ""))
+
+	clicks, _, err := service.GetAdClickHistory(ctx, "u1", db.HistoryQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, clicks, 3)
+
+	counts, err := service.CountAdClicks(ctx, "u1", db.HistoryQuery{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"ad1": 2, "ad2": 1}, counts)
+
+	future, _, err := service.GetAdClickHistory(ctx, "u1", db.HistoryQuery{Since: time.Now().Add(time.Hour)})
+	assert.NoError(t, err)
+	assert.Empty(t, future)
+}
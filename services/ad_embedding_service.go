@@ -0,0 +1,280 @@
+package services
+
+import (
+	"Ad-Recommendations/config"
+	"Ad-Recommendations/db"
+	"Ad-Recommendations/models"
+	"Ad-Recommendations/services/ann"
+	"Ad-Recommendations/vector"
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// embeddingBatchSize bounds the number of texts sent to the embedding model at once
+const embeddingBatchSize = 64
+
+// AdEmbeddingService computes ad embeddings once and keeps them in the embedding
+// store. When Index is set, active ads with a current embedding are kept in it.
+type AdEmbeddingService struct {
+	Ads          db.AdRepository
+	Embeddings   db.AdEmbeddingRepository
+	Embedder     Embedder
+	Index        *ann.Index
+	Model        string
+	ModelVersion string
+	Dimension    int // Expected vector size, 0 when not configured
+}
+
+// NewAdEmbeddingService creates an AdEmbeddingService for the configured model
+func NewAdEmbeddingService(store *db.Store, embedder Embedder, cfg config.EmbeddingConfig) *AdEmbeddingService {
+	return &AdEmbeddingService{
+		Ads:          store.Ads,
+		Embeddings:   store.Embeddings,
+		Embedder:     embedder,
+		Model:        cfg.Model,
+		ModelVersion: cfg.ModelVersion,
+		Dimension:    cfg.Dimension,
+	}
+}
+
+// EmbedAd makes sure a single ad has a current stored embedding and that the
+// index reflects its status
+func (s *AdEmbeddingService) EmbedAd(ctx context.Context, ad models.Ad) error {
+	if _, err := s.EnsureEmbeddings(ctx, []models.Ad{ad}); err != nil {
+		return err
+	}
+	if s.Index != nil && !ad.IsActive() {
+		s.Index.Delete(ad.AdID)
+	}
+	return nil
+}
+
+// DeleteEmbeddings removes the stored embeddings of an ad
+func (s *AdEmbeddingService) DeleteEmbeddings(ctx context.Context, adID string) error {
+	if s.Index != nil {
+		s.Index.Delete(adID)
+	}
+	return s.Embeddings.DeleteAdEmbeddings(ctx, adID)
+}
+
+// BuildIndex loads the stored embeddings of all active ads into the index
+func (s *AdEmbeddingService) BuildIndex(ctx context.Context) (int, error) {
+	if s.Index == nil {
+		return 0, nil
+	}
+
+	indexed := 0
+	err := s.forEachStoredEmbedding(ctx, func(ad models.Ad, vector []float64) {
+		if s.indexAd(ad, vector) {
+			indexed++
+		}
+	})
+
+	log.Printf("✅ Indexed %d ads for nearest-neighbour retrieval", indexed)
+	return indexed, err
+}
+
+// WarmCache loads the stored embeddings of all ads into an embedding cache
+func (s *AdEmbeddingService) WarmCache(ctx context.Context, cache *CachingEmbedder) (int, error) {
+	warmed := 0
+	err := s.forEachStoredEmbedding(ctx, func(ad models.Ad, vector []float64) {
+		cache.Put(ad.EmbeddingText(), vector)
+		warmed++
+	})
+
+	log.Printf("✅ Warmed embedding cache with %d ad embeddings", warmed)
+	return warmed, err
+}
+
+// forEachStoredEmbedding calls visit for every ad with a current stored embedding
+func (s *AdEmbeddingService) forEachStoredEmbedding(ctx context.Context, visit func(ad models.Ad, vector []float64)) error {
+	ads, err := s.Ads.ListAds(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list ads: %w", err)
+	}
+
+	for start := 0; start < len(ads); start += embeddingBatchSize {
+		batch := ads[start:min(start+embeddingBatchSize, len(ads))]
+
+		adIDs := make([]string, len(batch))
+		for i, ad := range batch {
+			adIDs[i] = ad.AdID
+		}
+		stored, err := s.Embeddings.GetAdEmbeddings(ctx, s.Model, adIDs)
+		if err != nil {
+			return fmt.Errorf("failed to load ad embeddings: %w", err)
+		}
+
+		for _, ad := range batch {
+			if embedding, ok := stored[ad.AdID]; ok && s.isCurrent(embedding, ad) {
+				visit(ad, embedding.Vector)
+			}
+		}
+	}
+	return nil
+}
+
+// indexAd inserts an active ad into the index and drops inactive ones,
+// reporting whether the ad was inserted
+func (s *AdEmbeddingService) indexAd(ad models.Ad, vector []float64) bool {
+	if s.Index == nil {
+		return false
+	}
+	if !ad.IsActive() {
+		s.Index.Delete(ad.AdID)
+		return false
+	}
+	if err := s.Index.Insert(ad.AdID, vector); err != nil {
+		log.Printf("⚠️ Failed to index ad %s: %v", ad.AdID, err)
+		return false
+	}
+	return true
+}
+
+// EnsureEmbeddings returns an embedding for every ad, keyed by ad ID. Stored
+// embeddings are reused; missing or stale ones are computed and stored.
+func (s *AdEmbeddingService) EnsureEmbeddings(ctx context.Context, ads []models.Ad) (map[string][]float64, error) {
+	adIDs := make([]string, len(ads))
+	for i, ad := range ads {
+		adIDs[i] = ad.AdID
+	}
+
+	stored, err := s.Embeddings.GetAdEmbeddings(ctx, s.Model, adIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ad embeddings: %w", err)
+	}
+
+	vectors := make(map[string][]float64, len(ads))
+	missing := []models.Ad{}
+	for _, ad := range ads {
+		embedding, ok := stored[ad.AdID]
+		if ok && s.isCurrent(embedding, ad) {
+			vectors[ad.AdID] = embedding.Vector
+			// Embeddings stored by another process are indexed on first use
+			if s.Index != nil && !s.Index.Contains(ad.AdID) {
+				s.indexAd(ad, embedding.Vector)
+			}
+			continue
+		}
+		missing = append(missing, ad)
+	}
+
+	if len(missing) > 0 {
+		log.Printf("🧮 Embedding %d ads without a current %s embedding", len(missing), s.Model)
+		computed, err := s.embedAndStore(ctx, missing)
+		if err != nil {
+			return nil, err
+		}
+		for adID, vector := range computed {
+			vectors[adID] = vector
+		}
+	}
+
+	return vectors, nil
+}
+
+// Backfill embeds every ad in the ad store. Ads with a current embedding are
+// skipped unless force is set, which re-embeds everything after a model change.
+func (s *AdEmbeddingService) Backfill(ctx context.Context, force bool) (int, error) {
+	ads, err := s.Ads.ListAds(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list ads: %w", err)
+	}
+
+	embedded := 0
+	for start := 0; start < len(ads); start += embeddingBatchSize {
+		batch := ads[start:min(start+embeddingBatchSize, len(ads))]
+
+		if !force {
+			batch, err = s.staleAds(ctx, batch)
+			if err != nil {
+				return embedded, err
+			}
+		}
+		if len(batch) == 0 {
+			continue
+		}
+
+		if _, err := s.embedAndStore(ctx, batch); err != nil {
+			return embedded, err
+		}
+		embedded += len(batch)
+		log.Printf("✅ Embedded %d/%d ads", embedded, len(ads))
+	}
+
+	log.Printf("✅ Ad embedding backfill complete: %d of %d ads embedded with %s v%s", embedded, len(ads), s.Model, s.ModelVersion)
+	return embedded, nil
+}
+
+// staleAds returns the ads that have no current embedding
+func (s *AdEmbeddingService) staleAds(ctx context.Context, ads []models.Ad) ([]models.Ad, error) {
+	adIDs := make([]string, len(ads))
+	for i, ad := range ads {
+		adIDs[i] = ad.AdID
+	}
+
+	stored, err := s.Embeddings.GetAdEmbeddings(ctx, s.Model, adIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ad embeddings: %w", err)
+	}
+
+	stale := []models.Ad{}
+	for _, ad := range ads {
+		if embedding, ok := stored[ad.AdID]; !ok || !s.isCurrent(embedding, ad) {
+			stale = append(stale, ad)
+		}
+	}
+	return stale, nil
+}
+
+// isCurrent reports whether a stored embedding can be used for the ad as it is now
+func (s *AdEmbeddingService) isCurrent(embedding models.AdEmbedding, ad models.Ad) bool {
+	if !embedding.IsCurrent(ad, s.ModelVersion) {
+		return false
+	}
+	// A stored vector of another size was written under a different configuration
+	return s.Dimension == 0 || len(embedding.Vector) == s.Dimension
+}
+
+// embedAndStore embeds the ads in batches and stores the resulting vectors
+func (s *AdEmbeddingService) embedAndStore(ctx context.Context, ads []models.Ad) (map[string][]float64, error) {
+	vectors := make(map[string][]float64, len(ads))
+
+	for start := 0; start < len(ads); start += embeddingBatchSize {
+		batch := ads[start:min(start+embeddingBatchSize, len(ads))]
+
+		texts := make([]string, len(batch))
+		for i, ad := range batch {
+			texts[i] = ad.EmbeddingText()
+		}
+
+		embeddings, err := s.Embedder.Embed(ctx, texts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed ads: %w", err)
+		}
+		if err := (vector.Spec{Model: s.Model, Dimension: s.Dimension}).CheckBatch(embeddings, len(batch)); err != nil {
+			return nil, fmt.Errorf("refusing to store ad embeddings: %w", err)
+		}
+
+		now := time.Now().UTC()
+		for i, ad := range batch {
+			embedding := models.AdEmbedding{
+				AdID:         ad.AdID,
+				Model:        s.Model,
+				ModelVersion: s.ModelVersion,
+				ContentHash:  models.ContentHash(texts[i]),
+				Vector:       embeddings[i],
+				UpdatedAt:    now,
+			}
+			if err := s.Embeddings.PutAdEmbedding(ctx, embedding); err != nil {
+				return nil, fmt.Errorf("failed to store embedding for ad %s: %w", ad.AdID, err)
+			}
+			vectors[ad.AdID] = embeddings[i]
+			s.indexAd(ad, embeddings[i])
+		}
+	}
+
+	return vectors, nil
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"Ad-Recommendations/config"
+	"Ad-Recommendations/db"
+	"Ad-Recommendations/models"
+	"Ad-Recommendations/vector"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnsureEmbeddingsReusesCurrentEmbeddings(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewMemoryStore()
+	ads := []models.Ad{
+		{AdID: "car", Category: "Cars", Description: "Fast sports car"},
+		{AdID: "tour", Category: "Comedy", Description: "Stand-up tour"},
+	}
+
+	embedded := []string{}
+	embed := EmbedderFunc(func(ctx context.Context, texts []string) ([][]float64, error) {
+		embedded = append(embedded, texts...)
+		return fakeEmbed(ctx, texts)
+	})
+	service := NewAdEmbeddingService(store, embed, config.EmbeddingConfig{Model: "fake", ModelVersion: "1"})
+
+	vectors, err := service.EnsureEmbeddings(ctx, ads)
+	assert.NoError(t, err)
+	assert.Len(t, vectors, 2)
+	assert.Equal(t, []string{"Fast sports car", "Stand-up tour"}, embedded)
+
+	// A second pass reads the stored vectors; only the changed description is re-embedded
+	embedded = nil
+	ads[1].Description = "Noise cancelling"
+	vectors, err = service.EnsureEmbeddings(ctx, ads)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Noise cancelling"}, embedded)
+	assert.Equal(t, 0.7, vectors["tour"][0])
+
+	// A new model version invalidates every stored embedding
+	embedded = nil
+	service.ModelVersion = "2"
+	assert.NoError(t, (&db.Fixture{Ads: ads}).Apply(ctx, store))
+	count, err := service.Backfill(ctx, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	count, err = service.Backfill(ctx, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestEnsureEmbeddingsRejectsWrongDimension(t *testing.T) {
+	store := db.NewMemoryStore()
+	service := NewAdEmbeddingService(store, NewHashingEmbedder(16), config.EmbeddingConfig{Model: "hashing", ModelVersion: "1", Dimension: 768})
+
+	_, err := service.EnsureEmbeddings(context.Background(), []models.Ad{{AdID: "car", Description: "Fast sports car"}})
+
+	assert.True(t, errors.Is(err, vector.ErrDimensionMismatch))
+	stored, _ := store.Embeddings.GetAdEmbeddings(context.Background(), "hashing", []string{"car"})
+	assert.Empty(t, stored)
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"Ad-Recommendations/db"
+	"Ad-Recommendations/models"
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// AdService manages ads and keeps their stored embeddings and text index in sync
+type AdService struct {
+	Ads        db.AdRepository
+	Embeddings *AdEmbeddingService
+	Text       *TextIndex
+}
+
+// NewAdService creates an AdService that embeds and indexes ads as they are written
+func NewAdService(ads db.AdRepository, embeddings *AdEmbeddingService, text *TextIndex) *AdService {
+	return &AdService{Ads: ads, Embeddings: embeddings, Text: text}
+}
+
+// AddAd stores a new ad and computes its embedding
+func (s *AdService) AddAd(ctx context.Context, ad models.Ad) error {
+	if ad.AdID == "" {
+		return errors.New("ad_id cannot be empty")
+	}
+	if ad.CreatedAt.IsZero() {
+		ad.CreatedAt = time.Now().UTC()
+	}
+
+	if err := s.Ads.PutAd(ctx, ad); err != nil {
+		return errors.New("failed to add ad: " + err.Error())
+	}
+
+	s.Text.Upsert(ad)
+	s.embed(ctx, ad)
+	return nil
+}
+
+// UpdateAd replaces an existing ad; it is re-embedded only when its description changed
+func (s *AdService) UpdateAd(ctx context.Context, ad models.Ad) error {
+	if ad.AdID == "" {
+		return errors.New("ad_id cannot be empty")
+	}
+
+	existing, err := s.Ads.GetAd(ctx, ad.AdID)
+	if errors.Is(err, db.ErrNotFound) {
+		return errors.New("ad not found")
+	}
+	if err != nil {
+		return errors.New("failed to fetch ad: " + err.Error())
+	}
+	if ad.CreatedAt.IsZero() {
+		ad.CreatedAt = existing.CreatedAt
+	}
+
+	if err := s.Ads.PutAd(ctx, ad); err != nil {
+		return errors.New("failed to update ad: " + err.Error())
+	}
+
+	s.Text.Upsert(ad)
+	s.embed(ctx, ad)
+	return nil
+}
+
+// GetAd retrieves an ad by ID
+func (s *AdService) GetAd(ctx context.Context, adID string) (*models.Ad, error) {
+	if adID == "" {
+		return nil, errors.New("ad_id cannot be empty")
+	}
+
+	ad, err := s.Ads.GetAd(ctx, adID)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, errors.New("ad not found")
+	}
+	if err != nil {
+		return nil, errors.New("failed to fetch ad: " + err.Error())
+	}
+	return ad, nil
+}
+
+// DeleteAd removes an ad and its stored embeddings
+func (s *AdService) DeleteAd(ctx context.Context, adID string) error {
+	if adID == "" {
+		return errors.New("ad_id cannot be empty")
+	}
+
+	if err := s.Ads.DeleteAd(ctx, adID); err != nil {
+		return errors.New("failed to delete ad: " + err.Error())
+	}
+	s.Text.Remove(adID)
+	if err := s.Embeddings.DeleteEmbeddings(ctx, adID); err != nil {
+		return errors.New("failed to delete ad embeddings: " + err.Error())
+	}
+	return nil
+}
+
+// embed stores the embedding of an ad. Failures are only logged: the ranker
+// embeds ads without a current embedding on demand.
+func (s *AdService) embed(ctx context.Context, ad models.Ad) {
+	if err := s.Embeddings.EmbedAd(ctx, ad); err != nil {
+		log.Printf("⚠️ Failed to embed ad %s, it will be embedded on first use: %v", ad.AdID, err)
+	}
+}
@@ -0,0 +1,317 @@
+// Package ann provides an in-process approximate nearest-neighbour index over
+// embedding vectors, used to retrieve ads by similarity to a user vector.
+package ann
+
+import (
+	"Ad-Recommendations/vector"
+	"container/heap"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// Config holds the HNSW construction and search parameters
+type Config struct {
+	M              int // Neighbours kept per node and layer (twice as many on layer 0)
+	EfConstruction int // Candidate list size while inserting
+	EfSearch       int // Candidate list size while searching
+}
+
+// DefaultConfig is a reasonable setting for up to a few hundred thousand vectors
+var DefaultConfig = Config{M: 16, EfConstruction: 200, EfSearch: 64}
+
+// Result is one search hit
+type Result struct {
+	ID    string
+	Score float64 // Cosine similarity to the query
+}
+
+// node is one inserted vector with its neighbour lists per layer
+type node struct {
+	id        string
+	vector    vector.Vector // Unit length, float32 to halve memory
+	neighbors [][]int
+	deleted   bool
+}
+
+// Index is a Hierarchical Navigable Small World graph with cosine similarity.
+// Inserts and deletes are incremental; deleted nodes stay in the graph as
+// tombstones until they outnumber live nodes and the graph is compacted.
+type Index struct {
+	mu        sync.RWMutex
+	cfg       Config
+	levelMult float64
+	rng       *rand.Rand
+
+	nodes     []*node
+	ids       map[string]int // Live node per ID
+	entry     int
+	maxLevel  int
+	dimension int
+	deleted   int
+}
+
+// New creates an empty Index
+func New(cfg Config) *Index {
+	if cfg.M < 2 {
+		cfg.M = DefaultConfig.M
+	}
+	if cfg.EfConstruction < cfg.M {
+		cfg.EfConstruction = max(DefaultConfig.EfConstruction, cfg.M)
+	}
+	if cfg.EfSearch <= 0 {
+		cfg.EfSearch = DefaultConfig.EfSearch
+	}
+	return &Index{
+		cfg:       cfg,
+		levelMult: 1 / math.Log(float64(cfg.M)),
+		rng:       rand.New(rand.NewSource(1)),
+		ids:       map[string]int{},
+		entry:     -1,
+	}
+}
+
+// Len returns the number of live vectors
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.ids)
+}
+
+// Contains reports whether a vector is stored under the ID
+func (idx *Index) Contains(id string) bool {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	_, ok := idx.ids[id]
+	return ok
+}
+
+// Insert adds a vector or replaces the vector stored under the same ID. All
+// vectors must have the dimension of the first one inserted into the index.
+func (idx *Index) Insert(id string, v []float64) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if len(idx.ids) == 0 {
+		idx.reset()
+		idx.dimension = len(v)
+	}
+	if err := (vector.Spec{Model: "ann index", Dimension: idx.dimension}).Check(v); err != nil {
+		return fmt.Errorf("vector for %s: %w", id, err)
+	}
+
+	idx.deleteLocked(id)
+	idx.insertLocked(id, vector.Normalize(v))
+	return nil
+}
+
+// Delete removes the vector stored under the ID, if any
+func (idx *Index) Delete(id string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.deleteLocked(id)
+}
+
+// Search returns up to k IDs most similar to the query, most similar first
+func (idx *Index) Search(query []float64, k int) []Result {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	if k <= 0 || len(idx.ids) == 0 || len(query) != idx.dimension {
+		return nil
+	}
+	q := vector.Normalize(query)
+
+	ep := idx.entry
+	for level := idx.maxLevel; level > 0; level-- {
+		ep = idx.searchLayer(q, ep, 1, level)[0].node
+	}
+	found := idx.searchLayer(q, ep, max(idx.cfg.EfSearch, k), 0)
+
+	results := []Result{}
+	for _, candidate := range found {
+		n := idx.nodes[candidate.node]
+		if n.deleted {
+			continue
+		}
+		results = append(results, Result{ID: n.id, Score: 1 - candidate.distance})
+		if len(results) == k {
+			break
+		}
+	}
+	return results
+}
+
+func (idx *Index) reset() {
+	idx.nodes = nil
+	idx.ids = map[string]int{}
+	idx.entry = -1
+	idx.maxLevel = 0
+	idx.deleted = 0
+}
+
+// insertLocked links a unit vector into the graph
+func (idx *Index) insertLocked(id string, v []float64) {
+	level := int(math.Floor(-math.Log(1-idx.rng.Float64()) * idx.levelMult))
+	n := &node{id: id, vector: vector.FromFloat64(v), neighbors: make([][]int, level+1)}
+	idx.nodes = append(idx.nodes, n)
+	current := len(idx.nodes) - 1
+	idx.ids[id] = current
+
+	if idx.entry < 0 {
+		idx.entry = current
+		idx.maxLevel = level
+		return
+	}
+
+	ep := idx.entry
+	for l := idx.maxLevel; l > level; l-- {
+		ep = idx.searchLayer(v, ep, 1, l)[0].node
+	}
+	for l := min(level, idx.maxLevel); l >= 0; l-- {
+		found := idx.searchLayer(v, ep, idx.cfg.EfConstruction, l)
+		limit := idx.maxNeighbors(l)
+
+		for _, candidate := range found[:min(limit, len(found))] {
+			n.neighbors[l] = append(n.neighbors[l], candidate.node)
+			neighbor := idx.nodes[candidate.node]
+			neighbor.neighbors[l] = append(neighbor.neighbors[l], current)
+			if len(neighbor.neighbors[l]) > limit {
+				neighbor.neighbors[l] = idx.closest(neighbor.vector.Float64(), neighbor.neighbors[l], limit)
+			}
+		}
+		ep = found[0].node
+	}
+
+	if level > idx.maxLevel {
+		idx.entry = current
+		idx.maxLevel = level
+	}
+}
+
+func (idx *Index) deleteLocked(id string) {
+	current, ok := idx.ids[id]
+	if !ok {
+		return
+	}
+	idx.nodes[current].deleted = true
+	delete(idx.ids, id)
+	idx.deleted++
+
+	if len(idx.ids) == 0 {
+		idx.reset()
+		return
+	}
+	// Tombstones slow down search; rebuild once they outnumber live nodes
+	if idx.deleted > len(idx.ids) {
+		idx.compact()
+	}
+}
+
+// compact rebuilds the graph from the live nodes only
+func (idx *Index) compact() {
+	live := make([]*node, 0, len(idx.ids))
+	for _, n := range idx.nodes {
+		if !n.deleted {
+			live = append(live, n)
+		}
+	}
+	idx.reset()
+	for _, n := range live {
+		idx.insertLocked(n.id, n.vector.Float64())
+	}
+}
+
+func (idx *Index) maxNeighbors(level int) int {
+	if level == 0 {
+		return 2 * idx.cfg.M
+	}
+	return idx.cfg.M
+}
+
+// closest returns the limit nodes nearest to the vector
+func (idx *Index) closest(v []float64, nodes []int, limit int) []int {
+	sort.Slice(nodes, func(i, j int) bool {
+		return distance(v, idx.nodes[nodes[i]].vector) < distance(v, idx.nodes[nodes[j]].vector)
+	})
+	return append([]int(nil), nodes[:limit]...)
+}
+
+// searchLayer returns up to ef nodes of one layer nearest to the query, nearest first
+func (idx *Index) searchLayer(query []float64, ep, ef, level int) []candidate {
+	visited := map[int]bool{ep: true}
+	start := candidate{node: ep, distance: distance(query, idx.nodes[ep].vector)}
+	candidates := &minHeap{start}
+	results := &maxHeap{start}
+
+	for candidates.Len() > 0 {
+		current := heap.Pop(candidates).(candidate)
+		if current.distance > (*results)[0].distance && results.Len() >= ef {
+			break
+		}
+		for _, neighbor := range idx.nodes[current.node].neighbors[level] {
+			if visited[neighbor] {
+				continue
+			}
+			visited[neighbor] = true
+
+			d := distance(query, idx.nodes[neighbor].vector)
+			if results.Len() < ef || d < (*results)[0].distance {
+				heap.Push(candidates, candidate{node: neighbor, distance: d})
+				heap.Push(results, candidate{node: neighbor, distance: d})
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	found := []candidate(*results)
+	sort.Slice(found, func(i, j int) bool { return found[i].distance < found[j].distance })
+	return found
+}
+
+// distance is the cosine distance of two unit vectors
+func distance(a []float64, b vector.Vector) float64 {
+	dot := 0.0
+	for i := range a {
+		dot += a[i] * float64(b[i])
+	}
+	return 1 - dot
+}
+
+// candidate is a node with its distance to the current query
+type candidate struct {
+	node     int
+	distance float64
+}
+
+// minHeap pops the nearest candidate first
+type minHeap []candidate
+
+func (h minHeap) Len() int            { return len(h) }
+func (h minHeap) Less(i, j int) bool  { return h[i].distance < h[j].distance }
+func (h minHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *minHeap) Push(x interface{}) { *h = append(*h, x.(candidate)) }
+func (h *minHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// maxHeap pops the farthest candidate first
+type maxHeap []candidate
+
+func (h maxHeap) Len() int            { return len(h) }
+func (h maxHeap) Less(i, j int) bool  { return h[i].distance > h[j].distance }
+func (h maxHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *maxHeap) Push(x interface{}) { *h = append(*h, x.(candidate)) }
+func (h *maxHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,86 @@
+package ann
+
+import (
+	"Ad-Recommendations/vector"
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func randomVectors(n, dimension int) map[string][]float64 {
+	rng := rand.New(rand.NewSource(42))
+	vectors := make(map[string][]float64, n)
+	for i := 0; i < n; i++ {
+		vector := make([]float64, dimension)
+		for j := range vector {
+			vector[j] = rng.NormFloat64()
+		}
+		vectors[fmt.Sprintf("ad-%d", i)] = vector
+	}
+	return vectors
+}
+
+// bruteForce returns the IDs of the k vectors most similar to the query
+func bruteForce(vectors map[string][]float64, query []float64, k int) []string {
+	similarity := func(id string) float64 {
+		s, _ := vector.Cosine(query, vectors[id])
+		return s
+	}
+	ids := make([]string, 0, len(vectors))
+	for id := range vectors {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return similarity(ids[i]) > similarity(ids[j]) })
+	return ids[:k]
+}
+
+func TestSearchRecall(t *testing.T) {
+	vectors := randomVectors(2000, 16)
+	index := New(DefaultConfig)
+	for id, vector := range vectors {
+		assert.NoError(t, index.Insert(id, vector))
+	}
+
+	queries := randomVectors(50, 16)
+	hits, total := 0, 0
+	for _, query := range queries {
+		expected := map[string]bool{}
+		for _, id := range bruteForce(vectors, query, 10) {
+			expected[id] = true
+		}
+		for _, result := range index.Search(query, 10) {
+			if expected[result.ID] {
+				hits++
+			}
+		}
+		total += 10
+	}
+
+	assert.Greater(t, float64(hits)/float64(total), 0.9)
+}
+
+func TestInsertAndDelete(t *testing.T) {
+	index := New(Config{M: 4})
+
+	assert.NoError(t, index.Insert("car", []float64{1, 0}))
+	assert.NoError(t, index.Insert("tour", []float64{0, 1}))
+	assert.Error(t, index.Insert("bad", []float64{1, 0, 0}))
+
+	results := index.Search([]float64{0.9, 0.1}, 1)
+	assert.Equal(t, "car", results[0].ID)
+
+	// Replacing a vector moves the ID; deleting hides it from search
+	assert.NoError(t, index.Insert("car", []float64{0, -1}))
+	index.Delete("tour")
+	results = index.Search([]float64{0.9, 0.1}, 2)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "car", results[0].ID)
+	assert.Equal(t, 1, index.Len())
+
+	index.Delete("car")
+	assert.Zero(t, index.Len())
+	assert.Empty(t, index.Search([]float64{1, 0}, 1))
+}
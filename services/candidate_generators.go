@@ -0,0 +1,130 @@
+package services
+
+import (
+	"Ad-Recommendations/config"
+	"Ad-Recommendations/db"
+	"Ad-Recommendations/models"
+	"Ad-Recommendations/services/ann"
+	"context"
+	"log"
+	"sort"
+	"sync"
+)
+
+// maxConcurrentCategoryQueries bounds the parallel per-category ad reads of one request
+const maxConcurrentCategoryQueries = 8
+
+// CategoryCandidates retrieves the active ads of each mapped category in
+// parallel. Ads are returned grouped by category, highest category weight first.
+type CategoryCandidates struct {
+	Ads db.AdRepository
+}
+
+// Name identifies the generator in logs and explanations
+func (g *CategoryCandidates) Name() string { return config.CandidatesCategory }
+
+// Generate returns the active ads of the request's mapped categories
+func (g *CategoryCandidates) Generate(ctx context.Context, req *RankingRequest, seen map[string]bool) ([]models.Ad, error) {
+	categories := req.CategoryWeights
+	log.Printf("🔍 Fetching ads for mapped categories: %v", categories)
+
+	// Order categories so the merged result does not depend on goroutine timing
+	ordered := make([]string, 0, len(categories))
+	for category := range categories {
+		ordered = append(ordered, category)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		if categories[ordered[i]] != categories[ordered[j]] {
+			return categories[ordered[i]] > categories[ordered[j]]
+		}
+		return ordered[i] < ordered[j]
+	})
+
+	results := make([][]models.Ad, len(ordered))
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, maxConcurrentCategoryQueries)
+	for i, category := range ordered {
+		wg.Add(1)
+		go func(i int, category string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			log.Printf("🔍 Querying ads for category: %s (weight: %.4f)", category, categories[category])
+			categoryAds, err := g.Ads.AdsByCategory(ctx, category)
+			if err != nil {
+				log.Printf("❌ Failed to fetch ads for category %s: %v", category, err)
+				return
+			}
+			results[i] = categoryAds
+		}(i, category)
+	}
+	wg.Wait()
+
+	ads := []models.Ad{}
+	for _, categoryAds := range results {
+		ads = append(ads, categoryAds...)
+	}
+	return ads, nil
+}
+
+// NearestCandidates retrieves the K active ads whose embeddings are nearest to
+// the user vector. It produces nothing without a user vector.
+type NearestCandidates struct {
+	Ads   db.AdRepository
+	Index *ann.Index
+	K     int
+}
+
+// Name identifies the generator in logs and explanations
+func (g *NearestCandidates) Name() string { return config.CandidatesNearest }
+
+// Generate returns the nearest active ads not already produced by an earlier generator
+func (g *NearestCandidates) Generate(ctx context.Context, req *RankingRequest, seen map[string]bool) ([]models.Ad, error) {
+	if req.UserVector == nil {
+		return nil, nil
+	}
+
+	nearestIDs := []string{}
+	for _, result := range g.Index.Search(req.UserVector, g.K) {
+		if !seen[result.ID] {
+			nearestIDs = append(nearestIDs, result.ID)
+		}
+	}
+
+	ads := fetchActiveAds(ctx, g.Ads, nearestIDs)
+	log.Printf("✅ Added %d ads from nearest-neighbour retrieval", len(ads))
+	return ads, nil
+}
+
+// fetchActiveAds reads the ads of the IDs in parallel and returns the active
+// ones in the order of the IDs. Ads that fail to read are left out.
+func fetchActiveAds(ctx context.Context, repo db.AdRepository, adIDs []string) []models.Ad {
+	results := make([]*models.Ad, len(adIDs))
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, maxConcurrentCategoryQueries)
+	for i, adID := range adIDs {
+		wg.Add(1)
+		go func(i int, adID string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			ad, err := repo.GetAd(ctx, adID)
+			if err != nil {
+				log.Printf("⚠️ Failed to fetch candidate ad %s: %v", adID, err)
+				return
+			}
+			results[i] = ad
+		}(i, adID)
+	}
+	wg.Wait()
+
+	ads := []models.Ad{}
+	for _, ad := range results {
+		if ad != nil && ad.IsActive() {
+			ads = append(ads, *ad)
+		}
+	}
+	return ads
+}
@@ -0,0 +1,296 @@
+// Package cf provides item-to-item collaborative filtering: similarities from
+// co-occurrence counts and implicit-feedback matrix factorisation trained by
+// alternating least squares.
+package cf
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Interaction is implicit feedback of a user on an item, such as clicks on an
+// ad or plays of a movie category. Weight is how much feedback there was.
+type Interaction struct {
+	User   string
+	Item   string
+	Weight float64
+}
+
+// Config holds the ALS training parameters
+type Config struct {
+	Factors        int     // Size of the latent factor vectors
+	Iterations     int     // Alternations of user and item solves
+	Regularization float64 // L2 penalty on the factors
+	Alpha          float64 // Confidence of an interaction is 1 + Alpha * weight
+	Seed           int64   // Seed of the random initial item factors
+}
+
+// DefaultConfig is a reasonable setting for a few thousand items
+var DefaultConfig = Config{Factors: 32, Iterations: 10, Regularization: 0.1, Alpha: 40, Seed: 1}
+
+// Model holds the factors learned for every user and item
+type Model struct {
+	Users map[string][]float64
+	Items map[string][]float64
+}
+
+// Train factorises the interactions with implicit-feedback ALS (Hu, Koren and
+// Volinsky, 2008): every observed user-item pair is a preference of 1 with
+// confidence 1 + Alpha * weight, every other pair a preference of 0 with
+// confidence 1. Weights of repeated pairs add up.
+func Train(interactions []Interaction, cfg Config) (*Model, error) {
+	if cfg.Factors < 1 || cfg.Iterations < 1 || cfg.Regularization <= 0 || cfg.Alpha < 0 {
+		return nil, fmt.Errorf("invalid ALS config %+v", cfg)
+	}
+
+	users, items, byUser, byItem := index(interactions)
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	userFactors := newFactors(len(users), cfg.Factors, nil)
+	itemFactors := newFactors(len(items), cfg.Factors, rng)
+
+	for iteration := 0; iteration < cfg.Iterations; iteration++ {
+		solveAll(userFactors, itemFactors, byUser, cfg)
+		solveAll(itemFactors, userFactors, byItem, cfg)
+		log.Printf("🧮 ALS iteration %d/%d, loss %.4f", iteration+1, cfg.Iterations, loss(userFactors, itemFactors, byUser, cfg))
+	}
+
+	model := &Model{Users: make(map[string][]float64, len(users)), Items: make(map[string][]float64, len(items))}
+	for i, user := range users {
+		model.Users[user] = userFactors[i]
+	}
+	for i, item := range items {
+		model.Items[item] = itemFactors[i]
+	}
+	return model, nil
+}
+
+// UserSolver computes the factors of users who were not trained on, from the
+// fixed item factors of a model
+type UserSolver struct {
+	cfg   Config
+	items map[string][]float64
+	gram  [][]float64 // Sum of the outer products of every item's factors
+}
+
+// NewUserSolver prepares to fold users into the item factors
+func NewUserSolver(items map[string][]float64, cfg Config) *UserSolver {
+	factors := make([][]float64, 0, len(items))
+	for _, item := range items {
+		factors = append(factors, item)
+	}
+	return &UserSolver{cfg: cfg, items: items, gram: gram(factors, cfg.Factors)}
+}
+
+// Solve returns the factors of a user with the given weights per item, the
+// same least-squares step as in training. It returns nil when none of the
+// items has factors.
+func (s *UserSolver) Solve(weights map[string]float64) []float64 {
+	observed := []entry{}
+	factors := [][]float64{}
+	for item, weight := range weights {
+		if itemFactors, ok := s.items[item]; ok && weight > 0 {
+			observed = append(observed, entry{index: len(factors), weight: weight})
+			factors = append(factors, itemFactors)
+		}
+	}
+	if len(observed) == 0 {
+		return nil
+	}
+	return solveOne(s.gram, factors, observed, s.cfg)
+}
+
+// entry is one interaction of a row of the feedback matrix
+type entry struct {
+	index  int // Column of the interaction
+	weight float64
+}
+
+// index numbers users and items in sorted order and groups the summed weights by both
+func index(interactions []Interaction) ([]string, []string, [][]entry, [][]entry) {
+	summed := map[[2]string]float64{}
+	userSet, itemSet := map[string]bool{}, map[string]bool{}
+	for _, interaction := range interactions {
+		if interaction.Weight <= 0 {
+			continue
+		}
+		summed[[2]string{interaction.User, interaction.Item}] += interaction.Weight
+		userSet[interaction.User] = true
+		itemSet[interaction.Item] = true
+	}
+
+	users, items := sortedKeys(userSet), sortedKeys(itemSet)
+	userIndex, itemIndex := positions(users), positions(items)
+	byUser, byItem := make([][]entry, len(users)), make([][]entry, len(items))
+	pairs := make([][2]string, 0, len(summed))
+	for pair := range summed {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	for _, pair := range pairs {
+		u, i := userIndex[pair[0]], itemIndex[pair[1]]
+		byUser[u] = append(byUser[u], entry{index: i, weight: summed[pair]})
+		byItem[i] = append(byItem[i], entry{index: u, weight: summed[pair]})
+	}
+	return users, items, byUser, byItem
+}
+
+// solveAll recomputes every row of target with the other side fixed
+func solveAll(target, fixed [][]float64, rows [][]entry, cfg Config) {
+	fixedGram := gram(fixed, cfg.Factors)
+	for row, observed := range rows {
+		target[row] = solveOne(fixedGram, fixed, observed, cfg)
+	}
+}
+
+// solveOne solves (YᵀY + Yᵀ(C - I)Y + λI) x = YᵀCp for one row, where Y are
+// the fixed factors and C the confidences of the row's interactions
+func solveOne(fixedGram, fixed [][]float64, observed []entry, cfg Config) []float64 {
+	k := cfg.Factors
+	a := make([][]float64, k)
+	for i := range a {
+		a[i] = append([]float64(nil), fixedGram[i]...)
+		a[i][i] += cfg.Regularization
+	}
+	b := make([]float64, k)
+	for _, e := range observed {
+		y := fixed[e.index]
+		confidence := 1 + cfg.Alpha*e.weight
+		for i := 0; i < k; i++ {
+			b[i] += confidence * y[i]
+			for j := 0; j < k; j++ {
+				a[i][j] += (confidence - 1) * y[i] * y[j]
+			}
+		}
+	}
+	return choleskySolve(a, b)
+}
+
+// gram returns the sum of the outer products of the factors
+func gram(factors [][]float64, k int) [][]float64 {
+	g := make([][]float64, k)
+	for i := range g {
+		g[i] = make([]float64, k)
+	}
+	for _, f := range factors {
+		for i := 0; i < k; i++ {
+			for j := i; j < k; j++ {
+				g[i][j] += f[i] * f[j]
+			}
+		}
+	}
+	for i := 0; i < k; i++ {
+		for j := 0; j < i; j++ {
+			g[i][j] = g[j][i]
+		}
+	}
+	return g
+}
+
+// choleskySolve solves a x = b for a symmetric positive-definite a, which it overwrites
+func choleskySolve(a [][]float64, b []float64) []float64 {
+	n := len(b)
+	for j := 0; j < n; j++ {
+		sum := a[j][j]
+		for k := 0; k < j; k++ {
+			sum -= a[j][k] * a[j][k]
+		}
+		a[j][j] = math.Sqrt(math.Max(sum, 1e-12))
+		for i := j + 1; i < n; i++ {
+			sum := a[i][j]
+			for k := 0; k < j; k++ {
+				sum -= a[i][k] * a[j][k]
+			}
+			a[i][j] = sum / a[j][j]
+		}
+	}
+
+	// Forward substitution with L, then back substitution with Lᵀ
+	x := make([]float64, n)
+	for i := 0; i < n; i++ {
+		sum := b[i]
+		for k := 0; k < i; k++ {
+			sum -= a[i][k] * x[k]
+		}
+		x[i] = sum / a[i][i]
+	}
+	for i := n - 1; i >= 0; i-- {
+		sum := x[i]
+		for k := i + 1; k < n; k++ {
+			sum -= a[k][i] * x[k]
+		}
+		x[i] = sum / a[i][i]
+	}
+	return x
+}
+
+// loss returns the weighted squared error over the observed pairs plus the
+// regularization, per observed pair; the unobserved zeros are left out
+func loss(userFactors, itemFactors [][]float64, byUser [][]entry, cfg Config) float64 {
+	total, count := 0.0, 0
+	for u, observed := range byUser {
+		for _, e := range observed {
+			diff := 1 - Dot(userFactors[u], itemFactors[e.index])
+			total += (1 + cfg.Alpha*e.weight) * diff * diff
+			count++
+		}
+	}
+	for _, factors := range [][][]float64{userFactors, itemFactors} {
+		for _, f := range factors {
+			total += cfg.Regularization * Dot(f, f)
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	return total / float64(count)
+}
+
+// newFactors allocates n factor vectors, small random values when rng is set
+func newFactors(n, k int, rng *rand.Rand) [][]float64 {
+	factors := make([][]float64, n)
+	for i := range factors {
+		factors[i] = make([]float64, k)
+		if rng != nil {
+			for j := range factors[i] {
+				factors[i][j] = rng.NormFloat64() * 0.01
+			}
+		}
+	}
+	return factors
+}
+
+// Dot returns the dot product of two factor vectors of the same size
+func Dot(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// sortedKeys returns the keys of a set in order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// positions maps each string to its position in the list
+func positions(list []string) map[string]int {
+	index := make(map[string]int, len(list))
+	for i, value := range list {
+		index[value] = i
+	}
+	return index
+}
@@ -0,0 +1,61 @@
+package cf
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// clusteredInteractions has car fans clicking car ads and food fans clicking food ads
+func clusteredInteractions() []Interaction {
+	interactions := []Interaction{}
+	for u := 0; u < 20; u++ {
+		items := []string{"car-1", "car-2", "car-3"}
+		if u%2 == 1 {
+			items = []string{"food-1", "food-2", "food-3"}
+		}
+		for i, item := range items {
+			if (u+i)%3 == 0 {
+				continue // Everyone misses one item of their cluster
+			}
+			interactions = append(interactions, Interaction{User: fmt.Sprintf("user-%d", u), Item: item, Weight: 1})
+		}
+	}
+	return interactions
+}
+
+func TestTrainSeparatesClusters(t *testing.T) {
+	cfg := Config{Factors: 2, Iterations: 15, Regularization: 0.1, Alpha: 10, Seed: 1}
+	model, err := Train(clusteredInteractions(), cfg)
+	assert.NoError(t, err)
+	assert.Len(t, model.Items, 6)
+	assert.Len(t, model.Users, 20)
+
+	// user-0 never clicked car-1 but should prefer it to every food ad
+	user := model.Users["user-0"]
+	for _, food := range []string{"food-1", "food-2", "food-3"} {
+		assert.Greater(t, Dot(user, model.Items["car-1"]), Dot(user, model.Items[food]))
+	}
+
+	// A new user folded in from one food click prefers the other food ads
+	solver := NewUserSolver(model.Items, cfg)
+	folded := solver.Solve(map[string]float64{"food-1": 1})
+	assert.Greater(t, Dot(folded, model.Items["food-2"]), Dot(folded, model.Items["car-2"]))
+	assert.Nil(t, solver.Solve(map[string]float64{"unknown": 1}))
+
+	_, err = Train(nil, Config{})
+	assert.Error(t, err)
+}
+
+func TestCoOccurrence(t *testing.T) {
+	similar := CoOccurrence(clusteredInteractions(), 2, 2)
+	assert.Len(t, similar["car-1"], 2)
+	assert.Contains(t, similar["car-1"], "car-2")
+	assert.NotContains(t, similar["car-1"], "food-1")
+	for _, score := range similar["food-2"] {
+		assert.InDelta(t, 0.5, score, 0.5)
+	}
+
+	assert.Empty(t, CoOccurrence(clusteredInteractions(), 2, 100))
+}
@@ -0,0 +1,67 @@
+package cf
+
+import (
+	"math"
+	"sort"
+)
+
+// CoOccurrence returns for every item the k items most often interacted with
+// by the same users, scored by the cosine of their user sets:
+// co(i, j) / sqrt(n(i) n(j)), where co counts the users of both items and n
+// the users of each. Pairs shared by fewer than minUsers users are left out.
+func CoOccurrence(interactions []Interaction, k, minUsers int) map[string]map[string]float64 {
+	itemsByUser := map[string]map[string]bool{}
+	for _, interaction := range interactions {
+		if interaction.Weight <= 0 {
+			continue
+		}
+		if itemsByUser[interaction.User] == nil {
+			itemsByUser[interaction.User] = map[string]bool{}
+		}
+		itemsByUser[interaction.User][interaction.Item] = true
+	}
+
+	users := map[string]int{}
+	together := map[[2]string]int{}
+	for _, items := range itemsByUser {
+		list := sortedKeys(items)
+		for i, a := range list {
+			users[a]++
+			for _, b := range list[i+1:] {
+				together[[2]string{a, b}]++
+			}
+		}
+	}
+
+	type scored struct {
+		item  string
+		score float64
+	}
+	neighbours := map[string][]scored{}
+	for pair, count := range together {
+		if count < max(minUsers, 1) {
+			continue
+		}
+		score := float64(count) / math.Sqrt(float64(users[pair[0]]*users[pair[1]]))
+		neighbours[pair[0]] = append(neighbours[pair[0]], scored{pair[1], score})
+		neighbours[pair[1]] = append(neighbours[pair[1]], scored{pair[0], score})
+	}
+
+	similar := make(map[string]map[string]float64, len(neighbours))
+	for item, list := range neighbours {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].score != list[j].score {
+				return list[i].score > list[j].score
+			}
+			return list[i].item < list[j].item
+		})
+		if k > 0 && len(list) > k {
+			list = list[:k]
+		}
+		similar[item] = make(map[string]float64, len(list))
+		for _, neighbour := range list {
+			similar[item][neighbour.item] = neighbour.score
+		}
+	}
+	return similar
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned while a circuit breaker rejects calls
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitBreaker stops calling a failing dependency. After Threshold
+// consecutive failures it opens and rejects calls for Cooldown, then lets a
+// single trial call through; the trial's outcome closes or reopens it. A
+// trial that is cancelled, or has not finished within Cooldown, lets the next
+// call through as a new trial.
+type CircuitBreaker struct {
+	Name      string
+	Threshold int
+	Cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	open     bool
+	openedAt time.Time
+	trial    bool // A half-open trial call is in flight
+	trialAt  time.Time
+}
+
+// NewCircuitBreaker creates a closed CircuitBreaker
+func NewCircuitBreaker(name string, threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{Name: name, Threshold: threshold, Cooldown: cooldown}
+}
+
+// Allow reports whether a call may proceed, returning ErrCircuitOpen if not
+func (b *CircuitBreaker) Allow() error {
+	if b == nil || b.Threshold <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.open {
+		return nil
+	}
+	if time.Since(b.openedAt) < b.Cooldown || (b.trial && time.Since(b.trialAt) < b.Cooldown) {
+		return ErrCircuitOpen
+	}
+	b.trial, b.trialAt = true, time.Now()
+	return nil
+}
+
+// Cancel records an allowed call that ended without an outcome, such as one
+// whose caller went away, so an open breaker lets the next trial through
+func (b *CircuitBreaker) Cancel() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+// Success records a successful call and closes the breaker
+func (b *CircuitBreaker) Success() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.open {
+		log.Printf("✅ Circuit breaker %s closed", b.Name)
+	}
+	b.failures = 0
+	b.open = false
+	b.trial = false
+}
+
+// Failure records a failed call and opens the breaker once the threshold is reached
+func (b *CircuitBreaker) Failure() {
+	if b == nil || b.Threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.trial || (!b.open && b.failures >= b.Threshold) {
+		log.Printf("🚨 Circuit breaker %s opened after %d consecutive failures", b.Name, b.failures)
+		b.open = true
+		b.openedAt = time.Now()
+		b.trial = false
+	}
+}
+
+// Open reports whether the breaker is currently rejecting calls
+func (b *CircuitBreaker) Open() bool {
+	if b == nil {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.open
+}
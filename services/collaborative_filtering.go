@@ -0,0 +1,385 @@
+package services
+
+import (
+	"Ad-Recommendations/config"
+	"Ad-Recommendations/db"
+	"Ad-Recommendations/models"
+	"Ad-Recommendations/services/cf"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cfRunIDLayout formats the training time of a run into its ID, which sorts by time
+const cfRunIDLayout = "20060102T150405.000000000Z"
+
+// cfMovieItem names a movie category among the items of the factorisation,
+// apart from the ads that are named by ID
+func cfMovieItem(category string) string { return "movie:" + category }
+
+// TrainCF trains the collaborative filtering model on the clicks and plays of
+// the last cfg.Window and stores it. Clicks on an ad and plays of a movie
+// category are the implicit feedback factorised by ALS, a play weighing
+// cfg.PlaybackWeight of a click; co-clicked ads come from clicks alone. The
+// items are written under a new run that is published once they are all
+// stored, then older runs are deleted. Without clicks an empty run is
+// published, so a model of older clicks is not served forever. It returns
+// the number of items stored.
+func TrainCF(ctx context.Context, store *db.Store, cfg config.CFConfig, now time.Time) (int, error) {
+	since := now.Add(-cfg.Window)
+	clicks, err := store.Clicks.ClicksSince(ctx, since)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read clicks: %w", err)
+	}
+	plays, err := store.Playback.PlaybackSince(ctx, since)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read playback: %w", err)
+	}
+	log.Printf("📥 Training collaborative filtering on %d clicks and %d plays since %s", len(clicks), len(plays), since.Format(time.RFC3339))
+
+	items, err := trainCFItems(clicks, plays, cfg)
+	if err != nil {
+		return 0, err
+	}
+	trainedAt := now.UTC()
+
+	// The run is recorded before its items so that a failed run is found and deleted by a later one
+	run := models.CFRun{ID: trainedAt.Format(cfRunIDLayout), TrainedAt: trainedAt, Items: len(items)}
+	if err := store.CFItems.PutCFRun(ctx, run); err != nil {
+		return 0, fmt.Errorf("failed to create collaborative filtering run: %w", err)
+	}
+	if err := store.CFItems.PutCFItems(ctx, run.ID, items); err != nil {
+		return 0, fmt.Errorf("failed to store collaborative filtering model: %w", err)
+	}
+	run.Published = true
+	if err := store.CFItems.PutCFRun(ctx, run); err != nil {
+		return 0, fmt.Errorf("failed to publish collaborative filtering run: %w", err)
+	}
+	log.Printf("✅ Published collaborative filtering run %s of %d items", run.ID, len(items))
+
+	deleteOlderCFRuns(ctx, store.CFItems, run)
+	return len(items), nil
+}
+
+// trainCFItems factorises the clicks and plays and finds the co-clicked ads,
+// returning the items of every ad and movie category; none without clicks
+func trainCFItems(clicks []models.AdClick, plays []models.PlaybackEvent, cfg config.CFConfig) ([]models.CFItem, error) {
+	if len(clicks) == 0 {
+		log.Printf("⚠️ No clicks to train collaborative filtering on, publishing an empty model")
+		return []models.CFItem{}, nil
+	}
+
+	clickInteractions := make([]cf.Interaction, 0, len(clicks))
+	for _, click := range clicks {
+		clickInteractions = append(clickInteractions, cf.Interaction{User: click.UserID, Item: click.AdID, Weight: 1})
+	}
+	interactions := slices.Clone(clickInteractions)
+	if cfg.PlaybackWeight > 0 {
+		for _, play := range plays {
+			interactions = append(interactions, cf.Interaction{User: play.UserID, Item: cfMovieItem(play.Category), Weight: cfg.PlaybackWeight})
+		}
+	}
+
+	model, err := cf.Train(interactions, cfTrainingConfig(cfg))
+	if err != nil {
+		return nil, err
+	}
+	similar := cf.CoOccurrence(clickInteractions, cfg.Neighbours, cfg.MinCoClicks)
+
+	items := make([]models.CFItem, 0, len(model.Items))
+	for id, factors := range model.Items {
+		item := models.CFItem{Kind: models.CFItemAd, ID: id, Factors: factors, Similar: similar[id]}
+		if category, ok := strings.CutPrefix(id, cfMovieItem("")); ok {
+			item = models.CFItem{Kind: models.CFItemMovie, ID: category, Factors: factors}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// deleteOlderCFRuns deletes the runs trained before the current one, published
+// or left unfinished. Failures are only logged; the next run retries them.
+func deleteOlderCFRuns(ctx context.Context, repo db.CFItemRepository, current models.CFRun) {
+	runs, err := repo.ListCFRuns(ctx)
+	if err != nil {
+		log.Printf("⚠️ Failed to list collaborative filtering runs: %v", err)
+		return
+	}
+	for _, run := range runs {
+		if run.ID == current.ID || !run.TrainedAt.Before(current.TrainedAt) {
+			continue
+		}
+		if err := repo.DeleteCFRun(ctx, run.ID); err != nil {
+			log.Printf("⚠️ Failed to delete collaborative filtering run %s: %v", run.ID, err)
+			continue
+		}
+		log.Printf("🗑️ Deleted collaborative filtering run %s", run.ID)
+	}
+}
+
+// cfTrainingConfig returns the ALS parameters of the configuration
+func cfTrainingConfig(cfg config.CFConfig) cf.Config {
+	return cf.Config{
+		Factors:        cfg.Factors,
+		Iterations:     cfg.Iterations,
+		Regularization: cfg.Regularization,
+		Alpha:          cfg.Alpha,
+		Seed:           cf.DefaultConfig.Seed,
+	}
+}
+
+// CFModel serves the collaborative filtering model last published by TrainCF:
+// the factors of ads and movie categories and the ads co-clicked with each
+// ad. Users are not stored; their factors are solved at request time from
+// their profile, so new clicks and plays count before the next training.
+type CFModel struct {
+	Items  db.CFItemRepository
+	Config config.CFConfig
+
+	mu      sync.RWMutex
+	ads     map[string][]float64 // Factors per ad ID
+	similar map[string]map[string]float64
+	solver  *cf.UserSolver
+	run     models.CFRun // Run served, zero before the first load
+	size    int          // Number of items served
+}
+
+// NewCFModel creates an empty CFModel loading from items
+func NewCFModel(items db.CFItemRepository, cfg config.CFConfig) *CFModel {
+	return &CFModel{Items: items, Config: cfg, ads: map[string][]float64{}, similar: map[string]map[string]float64{}}
+}
+
+// Load replaces the model with the published run, only reading its items when
+// the run changed. A run missing some of its items, deleted while being read,
+// leaves the model as it was. It returns the number of items served.
+func (m *CFModel) Load(ctx context.Context) (int, error) {
+	run, err := m.Items.CurrentCFRun(ctx)
+	if errors.Is(err, db.ErrNotFound) {
+		log.Printf("⚠️ No collaborative filtering run is published yet")
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	m.mu.RLock()
+	loaded, size := m.run.ID == run.ID, m.size
+	m.mu.RUnlock()
+	if loaded {
+		return size, nil
+	}
+
+	stored, err := m.Items.ListCFItems(ctx, run.ID)
+	if err != nil {
+		return 0, err
+	}
+	if len(stored) != run.Items {
+		return 0, fmt.Errorf("collaborative filtering run %s has %d of its %d items", run.ID, len(stored), run.Items)
+	}
+
+	ads, similar, factors := map[string][]float64{}, map[string]map[string]float64{}, map[string][]float64{}
+	size = 0
+	for _, item := range stored {
+		if len(item.Factors) == 0 {
+			continue
+		}
+		size = len(item.Factors)
+		switch item.Kind {
+		case models.CFItemAd:
+			ads[item.ID] = item.Factors
+			factors[item.ID] = item.Factors
+			if len(item.Similar) > 0 {
+				similar[item.ID] = item.Similar
+			}
+		case models.CFItemMovie:
+			factors[cfMovieItem(item.ID)] = item.Factors
+		}
+	}
+
+	// Users are solved with the factor size of the stored run, which may predate a change of CF_FACTORS
+	solverConfig := cfTrainingConfig(m.Config)
+	solverConfig.Factors = size
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ads, m.similar, m.run, m.size = ads, similar, *run, len(factors)
+	m.solver = nil
+	if size > 0 {
+		m.solver = cf.NewUserSolver(factors, solverConfig)
+	}
+
+	log.Printf("✅ Loaded collaborative filtering run %s of %d items trained at %s", run.ID, len(factors), run.TrainedAt.Format(time.RFC3339))
+	return len(factors), nil
+}
+
+// RunReloads reloads the model every interval until ctx is done
+func (m *CFModel) RunReloads(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := m.Load(ctx); err != nil {
+				log.Printf("⚠️ Failed to reload collaborative filtering model: %v", err)
+			}
+		}
+	}
+}
+
+// UserFactors solves the factors of a user from the decayed clicks and plays
+// of their profile. It returns nil when the model knows none of them.
+func (m *CFModel) UserFactors(profile UserProfile) []float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.solver == nil {
+		return nil
+	}
+
+	weights := make(map[string]float64, len(profile.ClickedAds)+len(profile.MovieCategories))
+	for adID, weight := range profile.ClickedAds {
+		weights[adID] = weight
+	}
+	if m.Config.PlaybackWeight > 0 {
+		for category, weight := range profile.MovieCategories {
+			weights[cfMovieItem(category)] = weight * m.Config.PlaybackWeight
+		}
+	}
+	return m.solver.Solve(weights)
+}
+
+// Score returns the preference of a user for an ad, false when the ad has no factors
+func (m *CFModel) Score(userFactors []float64, adID string) (float64, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	factors, ok := m.ads[adID]
+	if !ok || len(factors) != len(userFactors) {
+		return 0, false
+	}
+	return cf.Dot(userFactors, factors), true
+}
+
+// CoClicked returns the ads co-clicked with any of the clicked ads, scored by
+// their similarity times the click weight and summed, best first. Clicked ads
+// are left out.
+func (m *CFModel) CoClicked(clicked map[string]float64) []string {
+	m.mu.RLock()
+	scores := map[string]float64{}
+	for adID, weight := range clicked {
+		for neighbour, similarity := range m.similar[adID] {
+			if _, ok := clicked[neighbour]; !ok {
+				scores[neighbour] += similarity * weight
+			}
+		}
+	}
+	m.mu.RUnlock()
+	return rankedIDs(scores)
+}
+
+// Preferred returns the ads with the highest preference of the user, best first
+func (m *CFModel) Preferred(userFactors []float64) []string {
+	m.mu.RLock()
+	scores := make(map[string]float64, len(m.ads))
+	for adID, factors := range m.ads {
+		if len(factors) == len(userFactors) {
+			scores[adID] = cf.Dot(userFactors, factors)
+		}
+	}
+	m.mu.RUnlock()
+	return rankedIDs(scores)
+}
+
+// rankedIDs returns the keys of scores by descending score, then by ID
+func rankedIDs(scores map[string]float64) []string {
+	ids := make([]string, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+// CFScorer scores ads by the preference of the user for them under the
+// collaborative filtering model, relative to the best candidate. Ads or users
+// unknown to the model score 0.
+type CFScorer struct {
+	Model *CFModel
+}
+
+// Name identifies the scorer in weights
+func (s *CFScorer) Name() string { return config.ScorerCF }
+
+// Score returns the user's factors times each ad's, negative products as 0, normalized by the candidates' maximum
+func (s *CFScorer) Score(ctx context.Context, req *RankingRequest, ads []models.Ad) ([]float64, error) {
+	scores := make([]float64, len(ads))
+	userFactors := s.Model.UserFactors(req.Profile)
+	if userFactors == nil {
+		return scores, nil
+	}
+
+	maxScore := 0.0
+	for i, ad := range ads {
+		if score, ok := s.Model.Score(userFactors, ad.AdID); ok {
+			scores[i] = math.Max(score, 0)
+			maxScore = math.Max(maxScore, scores[i])
+		}
+	}
+	if maxScore > 0 {
+		for i := range scores {
+			scores[i] /= maxScore
+		}
+	}
+	return scores, nil
+}
+
+// CFCandidates retrieves up to K active ads by collaborative filtering: first
+// the ads co-clicked with the user's clicked ads, then the ads the user's
+// factors prefer. Ads the user clicked are left to the other sources. It
+// produces nothing for users unknown to the model.
+type CFCandidates struct {
+	Ads   db.AdRepository
+	Model *CFModel
+	K     int
+}
+
+// Name identifies the generator in logs and explanations
+func (g *CFCandidates) Name() string { return config.CandidatesCF }
+
+// Generate returns the co-clicked and preferred active ads not clicked by the user nor produced by an earlier generator
+func (g *CFCandidates) Generate(ctx context.Context, req *RankingRequest, seen map[string]bool) ([]models.Ad, error) {
+	adIDs := []string{}
+	picked := map[string]bool{}
+	pick := func(ids []string) {
+		for _, adID := range ids {
+			if len(adIDs) >= g.K {
+				return
+			}
+			if _, clicked := req.Profile.ClickedAds[adID]; !clicked && !seen[adID] && !picked[adID] {
+				picked[adID] = true
+				adIDs = append(adIDs, adID)
+			}
+		}
+	}
+	pick(g.Model.CoClicked(req.Profile.ClickedAds))
+	coClicked := len(adIDs)
+	if userFactors := g.Model.UserFactors(req.Profile); userFactors != nil {
+		pick(g.Model.Preferred(userFactors))
+	}
+
+	ads := fetchActiveAds(ctx, g.Ads, adIDs)
+	log.Printf("✅ Added %d ads from collaborative filtering (%d co-clicked)", len(ads), coClicked)
+	return ads, nil
+}
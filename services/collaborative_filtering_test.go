@@ -0,0 +1,100 @@
+package services
+
+import (
+	"Ad-Recommendations/config"
+	"Ad-Recommendations/db"
+	"Ad-Recommendations/models"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollaborativeFiltering(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewMemoryStore()
+	now := time.Now().UTC()
+
+	// Car fans click car ads and food fans food ads, everyone missing one of their cluster
+	fixture := &db.Fixture{}
+	for _, adID := range []string{"car-1", "car-2", "car-3", "food-1", "food-2", "food-3"} {
+		fixture.Ads = append(fixture.Ads, models.Ad{AdID: adID, Category: adID[:len(adID)-2]})
+	}
+	for u := 0; u < 20; u++ {
+		cluster := "car"
+		if u%2 == 1 {
+			cluster = "food"
+		}
+		for i := 1; i <= 3; i++ {
+			if (u+i)%3 != 0 {
+				fixture.Clicks = append(fixture.Clicks, models.AdClick{UserID: fmt.Sprintf("user-%d", u), AdID: fmt.Sprintf("%s-%d", cluster, i), Timestamp: now})
+			}
+		}
+	}
+	assert.NoError(t, fixture.Apply(ctx, store))
+
+	// An older published run is deleted, and a run still being written is not served
+	stale := models.CFRun{ID: "stale", TrainedAt: now.Add(-time.Hour), Items: 1, Published: true}
+	writing := models.CFRun{ID: "writing", TrainedAt: now.Add(time.Minute), Items: 2}
+	for _, run := range []models.CFRun{stale, writing} {
+		assert.NoError(t, store.CFItems.PutCFRun(ctx, run))
+		assert.NoError(t, store.CFItems.PutCFItems(ctx, run.ID, []models.CFItem{{Kind: models.CFItemAd, ID: "stale", Factors: []float64{1, 1}}}))
+	}
+
+	cfg := config.CFConfig{Factors: 2, Iterations: 15, Regularization: 0.1, Alpha: 10, Neighbours: 2, MinCoClicks: 2, Window: time.Hour}
+	trained, err := TrainCF(ctx, store, cfg, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, trained)
+	runs, err := store.CFItems.ListCFRuns(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, runs, 2)
+	assert.NotContains(t, runs, stale)
+
+	model := NewCFModel(store.CFItems, cfg)
+	loaded, err := model.Load(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, loaded)
+
+	// A published run missing items leaves the loaded model in place
+	assert.NoError(t, store.CFItems.PutCFRun(ctx, models.CFRun{ID: "partial", TrainedAt: now.Add(time.Second), Items: 6, Published: true}))
+	_, err = model.Load(ctx)
+	assert.Error(t, err)
+
+	req := &RankingRequest{Profile: UserProfile{ClickedAds: map[string]float64{"car-1": 1}}}
+	generator := &CFCandidates{Ads: store.Ads, Model: model, K: 3}
+	ads, err := generator.Generate(ctx, req, map[string]bool{"car-3": true})
+	assert.NoError(t, err)
+	assert.Len(t, ads, 3)
+	assert.Equal(t, "car-2", ads[0].AdID, "co-clicked ads come first")
+	for _, ad := range ads {
+		assert.NotContains(t, []string{"car-1", "car-3", "stale"}, ad.AdID)
+	}
+
+	scores, err := (&CFScorer{Model: model}).Score(ctx, req, []models.Ad{{AdID: "car-2"}, {AdID: "food-2"}, {AdID: "stale"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, scores[0])
+	assert.Less(t, scores[1], 0.5)
+	assert.Zero(t, scores[2])
+
+	// Users the model knows nothing of get no candidates and score 0
+	unknown := &RankingRequest{Profile: UserProfile{ClickedAds: map[string]float64{"new-ad": 1}}}
+	ads, err = generator.Generate(ctx, unknown, map[string]bool{})
+	assert.NoError(t, err)
+	assert.Empty(t, ads)
+	scores, err = (&CFScorer{Model: model}).Score(ctx, unknown, []models.Ad{{AdID: "car-2"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{0}, scores)
+
+	// Once the clicks leave the window an empty model replaces the old one
+	trained, err = TrainCF(ctx, store, cfg, now.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Zero(t, trained)
+	loaded, err = model.Load(ctx)
+	assert.NoError(t, err)
+	assert.Zero(t, loaded)
+	ads, err = generator.Generate(ctx, req, map[string]bool{})
+	assert.NoError(t, err)
+	assert.Empty(t, ads)
+}
@@ -0,0 +1,298 @@
+package services
+
+import (
+	"Ad-Recommendations/db"
+	"Ad-Recommendations/models"
+	"context"
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+// minFitImpressions is how many impressions an ad or pair needs to take part in fitting a prior
+const minFitImpressions = 20
+
+// maxPriorStrength bounds a fitted prior when the observed rates barely vary
+const maxPriorStrength = 1e4
+
+// BetaPrior is a Beta(Alpha, Beta) prior over a click-through rate
+type BetaPrior struct {
+	Alpha float64 `json:"alpha"`
+	Beta  float64 `json:"beta"`
+}
+
+// Smooth returns the posterior mean rate after clicks in impressions
+func (p BetaPrior) Smooth(clicks, impressions int) float64 {
+	total := float64(impressions) + p.Alpha + p.Beta
+	if total <= 0 {
+		return 0
+	}
+	return (float64(clicks) + p.Alpha) / total
+}
+
+// Strength returns the weight of the prior in impressions
+func (p BetaPrior) Strength() float64 {
+	return p.Alpha + p.Beta
+}
+
+// FitBetaPrior fits a prior centred on the global rate to the counts of many
+// ads or pairs. Its strength comes from the method of moments over the rates
+// of the counts with enough impressions, so the more those rates differ, the
+// less the prior weighs. fallbackStrength is used when fewer than two counts
+// qualify or the rates do not differ more than chance.
+func FitBetaPrior(counts []ctrCounts, fallbackStrength float64) BetaPrior {
+	clicks, impressions := 0, 0
+	for _, count := range counts {
+		clicks += count.clicks
+		impressions += count.impressions
+	}
+	mean := 0.0
+	if impressions > 0 {
+		mean = float64(clicks) / float64(impressions)
+	}
+
+	strength := fallbackStrength
+	fitted, weight, variance := 0, 0.0, 0.0
+	for _, count := range counts {
+		if count.impressions < minFitImpressions {
+			continue
+		}
+		rate := float64(count.clicks) / float64(count.impressions)
+		fitted++
+		weight += float64(count.impressions)
+		variance += float64(count.impressions) * (rate - mean) * (rate - mean)
+	}
+	if fitted >= 2 && variance > 0 {
+		variance /= weight
+		if moments := mean*(1-mean)/variance - 1; moments > 0 && !math.IsInf(moments, 0) {
+			strength = math.Min(moments, maxPriorStrength)
+		}
+	}
+	return BetaPrior{Alpha: mean * strength, Beta: (1 - mean) * strength}
+}
+
+// CTREstimator estimates click-through rates with Bayesian smoothing. It
+// counts impressions and clicks per ad and per pair of ad category and the
+// user's favourite movie category as they are logged. A pair's rate is
+// smoothed towards the global rate, and an ad's rate towards the rate of its
+// pair, so new ads start from how their category does with the user's taste.
+// Snapshot adds the counts since the last snapshot to storage, where the
+// estimators of every instance sum up, and refits the priors.
+type CTREstimator struct {
+	Counts           db.CTRCountRepository
+	FallbackStrength float64 // Prior strength when it cannot be fitted
+
+	mu        sync.RWMutex
+	ads       map[string]*ctrCounts
+	pairs     map[string]*ctrCounts
+	pending   map[[2]string]*ctrCounts // Counts by kind and key since the last snapshot
+	adPrior   BetaPrior
+	pairPrior BetaPrior
+}
+
+// NewCTREstimator creates an empty CTREstimator saving to counts
+func NewCTREstimator(counts db.CTRCountRepository, fallbackStrength float64) *CTREstimator {
+	e := &CTREstimator{
+		Counts:           counts,
+		FallbackStrength: fallbackStrength,
+		ads:              map[string]*ctrCounts{},
+		pairs:            map[string]*ctrCounts{},
+		pending:          map[[2]string]*ctrCounts{},
+	}
+	e.refit()
+	return e
+}
+
+// RecordImpression counts a served ad
+func (e *CTREstimator) RecordImpression(impression models.Impression) {
+	e.add(impression, 1, 0)
+}
+
+// RecordClick counts the first click on a served ad
+func (e *CTREstimator) RecordClick(impression models.Impression) {
+	e.add(impression, 0, 1)
+}
+
+// add counts impressions and clicks for the ad and pair of an impression
+func (e *CTREstimator) add(impression models.Impression, impressions, clicks int) {
+	pair := pairKey(impression.Category, impression.MovieCategory)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.addCount(models.CTRCountAd, impression.AdID, impressions, clicks)
+	e.addCount(models.CTRCountPair, pair, impressions, clicks)
+}
+
+// addCount adds to a count and to its pending delta; callers hold the lock
+func (e *CTREstimator) addCount(kind, key string, impressions, clicks int) {
+	for _, count := range []*ctrCounts{e.count(kind, key), e.pendingCount(kind, key)} {
+		count.impressions += impressions
+		count.clicks += clicks
+	}
+}
+
+// count returns the count of a kind and key, created when missing; callers hold the lock
+func (e *CTREstimator) count(kind, key string) *ctrCounts {
+	counts := e.ads
+	if kind == models.CTRCountPair {
+		counts = e.pairs
+	}
+	if counts[key] == nil {
+		counts[key] = &ctrCounts{}
+	}
+	return counts[key]
+}
+
+// pendingCount returns the pending delta of a kind and key, created when missing; callers hold the lock
+func (e *CTREstimator) pendingCount(kind, key string) *ctrCounts {
+	kindKey := [2]string{kind, key}
+	if e.pending[kindKey] == nil {
+		e.pending[kindKey] = &ctrCounts{}
+	}
+	return e.pending[kindKey]
+}
+
+// Estimate returns the smoothed click-through rate of an ad of adCategory
+// served to a user whose favourite movie category is movieCategory
+func (e *CTREstimator) Estimate(adID, adCategory, movieCategory string) float64 {
+	posterior := e.Posterior(adID, adCategory, movieCategory)
+	return posterior.Smooth(0, 0)
+}
+
+// Posterior returns the Beta distribution of the click-through rate of an ad:
+// the ad prior centred on the smoothed rate of its pair, updated with the
+// ad's own impressions and clicks
+func (e *CTREstimator) Posterior(adID, adCategory, movieCategory string) BetaPrior {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	pairRate := e.pairPrior.Smooth(countsOf(e.pairs[pairKey(adCategory, movieCategory)]))
+	strength := e.adPrior.Strength()
+	clicks, impressions := countsOf(e.ads[adID])
+	return BetaPrior{
+		Alpha: pairRate*strength + float64(clicks),
+		Beta:  (1-pairRate)*strength + float64(impressions-clicks),
+	}
+}
+
+// Priors returns the priors of ad and pair rates as last fitted
+func (e *CTREstimator) Priors() (BetaPrior, BetaPrior) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.adPrior, e.pairPrior
+}
+
+// Load replaces the counts with the stored totals, plus the counts not
+// snapshotted yet, and fits the priors. It returns the number of counts read.
+func (e *CTREstimator) Load(ctx context.Context) (int, error) {
+	stored, err := e.Counts.ListCTRCounts(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.ads, e.pairs = map[string]*ctrCounts{}, map[string]*ctrCounts{}
+	for _, count := range stored {
+		counts := &ctrCounts{impressions: count.Impressions, clicks: count.Clicks}
+		switch count.Kind {
+		case models.CTRCountAd:
+			e.ads[count.Key] = counts
+		case models.CTRCountPair:
+			e.pairs[count.Key] = counts
+		}
+	}
+	for kindKey, delta := range e.pending {
+		count := e.count(kindKey[0], kindKey[1])
+		count.impressions += delta.impressions
+		count.clicks += delta.clicks
+	}
+	e.refit()
+
+	log.Printf("✅ Loaded %d CTR counts, ad prior %+v, pair prior %+v", len(stored), e.adPrior, e.pairPrior)
+	return len(stored), nil
+}
+
+// Snapshot adds the counts since the last snapshot to storage and refits the
+// priors. Counts that fail to save are added with the next snapshot. It
+// returns the number of counts saved.
+func (e *CTREstimator) Snapshot(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+
+	e.mu.Lock()
+	deltas := make([]models.CTRCount, 0, len(e.pending))
+	for kindKey, delta := range e.pending {
+		deltas = append(deltas, models.CTRCount{
+			Kind: kindKey[0], Key: kindKey[1], Impressions: delta.impressions, Clicks: delta.clicks, UpdatedAt: now,
+		})
+	}
+	e.pending = map[[2]string]*ctrCounts{}
+	e.refit()
+	e.mu.Unlock()
+
+	if len(deltas) == 0 {
+		return 0, nil
+	}
+	added, err := e.Counts.AddCTRCounts(ctx, deltas)
+	if err != nil {
+		e.mu.Lock()
+		for _, delta := range deltas[added:] {
+			pending := e.pendingCount(delta.Kind, delta.Key)
+			pending.impressions += delta.Impressions
+			pending.clicks += delta.Clicks
+		}
+		e.mu.Unlock()
+		return added, err
+	}
+	return added, nil
+}
+
+// RunSnapshots snapshots the counts every interval until ctx is done
+func (e *CTREstimator) RunSnapshots(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			saved, err := e.Snapshot(ctx)
+			if err != nil {
+				log.Printf("⚠️ Failed to snapshot CTR counts: %v", err)
+				continue
+			}
+			if saved > 0 {
+				log.Printf("💾 Snapshotted %d CTR counts", saved)
+			}
+		}
+	}
+}
+
+// refit fits both priors to the current counts; callers hold the lock
+func (e *CTREstimator) refit() {
+	e.adPrior = FitBetaPrior(countList(e.ads), e.FallbackStrength)
+	e.pairPrior = FitBetaPrior(countList(e.pairs), e.FallbackStrength)
+}
+
+// countList returns the values of a count map
+func countList(counts map[string]*ctrCounts) []ctrCounts {
+	list := make([]ctrCounts, 0, len(counts))
+	for _, count := range counts {
+		list = append(list, *count)
+	}
+	return list
+}
+
+// countsOf returns the clicks and impressions of a count, 0 when missing
+func countsOf(count *ctrCounts) (int, int) {
+	if count == nil {
+		return 0, 0
+	}
+	return count.clicks, count.impressions
+}
+
+// pairKey joins an ad category and a movie category into a CTRCountPair key
+func pairKey(adCategory, movieCategory string) string {
+	return adCategory + "|" + movieCategory
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"Ad-Recommendations/db"
+	"Ad-Recommendations/models"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFitBetaPrior(t *testing.T) {
+	// Too little data keeps the fallback strength around the global rate
+	prior := FitBetaPrior([]ctrCounts{{impressions: 10, clicks: 1}}, 100)
+	assert.InDelta(t, 10, prior.Alpha, 1e-9)
+	assert.InDelta(t, 90, prior.Beta, 1e-9)
+
+	// Rates that differ a lot give a weak prior
+	spread := FitBetaPrior([]ctrCounts{{impressions: 100, clicks: 2}, {impressions: 100, clicks: 38}}, 100)
+	assert.InDelta(t, 0.2, spread.Alpha/spread.Strength(), 1e-9)
+	assert.Less(t, spread.Strength(), 100.0)
+	assert.InDelta(t, 0.2*0.8/0.0324-1, spread.Strength(), 1e-9)
+
+	assert.Equal(t, BetaPrior{Alpha: 0, Beta: 100}, FitBetaPrior(nil, 100))
+}
+
+func TestCTREstimatorSmoothsAndSnapshots(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewMemoryStore()
+	estimator := NewCTREstimator(store.CTRCounts, 10)
+
+	serve := func(adID, category, movieCategory string, impressions, clicks int) {
+		impression := models.Impression{AdID: adID, Category: category, MovieCategory: movieCategory}
+		for i := 0; i < impressions; i++ {
+			estimator.RecordImpression(impression)
+		}
+		for i := 0; i < clicks; i++ {
+			estimator.RecordClick(impression)
+		}
+	}
+	serve("car", "Cars", "Action", 10, 5)
+	serve("van", "Cars", "Comedy", 10, 0)
+
+	saved, err := estimator.Snapshot(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, saved)
+
+	// Pairs are smoothed towards the global rate of 0.25 with the fallback strength
+	adPrior, pairPrior := estimator.Priors()
+	assert.InDelta(t, 2.5, pairPrior.Alpha, 1e-9)
+	assert.InDelta(t, 10, adPrior.Strength(), 1e-9)
+	pairRate := (5 + 2.5) / 20.0
+	assert.InDelta(t, (5+pairRate*10)/20, estimator.Estimate("car", "Cars", "Action"), 1e-9)
+	// A new ad starts from its pair
+	assert.InDelta(t, pairRate, estimator.Estimate("new", "Cars", "Action"), 1e-9)
+	assert.Less(t, estimator.Estimate("new", "Cars", "Comedy"), pairRate)
+
+	restored := NewCTREstimator(store.CTRCounts, 10)
+	loaded, err := restored.Load(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, loaded)
+	assert.Equal(t, estimator.Estimate("car", "Cars", "Action"), restored.Estimate("car", "Cars", "Action"))
+
+	saved, err = estimator.Snapshot(ctx)
+	assert.NoError(t, err)
+	assert.Zero(t, saved)
+
+	scores, err := (&CTRScorer{Estimator: estimator}).Score(ctx, &RankingRequest{
+		Profile: UserProfile{MovieCategories: map[string]float64{"Action": 2, "Comedy": 1}},
+	}, []models.Ad{{AdID: "car", Category: "Cars"}, {AdID: "van", Category: "Cars"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, scores[0])
+	assert.Less(t, scores[1], 0.5)
+}
+
+func TestCTREstimatorsShareCounts(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewMemoryStore()
+	first, second := NewCTREstimator(store.CTRCounts, 10), NewCTREstimator(store.CTRCounts, 10)
+	impression := models.Impression{AdID: "car", Category: "Cars", MovieCategory: "Action"}
+
+	// Both instances count and snapshot twice; every event is kept
+	for round := 0; round < 2; round++ {
+		for _, estimator := range []*CTREstimator{first, second} {
+			estimator.RecordImpression(impression)
+			estimator.RecordImpression(impression)
+			estimator.RecordClick(impression)
+			_, err := estimator.Snapshot(ctx)
+			assert.NoError(t, err)
+		}
+	}
+
+	counts, err := store.CTRCounts.ListCTRCounts(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, counts, 2)
+	for _, count := range counts {
+		assert.Equal(t, 8, count.Impressions)
+		assert.Equal(t, 4, count.Clicks)
+	}
+
+	// Counts not snapshotted yet survive a load of the totals
+	first.RecordImpression(impression)
+	_, err = first.Load(ctx)
+	assert.NoError(t, err)
+	posterior := first.Posterior("car", "Cars", "Action")
+	assert.InDelta(t, 4+10*4.0/9, posterior.Alpha, 1e-9)
+	assert.InDelta(t, 5+10*5.0/9, posterior.Beta, 1e-9)
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"Ad-Recommendations/models"
+	"sync"
+	"time"
+)
+
+// Dimensions click-through rates are kept for
+const (
+	ctrAd       = "ad"
+	ctrCategory = "category"
+	ctrSegment  = "segment"
+)
+
+// CTR is the click-through rate of an ad, category or segment over the rolling window
+type CTR struct {
+	Impressions int     `json:"impressions"`
+	Clicks      int     `json:"clicks"`
+	Rate        float64 `json:"rate"` // Clicks per impression, 0 without impressions
+}
+
+// CTRReport holds the rolling click-through rates of every ad, category and segment
+type CTRReport struct {
+	Window     string         `json:"window"`
+	Ads        map[string]CTR `json:"ads"`
+	Categories map[string]CTR `json:"categories"`
+	Segments   map[string]CTR `json:"segments"`
+}
+
+// CTRTracker maintains click-through rates over a rolling window. Counts are
+// kept in time buckets by serve time, and a click counts in the bucket of its
+// impression, so a rate is the share of the impressions served in the window
+// that were clicked. The window advances one bucket at a time.
+type CTRTracker struct {
+	Window time.Duration
+	Bucket time.Duration
+	Now    func() time.Time
+
+	mu       sync.Mutex
+	counters map[ctrKey]map[int64]*ctrCounts // Bucket index to counts
+}
+
+// ctrKey identifies one ad, category or segment
+type ctrKey struct {
+	dimension string
+	value     string
+}
+
+// ctrCounts are the impressions and clicks of one bucket
+type ctrCounts struct {
+	impressions int
+	clicks      int
+}
+
+// NewCTRTracker creates a CTRTracker over window divided into buckets
+func NewCTRTracker(window time.Duration, buckets int) *CTRTracker {
+	bucket := window / time.Duration(max(buckets, 1))
+	if bucket <= 0 {
+		bucket = window
+	}
+	return &CTRTracker{Window: window, Bucket: bucket, Now: time.Now, counters: map[ctrKey]map[int64]*ctrCounts{}}
+}
+
+// RecordImpression counts a served ad
+func (t *CTRTracker) RecordImpression(impression models.Impression) {
+	t.add(impression, 1, 0)
+}
+
+// RecordClick counts the first click on a served ad
+func (t *CTRTracker) RecordClick(impression models.Impression) {
+	t.add(impression, 0, 1)
+}
+
+// add counts impressions and clicks in the bucket of the impression for each of its dimensions
+func (t *CTRTracker) add(impression models.Impression, impressions, clicks int) {
+	bucket := t.bucketOf(impression.Timestamp)
+	oldest := t.oldestBucket()
+	if bucket < oldest {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, key := range impressionKeys(impression) {
+		buckets := t.counters[key]
+		if buckets == nil {
+			buckets = map[int64]*ctrCounts{}
+			t.counters[key] = buckets
+		}
+		for index := range buckets {
+			if index < oldest {
+				delete(buckets, index)
+			}
+		}
+		if buckets[bucket] == nil {
+			buckets[bucket] = &ctrCounts{}
+		}
+		buckets[bucket].impressions += impressions
+		buckets[bucket].clicks += clicks
+	}
+}
+
+// AdCTR returns the rolling click-through rate of an ad
+func (t *CTRTracker) AdCTR(adID string) CTR {
+	return t.rate(ctrKey{ctrAd, adID})
+}
+
+// CategoryCTR returns the rolling click-through rate of an ad category
+func (t *CTRTracker) CategoryCTR(category string) CTR {
+	return t.rate(ctrKey{ctrCategory, category})
+}
+
+// SegmentCTR returns the rolling click-through rate of a user segment
+func (t *CTRTracker) SegmentCTR(segment string) CTR {
+	return t.rate(ctrKey{ctrSegment, segment})
+}
+
+// Report returns the rolling click-through rates of everything served in the
+// window, dropping what fell out of it
+func (t *CTRTracker) Report() CTRReport {
+	report := CTRReport{
+		Window:     t.Window.String(),
+		Ads:        map[string]CTR{},
+		Categories: map[string]CTR{},
+		Segments:   map[string]CTR{},
+	}
+	byDimension := map[string]map[string]CTR{ctrAd: report.Ads, ctrCategory: report.Categories, ctrSegment: report.Segments}
+	oldest := t.oldestBucket()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key, buckets := range t.counters {
+		ctr := sumBuckets(buckets, oldest)
+		if ctr.Impressions == 0 && ctr.Clicks == 0 {
+			delete(t.counters, key)
+			continue
+		}
+		byDimension[key.dimension][key.value] = ctr
+	}
+	return report
+}
+
+// rate sums the buckets of one key inside the window
+func (t *CTRTracker) rate(key ctrKey) CTR {
+	oldest := t.oldestBucket()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return sumBuckets(t.counters[key], oldest)
+}
+
+// bucketOf returns the index of the bucket holding a time
+func (t *CTRTracker) bucketOf(at time.Time) int64 {
+	return at.UnixNano() / int64(t.Bucket)
+}
+
+// oldestBucket returns the index of the oldest bucket inside the window
+func (t *CTRTracker) oldestBucket() int64 {
+	return t.bucketOf(t.Now().Add(-t.Window)) + 1
+}
+
+// sumBuckets adds up the buckets from oldest on
+func sumBuckets(buckets map[int64]*ctrCounts, oldest int64) CTR {
+	ctr := CTR{}
+	for index, counts := range buckets {
+		if index >= oldest {
+			ctr.Impressions += counts.impressions
+			ctr.Clicks += counts.clicks
+		}
+	}
+	if ctr.Impressions > 0 {
+		ctr.Rate = float64(ctr.Clicks) / float64(ctr.Impressions)
+	}
+	return ctr
+}
+
+// impressionKeys lists the ad, category and segment an impression counts for;
+// impressions without a category or segment are not counted for it
+func impressionKeys(impression models.Impression) []ctrKey {
+	keys := []ctrKey{{ctrAd, impression.AdID}}
+	if impression.Category != "" {
+		keys = append(keys, ctrKey{ctrCategory, impression.Category})
+	}
+	if impression.Segment != "" {
+		keys = append(keys, ctrKey{ctrSegment, impression.Segment})
+	}
+	return keys
+}
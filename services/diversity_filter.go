@@ -0,0 +1,144 @@
+package services
+
+import (
+	"Ad-Recommendations/models"
+	"Ad-Recommendations/vector"
+	"context"
+	"log"
+	"math"
+)
+
+// DiversityFilter re-ranks candidates with Maximal Marginal Relevance over ad
+// embeddings and caps how many ads of one category or advertiser are kept.
+// Each pick maximizes Lambda * relevance - (1 - Lambda) * the highest
+// similarity to the ads already picked, until the request limit is reached.
+type DiversityFilter struct {
+	Embeddings       *AdEmbeddingService // Source of ad vectors; ads without one are compared by category
+	Lambda           float64             // 1 ranks by score only
+	MaxPerCategory   int                 // 0 for no cap
+	MaxPerAdvertiser int                 // 0 for no cap; ads without an advertiser are not capped
+}
+
+// Name identifies the filter in logs
+func (f *DiversityFilter) Name() string { return "diversity" }
+
+// Filter returns up to req.Options.Limit candidates in MMR order within the caps
+func (f *DiversityFilter) Filter(ctx context.Context, req *RankingRequest, candidates []Candidate) []Candidate {
+	if len(candidates) == 0 || (f.Lambda >= 1 && f.MaxPerCategory <= 0 && f.MaxPerAdvertiser <= 0) {
+		return candidates
+	}
+
+	limit := req.Options.Limit
+	if limit <= 0 || limit > len(candidates) {
+		limit = len(candidates)
+	}
+	relevance := normalizedScores(candidates)
+	similarity := f.similarity(ctx, candidates)
+
+	used := make([]bool, len(candidates))
+	maxSimilarity := make([]float64, len(candidates)) // To the ads picked so far
+	perCategory := map[string]int{}
+	perAdvertiser := map[string]int{}
+	selected := make([]Candidate, 0, limit)
+
+	for len(selected) < limit {
+		best, bestValue := -1, math.Inf(-1)
+		for i, candidate := range candidates {
+			if used[i] || f.capped(candidate.Ad, perCategory, perAdvertiser) {
+				continue
+			}
+			value := f.Lambda*relevance[i] - (1-f.Lambda)*maxSimilarity[i]
+			if value > bestValue {
+				best, bestValue = i, value
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		picked := candidates[best]
+		used[best] = true
+		selected = append(selected, picked)
+		perCategory[picked.Ad.Category]++
+		if picked.Ad.Advertiser != "" {
+			perAdvertiser[picked.Ad.Advertiser]++
+		}
+		if f.Lambda < 1 {
+			for i := range candidates {
+				if !used[i] {
+					maxSimilarity[i] = math.Max(maxSimilarity[i], similarity(i, best))
+				}
+			}
+		}
+	}
+
+	if len(selected) < limit {
+		log.Printf("🎨 Diversity re-ranking kept %d of %d candidates", len(selected), len(candidates))
+	}
+	return selected
+}
+
+// capped reports whether an ad's category or advertiser already reached its cap
+func (f *DiversityFilter) capped(ad models.Ad, perCategory, perAdvertiser map[string]int) bool {
+	if f.MaxPerCategory > 0 && perCategory[ad.Category] >= f.MaxPerCategory {
+		return true
+	}
+	return f.MaxPerAdvertiser > 0 && ad.Advertiser != "" && perAdvertiser[ad.Advertiser] >= f.MaxPerAdvertiser
+}
+
+// similarity returns the pairwise similarity of candidates: the cosine of
+// their embeddings when both are available, otherwise 1 for ads of the same
+// category and 0 for ads of different categories
+func (f *DiversityFilter) similarity(ctx context.Context, candidates []Candidate) func(i, j int) float64 {
+	byCategory := func(i, j int) float64 {
+		if candidates[i].Ad.Category == candidates[j].Ad.Category {
+			return 1
+		}
+		return 0
+	}
+	if f.Lambda >= 1 || f.Embeddings == nil {
+		return byCategory
+	}
+
+	ads := make([]models.Ad, len(candidates))
+	for i, candidate := range candidates {
+		ads[i] = candidate.Ad
+	}
+	adVectors, err := f.Embeddings.EnsureEmbeddings(ctx, ads)
+	if err != nil {
+		log.Printf("⚠️ Diversity re-ranking falls back to category similarity: %v", err)
+		return byCategory
+	}
+
+	return func(i, j int) float64 {
+		a, b := adVectors[candidates[i].Ad.AdID], adVectors[candidates[j].Ad.AdID]
+		if a == nil || b == nil {
+			return byCategory(i, j)
+		}
+		score, err := vector.Cosine(a, b)
+		if err != nil {
+			return byCategory(i, j)
+		}
+		return score
+	}
+}
+
+// normalizedScores rescales candidate scores to [0, 1] so they are comparable
+// to similarities; equal scores all become 1
+func normalizedScores(candidates []Candidate) []float64 {
+	minScore, maxScore := math.Inf(1), math.Inf(-1)
+	for _, candidate := range candidates {
+		minScore = math.Min(minScore, candidate.Score)
+		maxScore = math.Max(maxScore, candidate.Score)
+	}
+
+	scores := make([]float64, len(candidates))
+	for i, candidate := range candidates {
+		if maxScore > minScore {
+			scores[i] = (candidate.Score - minScore) / (maxScore - minScore)
+		} else {
+			scores[i] = 1
+		}
+	}
+	return scores
+}
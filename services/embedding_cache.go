@@ -0,0 +1,255 @@
+package services
+
+import (
+	"Ad-Recommendations/models"
+	"Ad-Recommendations/vector"
+	"container/list"
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RemoteEmbeddingCache is a shared cache tier behind the in-memory LRU
+type RemoteEmbeddingCache interface {
+	// GetMany returns the cached vectors by key; missing keys are omitted
+	GetMany(ctx context.Context, keys []string) (map[string][]float64, error)
+	// SetMany stores vectors by key with the given time to live
+	SetMany(ctx context.Context, vectors map[string][]float64, ttl time.Duration) error
+}
+
+// CacheStats counts embedding cache lookups per tier
+type CacheStats struct {
+	LocalHits  int64 `json:"local_hits"`
+	RemoteHits int64 `json:"remote_hits"`
+	Misses     int64 `json:"misses"`
+	Entries    int   `json:"entries"` // Entries in the in-memory tier
+}
+
+// CachingEmbedder wraps an Embedder with an in-memory LRU tier and an optional
+// remote tier. Entries are keyed by model and a hash of the text, so texts
+// embedded by another model or version are never returned.
+type CachingEmbedder struct {
+	Next   Embedder
+	Model  string // Model name and version the cached vectors belong to
+	Local  *LRUCache
+	Remote RemoteEmbeddingCache // Optional
+	TTL    time.Duration
+
+	localHits  atomic.Int64
+	remoteHits atomic.Int64
+	misses     atomic.Int64
+}
+
+// NewCachingEmbedder creates a CachingEmbedder holding up to size vectors in memory
+func NewCachingEmbedder(next Embedder, model string, size int, ttl time.Duration, remote RemoteEmbeddingCache) *CachingEmbedder {
+	return &CachingEmbedder{Next: next, Model: model, Local: NewLRUCache(size, ttl), Remote: remote, TTL: ttl}
+}
+
+// Embed returns cached vectors and embeds only the texts missing from every tier
+func (c *CachingEmbedder) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	embeddings := make([][]float64, len(texts))
+	missing := map[string][]int{} // Cache key to the positions of its text
+
+	for i, text := range texts {
+		key := c.key(text)
+		if cached, ok := c.Local.Get(key); ok {
+			c.localHits.Add(1)
+			embeddings[i] = cached
+			continue
+		}
+		missing[key] = append(missing[key], i)
+	}
+
+	if len(missing) > 0 && c.Remote != nil {
+		keys := make([]string, 0, len(missing))
+		for key := range missing {
+			keys = append(keys, key)
+		}
+		found, err := c.Remote.GetMany(ctx, keys)
+		if err != nil {
+			log.Printf("⚠️ Remote embedding cache unavailable: %v", err)
+		}
+		for key, cached := range found {
+			c.remoteHits.Add(int64(len(missing[key])))
+			c.Local.Put(key, cached)
+			for _, i := range missing[key] {
+				embeddings[i] = cached
+			}
+			delete(missing, key)
+		}
+	}
+
+	if len(missing) == 0 {
+		return embeddings, nil
+	}
+
+	// Embed each missing text once, however often it appears
+	keys := make([]string, 0, len(missing))
+	missingTexts := make([]string, 0, len(missing))
+	for key, positions := range missing {
+		keys = append(keys, key)
+		missingTexts = append(missingTexts, texts[positions[0]])
+		c.misses.Add(int64(len(positions)))
+	}
+
+	computed, err := c.Next.Embed(ctx, missingTexts)
+	if err != nil {
+		return nil, err
+	}
+	if len(computed) != len(missingTexts) {
+		return nil, fmt.Errorf("embedding service returned %d vectors for %d texts", len(computed), len(missingTexts))
+	}
+
+	remoteEntries := make(map[string][]float64, len(keys))
+	for j, key := range keys {
+		c.Local.Put(key, computed[j])
+		remoteEntries[key] = computed[j]
+		for _, i := range missing[key] {
+			embeddings[i] = computed[j]
+		}
+	}
+	if c.Remote != nil {
+		if err := c.Remote.SetMany(ctx, remoteEntries, c.TTL); err != nil {
+			log.Printf("⚠️ Failed to write remote embedding cache: %v", err)
+		}
+	}
+
+	return embeddings, nil
+}
+
+// Put stores a known embedding of a text in the in-memory tier
+func (c *CachingEmbedder) Put(text string, values []float64) {
+	c.Local.Put(c.key(text), values)
+}
+
+// Stats returns the lookup counters of the cache
+func (c *CachingEmbedder) Stats() CacheStats {
+	return CacheStats{
+		LocalHits:  c.localHits.Load(),
+		RemoteHits: c.remoteHits.Load(),
+		Misses:     c.misses.Load(),
+		Entries:    c.Local.Len(),
+	}
+}
+
+// key identifies a text embedded by the cache's model
+func (c *CachingEmbedder) key(text string) string {
+	return "emb:" + c.Model + ":" + models.ContentHash(text)
+}
+
+// LRUCache is a size-bounded, thread-safe vector cache with per-entry expiry.
+// Vectors are held as float32.
+type LRUCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration // 0 for no expiry
+	order   *list.List    // Most recently used first
+	entries map[string]*list.Element
+}
+
+// lruEntry is one cached vector
+type lruEntry struct {
+	key       string
+	vector    vector.Vector
+	expiresAt time.Time
+}
+
+// NewLRUCache creates an LRUCache holding up to size entries for ttl each
+func NewLRUCache(size int, ttl time.Duration) *LRUCache {
+	return &LRUCache{size: size, ttl: ttl, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+// Get returns an unexpired entry and marks it as recently used
+func (c *LRUCache) Get(key string) ([]float64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*lruEntry)
+	if c.ttl > 0 && time.Now().After(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return entry.vector.Float64(), true
+}
+
+// Put stores an entry, evicting the least recently used one when full
+func (c *LRUCache) Put(key string, values []float64) {
+	if c.size <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(c.ttl)
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*lruEntry)
+		entry.vector = vector.FromFloat64(values)
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, vector: vector.FromFloat64(values), expiresAt: expiresAt})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// Len returns the number of cached entries, including expired ones not yet evicted
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// RedisEmbeddingCache stores vectors in Redis packed by vector.Encode
+type RedisEmbeddingCache struct {
+	Client *redis.Client
+}
+
+// GetMany returns the cached vectors by key; missing keys are omitted
+func (r *RedisEmbeddingCache) GetMany(ctx context.Context, keys []string) (map[string][]float64, error) {
+	values, err := r.Client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string][]float64, len(keys))
+	for i, value := range values {
+		raw, ok := value.(string)
+		if !ok {
+			continue
+		}
+		decoded, err := vector.Decode([]byte(raw))
+		if err != nil {
+			log.Printf("⚠️ Ignoring unreadable cached embedding %s: %v", keys[i], err)
+			continue
+		}
+		found[keys[i]] = decoded
+	}
+	return found, nil
+}
+
+// SetMany stores vectors by key with the given time to live
+func (r *RedisEmbeddingCache) SetMany(ctx context.Context, vectors map[string][]float64, ttl time.Duration) error {
+	pipe := r.Client.Pipeline()
+	for key, values := range vectors {
+		pipe.Set(ctx, key, vector.Encode(values), ttl)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRemoteCache is an in-memory RemoteEmbeddingCache
+type fakeRemoteCache map[string][]float64
+
+func (f fakeRemoteCache) GetMany(ctx context.Context, keys []string) (map[string][]float64, error) {
+	found := map[string][]float64{}
+	for _, key := range keys {
+		if vector, ok := f[key]; ok {
+			found[key] = vector
+		}
+	}
+	return found, nil
+}
+
+func (f fakeRemoteCache) SetMany(ctx context.Context, vectors map[string][]float64, ttl time.Duration) error {
+	for key, vector := range vectors {
+		f[key] = vector
+	}
+	return nil
+}
+
+func TestCachingEmbedderTiers(t *testing.T) {
+	ctx := context.Background()
+	embedded := []string{}
+	next := EmbedderFunc(func(ctx context.Context, texts []string) ([][]float64, error) {
+		embedded = append(embedded, texts...)
+		return NewHashingEmbedder(8).Embed(ctx, texts)
+	})
+	remote := fakeRemoteCache{}
+
+	cache := NewCachingEmbedder(next, "hashing@1", 10, time.Hour, remote)
+	first, err := cache.Embed(ctx, []string{"Action", "Comedy", "Action"})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"Action", "Comedy"}, embedded)
+	assert.Equal(t, first[0], first[2])
+
+	// A second instance sharing the remote tier does not call the model
+	embedded = nil
+	other := NewCachingEmbedder(next, "hashing@1", 10, time.Hour, remote)
+	second, err := other.Embed(ctx, []string{"Comedy"})
+	assert.NoError(t, err)
+	assert.Empty(t, embedded)
+	assert.Equal(t, first[1], second[0])
+
+	_, err = cache.Embed(ctx, []string{"Comedy"})
+	assert.NoError(t, err)
+	assert.Equal(t, CacheStats{LocalHits: 1, Misses: 3, Entries: 2}, cache.Stats())
+	assert.Equal(t, int64(1), other.Stats().RemoteHits)
+
+	// Another model version never shares entries
+	embedded = nil
+	_, err = NewCachingEmbedder(next, "hashing@2", 10, time.Hour, remote).Embed(ctx, []string{"Comedy"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Comedy"}, embedded)
+}
+
+func TestLRUCacheEvictsAndExpires(t *testing.T) {
+	cache := NewLRUCache(2, time.Hour)
+	cache.Put("a", []float64{1})
+	cache.Put("b", []float64{2})
+	cache.Get("a")
+	cache.Put("c", []float64{3})
+
+	_, ok := cache.Get("b")
+	assert.False(t, ok)
+	_, ok = cache.Get("a")
+	assert.True(t, ok)
+
+	expiring := NewLRUCache(2, time.Nanosecond)
+	expiring.Put("a", []float64{1})
+	time.Sleep(time.Millisecond)
+	_, ok = expiring.Get("a")
+	assert.False(t, ok)
+}
@@ -1,62 +1,117 @@
 package services
 
 import (
+	"Ad-Recommendations/config"
+	"Ad-Recommendations/vector"
 	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
-	"io/ioutil"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
 )
 
-// Flask AI Server URL
-const AI_SERVICE_URL = "http://localhost:5001"
+// Embedder turns texts into embedding vectors, one per text
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float64, error)
+}
 
-// Handle API Calls to AI Service
-func callAIService(endpoint string, texts []string) ([][]float64, error) {
-	payload, err := json.Marshal(map[string]interface{}{"texts": texts})
-	if err != nil {
-		log.Printf("❌ Error: Failed to marshal request for %s: %v", endpoint, err)
-		return nil, err
+// EmbedderFunc adapts a plain function to the Embedder interface
+type EmbedderFunc func(ctx context.Context, texts []string) ([][]float64, error)
+
+// Embed calls f(ctx, texts)
+func (f EmbedderFunc) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	return f(ctx, texts)
+}
+
+// NewEmbedder builds the embedding provider selected in the configuration.
+// Remote providers are wrapped in a ResilientEmbedder; deadlines come from the
+// per-call context, so the HTTP client itself has no timeout.
+func NewEmbedder(cfg config.EmbeddingConfig) (Embedder, error) {
+	client := &http.Client{}
+
+	var remote Embedder
+	switch cfg.Provider {
+	case config.EmbeddingProviderFlask:
+		remote = &FlaskEmbedder{URL: cfg.Endpoint, Dimension: cfg.Dimension, Client: client}
+	case config.EmbeddingProviderOpenAI:
+		remote = &OpenAIEmbedder{URL: cfg.Endpoint, APIKey: cfg.APIKey, Model: cfg.Model, Dimension: cfg.Dimension, Client: client}
+	case config.EmbeddingProviderHashing:
+		return NewHashingEmbedder(cfg.Dimension), nil
+	default:
+		return nil, fmt.Errorf("unknown embedding provider %q", cfg.Provider)
 	}
 
-	resp, err := http.Post(AI_SERVICE_URL+endpoint, "application/json", bytes.NewBuffer(payload))
-	if err != nil {
-		log.Printf("🚨 AI Service Unavailable (%s): %v", endpoint, err)
+	return &ResilientEmbedder{
+		Next:         remote,
+		Spec:         vector.Spec{Model: cfg.Model, Dimension: cfg.Dimension},
+		Timeout:      cfg.Timeout,
+		MaxRetries:   cfg.MaxRetries,
+		RetryBackoff: cfg.RetryBackoff,
+		BatchSize:    cfg.BatchSize,
+		MaxParallel:  cfg.MaxParallel,
+		Breaker:      NewCircuitBreaker(cfg.Provider, cfg.BreakerThreshold, cfg.BreakerCooldown),
+	}, nil
+}
+
+// FlaskEmbedder calls the Python AI service, which accepts {"texts": [...]}
+// and returns a JSON array of vectors
+type FlaskEmbedder struct {
+	URL       string // Full endpoint, e.g. http://localhost:5001/generate-bert
+	Dimension int    // Expected vector size, 0 to accept any
+	Client    *http.Client
+}
+
+// Embed sends the texts to the AI service
+func (e *FlaskEmbedder) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	var embeddings [][]float64
+	if err := postJSON(ctx, e.Client, e.URL, nil, map[string]interface{}{"texts": texts}, &embeddings); err != nil {
+		log.Printf("🚨 AI Service Unavailable (%s): %v", e.URL, err)
+		return nil, err
+	}
+	if err := (vector.Spec{Model: e.URL, Dimension: e.Dimension}).CheckBatch(embeddings, len(texts)); err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	// Handle HTTP errors (non-200 responses)
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("⚠️ AI Service Error (%s): HTTP %d", endpoint, resp.StatusCode)
-		return nil, errors.New("AI service returned an error")
+	log.Printf("✅ Successfully generated embeddings via %s", e.URL)
+	return embeddings, nil
+}
+
+// postJSON posts a JSON payload and decodes the JSON response into out
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, payload, out interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	// Read response body
-	body, err := ioutil.ReadAll(resp.Body)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
-		log.Printf("❌ Error: Failed to read response from %s: %v", endpoint, err)
-		return nil, err
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
 	}
 
-	// Parse embeddings
-	var embeddings [][]float64
-	if err := json.Unmarshal(body, &embeddings); err != nil {
-		log.Printf("❌ Error: Failed to unmarshal response from %s: %v", endpoint, err)
-		return nil, err
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
 	}
+	defer resp.Body.Close()
 
-	log.Printf("✅ Successfully generated embeddings via %s", endpoint)
-	return embeddings, nil
-}
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
 
-// Generate TF-IDF Embeddings
-func GenerateTFIDFEmbeddings(texts []string) ([][]float64, error) {
-	return callAIService("/generate-tfidf", texts)
-}
+	// Handle HTTP errors (non-200 responses)
+	if resp.StatusCode != http.StatusOK {
+		return &StatusError{StatusCode: resp.StatusCode, Body: string(bytes.TrimSpace(respBody))}
+	}
 
-// Generate BERT Embeddings
-func GenerateBERTEmbeddings(texts []string) ([][]float64, error) {
-	return callAIService("/generate-bert", texts)
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
 }
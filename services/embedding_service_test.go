@@ -0,0 +1,69 @@
+package services
+
+import (
+	"Ad-Recommendations/config"
+	"Ad-Recommendations/vector"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashingEmbedderIsDeterministic(t *testing.T) {
+	embedder := NewHashingEmbedder(64)
+
+	first, err := embedder.Embed(context.Background(), []string{"Fast sports car", "Stand-up comedy tour"})
+	assert.NoError(t, err)
+	second, err := embedder.Embed(context.Background(), []string{"fast SPORTS car"})
+	assert.NoError(t, err)
+
+	assert.Len(t, first[0], 64)
+	assert.Equal(t, first[0], second[0])
+	same, err := vector.Cosine(first[0], second[0])
+	assert.NoError(t, err)
+	assert.InDelta(t, 1.0, same, 1e-9)
+	different, err := vector.Cosine(first[0], first[1])
+	assert.NoError(t, err)
+	assert.Less(t, different, 1.0)
+}
+
+func TestOpenAIEmbedderOrdersVectorsByIndex(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request openAIEmbeddingRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.Equal(t, "small", request.Model)
+		assert.Equal(t, 2, request.Dimensions)
+
+		w.Write([]byte(`{"data": [
+			{"index": 1, "embedding": [0, 1]},
+			{"index": 0, "embedding": [1, 0]}
+		]}`))
+	}))
+	defer server.Close()
+
+	embedder, err := NewEmbedder(config.EmbeddingConfig{
+		Provider: config.EmbeddingProviderOpenAI, Endpoint: server.URL, APIKey: "secret", Model: "small", Dimension: 2,
+	})
+	assert.NoError(t, err)
+
+	embeddings, err := embedder.Embed(context.Background(), []string{"a", "b"})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]float64{{1, 0}, {0, 1}}, embeddings)
+}
+
+func TestFlaskEmbedderRejectsWrongDimension(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[[1, 0, 0]]`))
+	}))
+	defer server.Close()
+
+	embedder, err := NewEmbedder(config.EmbeddingConfig{Provider: config.EmbeddingProviderFlask, Endpoint: server.URL, Dimension: 768})
+	assert.NoError(t, err)
+
+	_, err = embedder.Embed(context.Background(), []string{"a"})
+	assert.Error(t, err)
+}
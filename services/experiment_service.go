@@ -0,0 +1,190 @@
+package services
+
+import (
+	"Ad-Recommendations/config"
+	"Ad-Recommendations/db"
+	"context"
+	"hash/fnv"
+	"log"
+	"math"
+	"time"
+)
+
+// confidenceZ is the normal quantile of the two-sided 95% intervals in experiment reports
+const confidenceZ = 1.96
+
+// Assignment is the experiment and variant a user takes part in, empty when none
+type Assignment struct {
+	Experiment string `json:"experiment"`
+	Variant    string `json:"variant"`
+}
+
+// ExperimentService assigns users to the variants of A/B experiments and
+// reports how the variants compare. Users are bucketed by hashing their ID
+// with the experiment name, so a user stays in the same variant for as long
+// as the experiment is configured the same way, on every instance.
+type ExperimentService struct {
+	Experiments []config.Experiment
+	Impressions db.ImpressionRepository
+
+	base     *RecommendationService
+	variants map[Assignment]*RecommendationService
+}
+
+// NewExperimentService creates an ExperimentService for the configured
+// experiments. Each variant ranks with a copy of base whose configuration is
+// changed by the variant and whose pipeline is built by newPipeline.
+func NewExperimentService(cfg config.ExperimentConfig, store *db.Store, base *RecommendationService, newPipeline func(config.RecommendationConfig) *Pipeline) *ExperimentService {
+	s := &ExperimentService{
+		Experiments: cfg.Experiments,
+		Impressions: store.Impressions,
+		base:        base,
+		variants:    map[Assignment]*RecommendationService{},
+	}
+	for _, experiment := range cfg.Experiments {
+		for _, variant := range experiment.Variants {
+			service := *base
+			service.Config = variant.Apply(base.Config)
+			service.Pipeline = newPipeline(service.Config)
+			s.variants[Assignment{experiment.Name, variant.Name}] = &service
+		}
+		log.Printf("🧪 Running experiment %s on %.0f%% of users with %d variants", experiment.Name, experiment.Traffic*100, len(experiment.Variants))
+	}
+	return s
+}
+
+// Assign returns the experiment and variant of a user: the first experiment
+// whose traffic share the user falls in, and a variant by the splits
+func (s *ExperimentService) Assign(userID string) Assignment {
+	for _, experiment := range s.Experiments {
+		if hashShare(experiment.Name, "traffic", userID) >= experiment.Traffic {
+			continue
+		}
+
+		total := 0.0
+		for _, variant := range experiment.Variants {
+			total += variant.Split
+		}
+		share := hashShare(experiment.Name, "variant", userID) * total
+		for _, variant := range experiment.Variants {
+			if share < variant.Split {
+				return Assignment{experiment.Name, variant.Name}
+			}
+			share -= variant.Split
+		}
+		// Rounding left the share past the last split
+		return Assignment{experiment.Name, experiment.Variants[len(experiment.Variants)-1].Name}
+	}
+	return Assignment{}
+}
+
+// Recommender returns the recommendation service of the user's variant, the
+// base service when the user is in no experiment, and the assignment
+func (s *ExperimentService) Recommender(userID string) (*RecommendationService, Assignment) {
+	assignment := s.Assign(userID)
+	if service, ok := s.variants[assignment]; ok {
+		return service, assignment
+	}
+	return s.base, assignment
+}
+
+// VariantReport holds the click-through rate of one variant
+type VariantReport struct {
+	Variant     string      `json:"variant"`
+	Impressions int         `json:"impressions"`
+	Clicks      int         `json:"clicks"`
+	CTR         float64     `json:"ctr"`
+	CTRLow      float64     `json:"ctr_low"`  // Lower bound of the 95% Wilson interval
+	CTRHigh     float64     `json:"ctr_high"` // Upper bound of the 95% Wilson interval
+	VsControl   *Comparison `json:"vs_control,omitempty"`
+}
+
+// Comparison compares the click-through rate of a variant to the control
+type Comparison struct {
+	Difference   float64 `json:"difference"` // CTR of the variant minus CTR of the control
+	Low          float64 `json:"low"`        // Lower bound of the 95% interval of the difference
+	High         float64 `json:"high"`       // Upper bound of the 95% interval of the difference
+	RelativeLift float64 `json:"relative_lift"`
+	PValue       float64 `json:"p_value"` // Two-sided z-test of equal rates
+}
+
+// ExperimentReport compares the variants of one experiment; the first variant is the control
+type ExperimentReport struct {
+	Experiment string          `json:"experiment"`
+	Since      time.Time       `json:"since"`
+	Variants   []VariantReport `json:"variants"`
+}
+
+// Report counts the impressions served since a time and their attributed
+// clicks per variant of every configured experiment, and compares each
+// variant to the control
+func (s *ExperimentService) Report(ctx context.Context, since time.Time) ([]ExperimentReport, error) {
+	variantCounts, err := s.Impressions.CountVariantsSince(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	counts := map[Assignment]*ctrCounts{}
+	for _, count := range variantCounts {
+		counts[Assignment{count.Experiment, count.Variant}] = &ctrCounts{impressions: count.Impressions, clicks: count.Clicks}
+	}
+
+	reports := make([]ExperimentReport, 0, len(s.Experiments))
+	for _, experiment := range s.Experiments {
+		report := ExperimentReport{Experiment: experiment.Name, Since: since, Variants: []VariantReport{}}
+		for i, variant := range experiment.Variants {
+			clicks, shown := countsOf(counts[Assignment{experiment.Name, variant.Name}])
+			variantReport := VariantReport{Variant: variant.Name, Impressions: shown, Clicks: clicks}
+			variantReport.CTR, variantReport.CTRLow, variantReport.CTRHigh = wilsonInterval(clicks, shown)
+			if i > 0 {
+				control := report.Variants[0]
+				variantReport.VsControl = compareRates(control.Clicks, control.Impressions, clicks, shown)
+			}
+			report.Variants = append(report.Variants, variantReport)
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// wilsonInterval returns a rate and its 95% Wilson score interval, all 0 without trials
+func wilsonInterval(successes, trials int) (float64, float64, float64) {
+	if trials == 0 {
+		return 0, 0, 0
+	}
+	n := float64(trials)
+	rate := float64(successes) / n
+	z2 := confidenceZ * confidenceZ
+	center := (rate + z2/(2*n)) / (1 + z2/n)
+	margin := confidenceZ / (1 + z2/n) * math.Sqrt(rate*(1-rate)/n+z2/(4*n*n))
+	return rate, math.Max(0, center-margin), math.Min(1, center+margin)
+}
+
+// compareRates compares a variant's rate to the control's with a normal
+// approximation; it returns nil when either has no trials
+func compareRates(controlSuccesses, controlTrials, successes, trials int) *Comparison {
+	if controlTrials == 0 || trials == 0 {
+		return nil
+	}
+	n0, n1 := float64(controlTrials), float64(trials)
+	p0, p1 := float64(controlSuccesses)/n0, float64(successes)/n1
+
+	comparison := &Comparison{Difference: p1 - p0, PValue: 1}
+	margin := confidenceZ * math.Sqrt(p0*(1-p0)/n0+p1*(1-p1)/n1)
+	comparison.Low, comparison.High = comparison.Difference-margin, comparison.Difference+margin
+	if p0 > 0 {
+		comparison.RelativeLift = comparison.Difference / p0
+	}
+
+	pooled := float64(controlSuccesses+successes) / (n0 + n1)
+	if se := math.Sqrt(pooled * (1 - pooled) * (1/n0 + 1/n1)); se > 0 {
+		comparison.PValue = math.Erfc(math.Abs(comparison.Difference/se) / math.Sqrt2)
+	}
+	return comparison
+}
+
+// hashShare maps an experiment, a purpose and a user ID to a stable share in [0, 1)
+func hashShare(experiment, purpose, userID string) float64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(experiment + "/" + purpose + "/" + userID))
+	return float64(hash.Sum64()>>11) / (1 << 53)
+}
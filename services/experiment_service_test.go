@@ -0,0 +1,95 @@
+package services
+
+import (
+	"Ad-Recommendations/config"
+	"Ad-Recommendations/db"
+	"Ad-Recommendations/models"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExperimentAssignment(t *testing.T) {
+	store := db.NewMemoryStore()
+	base := &RecommendationService{Config: config.RecommendationConfig{Weights: map[string]float64{"category": 0.4, "content": 0.6}, Limit: 5, MaxLimit: 20}}
+	experiments := NewExperimentService(config.ExperimentConfig{Experiments: []config.Experiment{{
+		Name:    "weights",
+		Traffic: 0.5,
+		Variants: []config.ExperimentVariant{
+			{Name: "control", Split: 1},
+			{Name: "content-heavy", Split: 3, Weights: map[string]float64{"category": 0.3, "content": 0.7}},
+		},
+	}}}, store, base, func(cfg config.RecommendationConfig) *Pipeline { return &Pipeline{} })
+
+	counts := map[Assignment]int{}
+	for i := 0; i < 4000; i++ {
+		userID := fmt.Sprintf("user-%d", i)
+		assignment := experiments.Assign(userID)
+		assert.Equal(t, assignment, experiments.Assign(userID), "assignment must be deterministic")
+		counts[assignment]++
+	}
+	assert.InDelta(t, 2000, counts[Assignment{}], 150)
+	assert.InDelta(t, 500, counts[Assignment{"weights", "control"}], 100)
+	assert.InDelta(t, 1500, counts[Assignment{"weights", "content-heavy"}], 150)
+
+	for i := 0; ; i++ {
+		recommender, assignment := experiments.Recommender(fmt.Sprintf("user-%d", i))
+		if assignment.Variant != "content-heavy" {
+			continue
+		}
+		assert.Equal(t, 0.7, recommender.Config.Weights["content"])
+		assert.Equal(t, 0.6, base.Config.Weights["content"], "variants must not change the base configuration")
+		break
+	}
+}
+
+func TestExperimentReport(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewMemoryStore()
+	experiments := NewExperimentService(config.ExperimentConfig{Experiments: []config.Experiment{{
+		Name:     "weights",
+		Traffic:  1,
+		Variants: []config.ExperimentVariant{{Name: "control", Split: 1}, {Name: "treatment", Split: 1}},
+	}}}, store, &RecommendationService{}, func(cfg config.RecommendationConfig) *Pipeline { return &Pipeline{} })
+
+	now := time.Now().UTC()
+	impressions := []models.Impression{}
+	serve := func(variant string, shown, clicked int) {
+		for i := 0; i < shown; i++ {
+			impression := models.Impression{
+				RecommendationID: fmt.Sprintf("%s-%d", variant, i), AdID: "car", Experiment: "weights", Variant: variant, Timestamp: now,
+			}
+			if i < clicked {
+				impression.ClickedAt = now
+			}
+			impressions = append(impressions, impression)
+		}
+	}
+	serve("control", 1000, 100)
+	serve("treatment", 1000, 150)
+	// Impressions of no experiment or before since are left out
+	impressions = append(impressions,
+		models.Impression{RecommendationID: "none", AdID: "car", Timestamp: now},
+		models.Impression{RecommendationID: "old", AdID: "car", Experiment: "weights", Variant: "control", Timestamp: now.Add(-2 * time.Hour)})
+	_, err := store.Impressions.LogImpressions(ctx, impressions)
+	assert.NoError(t, err)
+
+	reports, err := experiments.Report(ctx, now.Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, reports, 1)
+	control, treatment := reports[0].Variants[0], reports[0].Variants[1]
+	assert.Equal(t, 1000, control.Impressions)
+	assert.Equal(t, 0.1, control.CTR)
+	assert.Nil(t, control.VsControl)
+	assert.InDelta(t, 0.083, control.CTRLow, 0.001)
+	assert.InDelta(t, 0.120, control.CTRHigh, 0.001)
+
+	assert.Equal(t, 150, treatment.Clicks)
+	assert.InDelta(t, 0.05, treatment.VsControl.Difference, 1e-9)
+	assert.InDelta(t, 0.5, treatment.VsControl.RelativeLift, 1e-9)
+	assert.Greater(t, treatment.VsControl.Low, 0.0)
+	assert.Less(t, treatment.VsControl.PValue, 0.01)
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"Ad-Recommendations/config"
+	"context"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// minBetaShape keeps sampled Beta shapes positive when a prior has no weight
+const minBetaShape = 1e-3
+
+// NewExplorer returns the explorer of the configured exploration policy, or
+// nil when exploration is off
+func NewExplorer(cfg config.RecommendationConfig, estimator *CTREstimator) Explorer {
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	switch cfg.ExplorationPolicy {
+	case config.ExplorationThompson:
+		return &ThompsonExplorer{Estimator: estimator, Rand: random}
+	case config.ExplorationEpsilonGreedy:
+		return &EpsilonGreedyExplorer{Epsilon: cfg.ExplorationEpsilon, Rand: random}
+	}
+	return nil
+}
+
+// ThompsonExplorer fills exploration slots by Thompson sampling: it draws a
+// click-through rate from the posterior of every ranked ad and every pool ad,
+// and from the bottom of the list up, gives a slot to the pool ad with the
+// best draw when that draw beats the draw of the ad in the slot. Ads with few
+// impressions have wide posteriors and so win slots often until their rate is
+// known.
+type ThompsonExplorer struct {
+	Estimator *CTREstimator
+	Rand      *rand.Rand
+
+	mu sync.Mutex // Guards Rand
+}
+
+// Name identifies the explorer in logs
+func (e *ThompsonExplorer) Name() string { return config.ExplorationThompson }
+
+// Explore swaps pool ads into the bottom slots of ranked where their sampled rate is higher
+func (e *ThompsonExplorer) Explore(ctx context.Context, req *RankingRequest, ranked, pool []Candidate) []Candidate {
+	movieCategory := req.Profile.FavouriteCategory()
+	sample := func(candidate Candidate) float64 {
+		posterior := e.Estimator.Posterior(candidate.Ad.AdID, candidate.Ad.Category, movieCategory)
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		return sampleBeta(e.Rand, posterior.Alpha, posterior.Beta)
+	}
+
+	draws := make([]float64, len(pool))
+	order := make([]int, len(pool))
+	for i, candidate := range pool {
+		draws[i], order[i] = sample(candidate), i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return draws[order[i]] > draws[order[j]] })
+
+	next := 0
+	for slot := len(ranked) - 1; slot >= 0 && slot >= len(ranked)-req.Options.Exploration && next < len(order); slot-- {
+		challenger := order[next]
+		if draws[challenger] <= sample(ranked[slot]) {
+			continue
+		}
+		ranked[slot] = explore(ranked[slot], pool[challenger], slot, e.Name())
+		next++
+	}
+	return ranked
+}
+
+// EpsilonGreedyExplorer gives each exploration slot, from the bottom of the
+// list up, with probability Epsilon to a pool ad picked uniformly at random
+type EpsilonGreedyExplorer struct {
+	Epsilon float64
+	Rand    *rand.Rand
+
+	mu sync.Mutex // Guards Rand
+}
+
+// Name identifies the explorer in logs
+func (e *EpsilonGreedyExplorer) Name() string { return config.ExplorationEpsilonGreedy }
+
+// Explore swaps random pool ads into the bottom slots of ranked
+func (e *EpsilonGreedyExplorer) Explore(ctx context.Context, req *RankingRequest, ranked, pool []Candidate) []Candidate {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	remaining := append([]Candidate(nil), pool...)
+	for slot := len(ranked) - 1; slot >= 0 && slot >= len(ranked)-req.Options.Exploration && len(remaining) > 0; slot-- {
+		if e.Rand.Float64() >= e.Epsilon {
+			continue
+		}
+		pick := e.Rand.Intn(len(remaining))
+		ranked[slot] = explore(ranked[slot], remaining[pick], slot, e.Name())
+		remaining = append(remaining[:pick], remaining[pick+1:]...)
+	}
+	return ranked
+}
+
+// explore returns the pool candidate taking a slot, marked as explored
+func explore(replaced, candidate Candidate, slot int, policy string) Candidate {
+	log.Printf("🎲 Exploration slot #%d: ad %s replaces %s (%s)", slot+1, candidate.Ad.AdID, replaced.Ad.AdID, policy)
+	candidate.Explored = true
+	return candidate
+}
+
+// sampleBeta draws from Beta(alpha, beta) as the ratio of two Gamma draws
+func sampleBeta(random *rand.Rand, alpha, beta float64) float64 {
+	x := sampleGamma(random, math.Max(alpha, minBetaShape))
+	y := sampleGamma(random, math.Max(beta, minBetaShape))
+	if x+y == 0 {
+		return 0
+	}
+	return x / (x + y)
+}
+
+// sampleGamma draws from Gamma(shape, 1) with the Marsaglia-Tsang method;
+// shapes below 1 are boosted by a uniform power
+func sampleGamma(random *rand.Rand, shape float64) float64 {
+	if shape < 1 {
+		return sampleGamma(random, shape+1) * math.Pow(random.Float64(), 1/shape)
+	}
+
+	d := shape - 1.0/3
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := random.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := random.Float64()
+		if math.Log(u) < 0.5*x*x+d-d*v+d*math.Log(v) {
+			return d * v
+		}
+	}
+}
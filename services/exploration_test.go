@@ -0,0 +1,90 @@
+package services
+
+import (
+	"Ad-Recommendations/db"
+	"Ad-Recommendations/models"
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// explorationPipeline ranks "old" and "known" above a "new" ad that is cut by the limit of 2
+func explorationPipeline(explorer Explorer) (*Pipeline, *RankingRequest) {
+	pipeline := &Pipeline{
+		Generators: []CandidateGenerator{staticCandidates{{AdID: "old", Category: "Cars"}, {AdID: "known", Category: "Cars"}, {AdID: "new", Category: "Cars"}}},
+		Scorers:    []Scorer{&staticScorer{name: "content", scores: map[string]float64{"old": 0.9, "known": 0.8, "new": 0.1}}},
+		Combiner:   WeightedSum{},
+		Filters:    []PostFilter{MinScoreFilter{}},
+		Explorer:   explorer,
+	}
+	return pipeline, &RankingRequest{Options: RankingOptions{Weights: ScoringWeights{"content": 1}, Limit: 2, Exploration: 1}}
+}
+
+func TestThompsonExplorerServesNewAds(t *testing.T) {
+	estimator := NewCTREstimator(db.NewMemoryStore().CTRCounts, 10)
+	for _, adID := range []string{"old", "known"} {
+		impression := models.Impression{AdID: adID, Category: "Cars"}
+		for i := 0; i < 1000; i++ {
+			estimator.RecordImpression(impression)
+		}
+		for i := 0; i < 100; i++ {
+			estimator.RecordClick(impression)
+		}
+	}
+	pipeline, req := explorationPipeline(&ThompsonExplorer{Estimator: estimator, Rand: rand.New(rand.NewSource(1))})
+
+	explored := 0
+	for i := 0; i < 200; i++ {
+		ranked := pipeline.Rank(context.Background(), req)
+		assert.Len(t, ranked, 2)
+		assert.Equal(t, "old", ranked[0].Ad.AdID, "only the bottom slot is in the budget")
+		if ranked[1].Explored {
+			assert.Equal(t, "new", ranked[1].Ad.AdID)
+			explored++
+		}
+	}
+	// The new ad's posterior is wide around the same rate, so it wins the slot some of the time
+	assert.Greater(t, explored, 10)
+	assert.Less(t, explored, 190)
+
+	req.Options.Exploration = 0
+	assert.False(t, pipeline.Rank(context.Background(), req)[1].Explored)
+}
+
+func TestEpsilonGreedyExplorer(t *testing.T) {
+	pipeline, req := explorationPipeline(&EpsilonGreedyExplorer{Epsilon: 1, Rand: rand.New(rand.NewSource(1))})
+	ranked := pipeline.Rank(context.Background(), req)
+	assert.Equal(t, []string{"old", "new"}, []string{ranked[0].Ad.AdID, ranked[1].Ad.AdID})
+	assert.True(t, ranked[1].Explored)
+	assert.False(t, ranked[0].Explored)
+
+	pipeline, req = explorationPipeline(&EpsilonGreedyExplorer{Epsilon: 0, Rand: rand.New(rand.NewSource(1))})
+	ranked = pipeline.Rank(context.Background(), req)
+	assert.Equal(t, "known", ranked[1].Ad.AdID)
+	assert.False(t, ranked[1].Explored)
+}
+
+func TestExplorationKeepsFilters(t *testing.T) {
+	pipeline := &Pipeline{
+		Generators: []CandidateGenerator{staticCandidates{
+			{AdID: "car", Category: "Cars"}, {AdID: "bike", Category: "Bikes"},
+			{AdID: "van", Category: "Cars"}, {AdID: "weak", Category: "Boats"}, {AdID: "boat", Category: "Boats"},
+		}},
+		Scorers: []Scorer{&staticScorer{name: "content", scores: map[string]float64{
+			"car": 0.9, "bike": 0.8, "van": 0.7, "boat": 0.6, "weak": 0.05,
+		}}},
+		Combiner: WeightedSum{},
+		Filters:  []PostFilter{MinScoreFilter{}, &DiversityFilter{Lambda: 1, MaxPerCategory: 1}},
+		Explorer: &EpsilonGreedyExplorer{Epsilon: 1, Rand: rand.New(rand.NewSource(1))},
+	}
+	req := &RankingRequest{Options: RankingOptions{Weights: ScoringWeights{"content": 1}, Limit: 2, MinScore: 0.1, Exploration: 1}}
+
+	// van breaks the category cap and weak the minimum score, so boat is the only ad to explore
+	for i := 0; i < 20; i++ {
+		ranked := pipeline.Rank(context.Background(), req)
+		assert.Equal(t, []string{"car", "boat"}, []string{ranked[0].Ad.AdID, ranked[1].Ad.AdID})
+		assert.True(t, ranked[1].Explored)
+	}
+}
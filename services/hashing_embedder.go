@@ -0,0 +1,52 @@
+package services
+
+import (
+	"Ad-Recommendations/vector"
+	"context"
+	"hash/fnv"
+	"strings"
+	"unicode"
+)
+
+// HashingEmbedder is a deterministic, dependency-free embedder for tests and
+// local runs. Each lowercased word is hashed to a signed bucket of the vector,
+// so texts that share words point in similar directions.
+type HashingEmbedder struct {
+	Dimension int
+}
+
+// NewHashingEmbedder creates a HashingEmbedder with the given vector size
+func NewHashingEmbedder(dimension int) *HashingEmbedder {
+	if dimension <= 0 {
+		dimension = 768
+	}
+	return &HashingEmbedder{Dimension: dimension}
+}
+
+// Embed hashes each text into a unit-length vector
+func (e *HashingEmbedder) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	embeddings := make([][]float64, len(texts))
+	for i, text := range texts {
+		values := make([]float64, e.Dimension)
+		for _, token := range tokenize(text) {
+			hash := fnv.New64a()
+			hash.Write([]byte(token))
+			sum := hash.Sum64()
+
+			sign := 1.0
+			if sum&1 == 1 {
+				sign = -1.0
+			}
+			values[(sum>>1)%uint64(e.Dimension)] += sign
+		}
+		embeddings[i] = vector.Normalize(values)
+	}
+	return embeddings, nil
+}
+
+// tokenize splits text into lowercased words
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
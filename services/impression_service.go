@@ -0,0 +1,184 @@
+package services
+
+import (
+	"Ad-Recommendations/db"
+	"Ad-Recommendations/models"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ErrUnknownAd is returned when impressions are reported for an ad that does not exist
+var ErrUnknownAd = errors.New("unknown ad")
+
+// ImpressionService records served ads, attributes clicks to them and keeps
+// the rolling click-through rates and CTR estimates up to date
+type ImpressionService struct {
+	Impressions db.ImpressionRepository
+	Ads         db.AdRepository
+	CTR         *CTRTracker
+	Estimator   *CTREstimator          // Optional
+	Profiles    *RecommendationService // Optional; tags impressions with the user's favourite movie category
+	Experiments *ExperimentService     // Optional; tags impressions with the user's experiment variant
+}
+
+// NewImpressionService creates an ImpressionService backed by the given store
+func NewImpressionService(store *db.Store, ctr *CTRTracker) *ImpressionService {
+	return &ImpressionService{Impressions: store.Impressions, Ads: store.Ads, CTR: ctr}
+}
+
+// LogServed records the ads of a recommendation list in the order served,
+// marking those in exploration slots, and returns the new recommendation ID
+// clicks refer to
+func (s *ImpressionService) LogServed(ctx context.Context, userID, segment string, recommendations []Recommendation) (string, error) {
+	ads := make([]models.Ad, len(recommendations))
+	explored := make([]bool, len(recommendations))
+	for i, recommendation := range recommendations {
+		ads[i], explored[i] = recommendation.Ad, recommendation.Explanation.Explored
+	}
+
+	recommendationID := newRecommendationID()
+	if err := s.record(ctx, userID, recommendationID, segment, ads, explored); err != nil {
+		return "", err
+	}
+	return recommendationID, nil
+}
+
+// LogImpressions records ads shown to a user, in the order shown, under a
+// recommendation ID. An empty recommendation ID gets a new one. It returns the
+// recommendation ID, or ErrUnknownAd when an ad does not exist.
+func (s *ImpressionService) LogImpressions(ctx context.Context, userID, recommendationID, segment string, adIDs []string) (string, error) {
+	if userID == "" {
+		return "", fmt.Errorf("user_id cannot be empty")
+	}
+	if len(adIDs) == 0 {
+		return "", fmt.Errorf("ad_ids cannot be empty")
+	}
+
+	ads := make([]models.Ad, 0, len(adIDs))
+	for _, adID := range adIDs {
+		ad, err := s.Ads.GetAd(ctx, adID)
+		if errors.Is(err, db.ErrNotFound) {
+			return "", fmt.Errorf("%w: %s", ErrUnknownAd, adID)
+		}
+		if err != nil {
+			return "", err
+		}
+		ads = append(ads, *ad)
+	}
+
+	if recommendationID == "" {
+		recommendationID = newRecommendationID()
+	}
+	if err := s.record(ctx, userID, recommendationID, segment, ads, nil); err != nil {
+		return "", err
+	}
+	return recommendationID, nil
+}
+
+// record stores one impression per ad and counts them in the click-through rates.
+// explored marks the ads served in exploration slots and may be nil.
+func (s *ImpressionService) record(ctx context.Context, userID, recommendationID, segment string, ads []models.Ad, explored []bool) error {
+	movieCategory := ""
+	if s.Profiles != nil {
+		var err error
+		if movieCategory, err = s.Profiles.FavouriteMovieCategory(ctx, userID); err != nil {
+			log.Printf("⚠️ Logging impressions without movie category: %v", err)
+		}
+	}
+
+	assignment := Assignment{}
+	if s.Experiments != nil {
+		assignment = s.Experiments.Assign(userID)
+	}
+
+	now := time.Now().UTC()
+	impressions := make([]models.Impression, len(ads))
+	for i, ad := range ads {
+		impressions[i] = models.Impression{
+			RecommendationID: recommendationID,
+			UserID:           userID,
+			AdID:             ad.AdID,
+			Category:         ad.Category,
+			Segment:          segment,
+			MovieCategory:    movieCategory,
+			Position:         i + 1,
+			Explored:         i < len(explored) && explored[i],
+			Experiment:       assignment.Experiment,
+			Variant:          assignment.Variant,
+			Timestamp:        now,
+		}
+	}
+
+	// Impressions reported again are already counted
+	stored, err := s.Impressions.LogImpressions(ctx, impressions)
+	if err != nil {
+		log.Printf("❌ Failed to log impressions of recommendation %s: %v", recommendationID, err)
+		return err
+	}
+	for _, impression := range stored {
+		s.CTR.RecordImpression(impression)
+		if s.Estimator != nil {
+			s.Estimator.RecordImpression(impression)
+		}
+	}
+
+	log.Printf("👀 Logged %d impressions for %s in recommendation %s (%d already stored)", len(stored), userID, recommendationID, len(impressions)-len(stored))
+	return nil
+}
+
+// RecordClick attributes a click to the impression of its ad in its
+// recommendation. Only the first click on an impression counts towards the
+// click-through rates; clicks by another user than the one served are ignored.
+func (s *ImpressionService) RecordClick(ctx context.Context, click models.AdClick) error {
+	impression, err := s.Impressions.GetImpression(ctx, click.RecommendationID, click.AdID)
+	if err != nil {
+		return err
+	}
+	if impression.UserID != click.UserID {
+		return fmt.Errorf("recommendation %s was served to another user", click.RecommendationID)
+	}
+
+	first, err := s.Impressions.MarkClicked(ctx, click.RecommendationID, click.AdID, click.Timestamp)
+	if err != nil {
+		return err
+	}
+	if first {
+		s.CTR.RecordClick(*impression)
+		if s.Estimator != nil {
+			s.Estimator.RecordClick(*impression)
+		}
+	}
+	return nil
+}
+
+// LoadCTR counts the stored impressions and clicks of the rolling window, so
+// click-through rates survive a restart. It returns the number of impressions read.
+func (s *ImpressionService) LoadCTR(ctx context.Context) (int, error) {
+	impressions, err := s.Impressions.ImpressionsSince(ctx, s.CTR.Now().Add(-s.CTR.Window))
+	if err != nil {
+		return 0, err
+	}
+	for _, impression := range impressions {
+		s.CTR.RecordImpression(impression)
+		if impression.Clicked() {
+			s.CTR.RecordClick(impression)
+		}
+	}
+
+	log.Printf("✅ Loaded %d impressions into the click-through rates", len(impressions))
+	return len(impressions), nil
+}
+
+// newRecommendationID returns a random ID for a served recommendation list
+func newRecommendationID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(id)
+}
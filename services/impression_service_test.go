@@ -0,0 +1,104 @@
+package services
+
+import (
+	"Ad-Recommendations/db"
+	"Ad-Recommendations/models"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClicksJoinImpressionsForCTR(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewMemoryStore()
+	assert.NoError(t, (&db.Fixture{Ads: []models.Ad{
+		{AdID: "car", Category: "Cars"},
+		{AdID: "van", Category: "Cars"},
+	}}).Apply(ctx, store))
+	impressions := NewImpressionService(store, NewCTRTracker(time.Hour, 6))
+	clicks := NewAdClickService(store.Clicks)
+	clicks.Impressions = impressions
+
+	recommendationID, err := impressions.LogServed(ctx, "u1", "premium", []Recommendation{
+		{Ad: models.Ad{AdID: "car", Category: "Cars"}}, {Ad: models.Ad{AdID: "van", Category: "Cars"}},
+	})
+	assert.NoError(t, err)
+	_, err = impressions.LogImpressions(ctx, "u2", "", "", []string{"car"})
+	assert.NoError(t, err)
+	_, err = impressions.LogImpressions(ctx, "u2", "", "", []string{"bike"})
+	assert.ErrorIs(t, err, ErrUnknownAd)
+
+	// Only the first click of the served user counts
+	assert.NoError(t, clicks.LogAdClick(ctx, "u1", "car", recommendationID))
+	assert.NoError(t, clicks.LogAdClick(ctx, "u1", "car", recommendationID))
+	assert.NoError(t, clicks.LogAdClick(ctx, "u2", "van", recommendationID))
+
+	assert.Equal(t, CTR{Impressions: 2, Clicks: 1, Rate: 0.5}, impressions.CTR.AdCTR("car"))
+	assert.Equal(t, CTR{Impressions: 3, Clicks: 1, Rate: 1.0 / 3}, impressions.CTR.CategoryCTR("Cars"))
+	assert.Equal(t, CTR{Impressions: 2, Clicks: 1, Rate: 0.5}, impressions.CTR.SegmentCTR("premium"))
+
+	impression, err := store.Impressions.GetImpression(ctx, recommendationID, "van")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, impression.Position)
+	assert.False(t, impression.Clicked())
+
+	// A restarted service restores the rates from storage
+	restored := NewImpressionService(store, NewCTRTracker(time.Hour, 6))
+	loaded, err := restored.LoadCTR(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, loaded)
+	assert.Equal(t, impressions.CTR.Report(), restored.CTR.Report())
+}
+
+func TestReportedImpressionsCountOnce(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewMemoryStore()
+	assert.NoError(t, (&db.Fixture{Ads: []models.Ad{{AdID: "car", Category: "Cars"}, {AdID: "van", Category: "Cars"}}}).Apply(ctx, store))
+	impressions := NewImpressionService(store, NewCTRTracker(time.Hour, 6))
+	impressions.Estimator = NewCTREstimator(store.CTRCounts, 100)
+
+	recommendationID, err := impressions.LogImpressions(ctx, "u1", "", "", []string{"car"})
+	assert.NoError(t, err)
+	again, err := impressions.LogImpressions(ctx, "u1", recommendationID, "", []string{"car", "van"})
+	assert.NoError(t, err)
+	assert.Equal(t, recommendationID, again)
+
+	// Only van is new in the second report
+	assert.Equal(t, CTR{Impressions: 1}, impressions.CTR.AdCTR("car"))
+	assert.Equal(t, CTR{Impressions: 2}, impressions.CTR.CategoryCTR("Cars"))
+	saved, err := impressions.Estimator.Snapshot(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, saved, "car, van and their pair")
+	counts, err := store.CTRCounts.ListCTRCounts(ctx)
+	assert.NoError(t, err)
+	for _, count := range counts {
+		if count.Key == "car" {
+			assert.Equal(t, 1, count.Impressions)
+		}
+	}
+
+	// The rates match what a restart rebuilds from storage
+	restored := NewImpressionService(store, NewCTRTracker(time.Hour, 6))
+	_, err = restored.LoadCTR(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, impressions.CTR.Report(), restored.CTR.Report())
+}
+
+func TestCTRTrackerRollsOff(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	tracker := NewCTRTracker(time.Hour, 4)
+	tracker.Now = func() time.Time { return now }
+
+	old := models.Impression{AdID: "car", Category: "Cars", Timestamp: now.Add(-40 * time.Minute)}
+	recent := models.Impression{AdID: "car", Category: "Cars", Timestamp: now.Add(-5 * time.Minute)}
+	tracker.RecordImpression(old)
+	tracker.RecordClick(old)
+	tracker.RecordImpression(recent)
+	assert.Equal(t, CTR{Impressions: 2, Clicks: 1, Rate: 0.5}, tracker.AdCTR("car"))
+
+	now = now.Add(15 * time.Minute)
+	assert.Equal(t, CTR{Impressions: 1}, tracker.AdCTR("car"))
+	assert.Equal(t, map[string]CTR{"Cars": {Impressions: 1}}, tracker.Report().Categories)
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"Ad-Recommendations/db/paginate"
 	"context"
 	"log"
 	"time"
@@ -44,20 +45,20 @@ func (s *InteractionService) LogInteraction(userID, interactionType, target stri
 }
 
 func (s *InteractionService) FetchInteractions(userID string) ([]Interaction, error) {
-	output, err := s.DynamoClient.Query(context.TODO(), &dynamodb.QueryInput{
+	page, err := paginate.Query(context.TODO(), s.DynamoClient, &dynamodb.QueryInput{
 		TableName:              &s.InteractionTableName,
 		KeyConditionExpression: aws.String("user_id = :userID"),
 		ExpressionAttributeValues: map[string]types.AttributeValue{
 			":userID": &types.AttributeValueMemberS{Value: userID},
 		},
-	})
+	}, 0)
 	if err != nil {
 		log.Printf("Failed to fetch interactions: %v", err)
 		return nil, err
 	}
 
 	interactions := []Interaction{}
-	for _, item := range output.Items {
+	for _, item := range page.Items {
 		interaction := Interaction{
 			UserID:    item["user_id"].(*types.AttributeValueMemberS).Value,
 			Type:      item["type"].(*types.AttributeValueMemberS).Value,
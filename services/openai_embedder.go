@@ -0,0 +1,67 @@
+package services
+
+import (
+	"Ad-Recommendations/vector"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+)
+
+// OpenAIEmbedder calls an OpenAI-compatible /v1/embeddings API
+type OpenAIEmbedder struct {
+	URL       string // Full endpoint, e.g. https://api.openai.com/v1/embeddings
+	APIKey    string // Sent as a bearer token when set
+	Model     string
+	Dimension int // Requested vector size, 0 for the model default
+	Client    *http.Client
+}
+
+// openAIEmbeddingRequest is the body of a /v1/embeddings request
+type openAIEmbeddingRequest struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions int      `json:"dimensions,omitempty"`
+}
+
+// openAIEmbeddingResponse is the body of a /v1/embeddings response
+type openAIEmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Embed sends the texts to the embeddings API and returns the vectors in input order
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	headers := map[string]string{}
+	if e.APIKey != "" {
+		headers["Authorization"] = "Bearer " + e.APIKey
+	}
+
+	var response openAIEmbeddingResponse
+	request := openAIEmbeddingRequest{Model: e.Model, Input: texts, Dimensions: e.Dimension}
+	if err := postJSON(ctx, e.Client, e.URL, headers, request, &response); err != nil {
+		log.Printf("🚨 Embedding API Unavailable (%s): %v", e.URL, err)
+		return nil, err
+	}
+
+	// The API tags each vector with the index of its input
+	sort.Slice(response.Data, func(i, j int) bool {
+		return response.Data[i].Index < response.Data[j].Index
+	})
+	embeddings := make([][]float64, len(response.Data))
+	for i, item := range response.Data {
+		if item.Index != i {
+			return nil, fmt.Errorf("embedding API returned no vector for input %d", i)
+		}
+		embeddings[i] = item.Embedding
+	}
+	if err := (vector.Spec{Model: e.Model, Dimension: e.Dimension}).CheckBatch(embeddings, len(texts)); err != nil {
+		return nil, err
+	}
+
+	log.Printf("✅ Successfully generated %d embeddings with %s", len(embeddings), e.Model)
+	return embeddings, nil
+}
@@ -2,34 +2,56 @@ package services
 
 import (
 	"Ad-Recommendations/db"
+	"Ad-Recommendations/models"
 	"context"
+	"errors"
 	"log"
 	"time"
-
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
-// LogPlayback logs playback data to DynamoDB
-func LogPlayback(userID, category string) error {
-	timestamp := time.Now().Format(time.RFC3339)
+// PlaybackService records and reads playback events
+type PlaybackService struct {
+	Playback db.PlaybackRepository
+}
+
+// NewPlaybackService creates a PlaybackService backed by the given repository
+func NewPlaybackService(playback db.PlaybackRepository) *PlaybackService {
+	return &PlaybackService{Playback: playback}
+}
 
-	entry := map[string]types.AttributeValue{
-		"user_id":   &types.AttributeValueMemberS{Value: userID},
-		"category":  &types.AttributeValueMemberS{Value: category},
-		"timestamp": &types.AttributeValueMemberS{Value: timestamp},
+// LogPlayback logs playback data to the playback store.
+// A zero timestamp records the event at the current time.
+func (s *PlaybackService) LogPlayback(ctx context.Context, userID, category string, timestamp time.Time) error {
+	if timestamp.IsZero() {
+		timestamp = time.Now()
 	}
 
-	_, err := db.DynamoClient.PutItem(context.TODO(), &dynamodb.PutItemInput{
-		TableName: aws.String(db.PlaybackTableName),
-		Item:      entry,
-	})
-	if err != nil {
+	event := models.PlaybackEvent{
+		UserID:    userID,
+		Category:  category,
+		Timestamp: timestamp.UTC(),
+	}
+
+	if err := s.Playback.LogPlayback(ctx, event); err != nil {
 		log.Printf("Failed to log playback data: %v", err)
 		return err
 	}
 
-	log.Printf("Playback data logged: UserID=%s, Category=%s, Timestamp=%s", userID, category, timestamp)
+	log.Printf("Playback data logged: UserID=%s, Category=%s, Timestamp=%s", userID, category, event.Timestamp.Format(time.RFC3339))
 	return nil
 }
+
+// GetPlaybackHistory returns a user's playback events matching the query, oldest
+// first, and the cursor of the next older page
+func (s *PlaybackService) GetPlaybackHistory(ctx context.Context, userID string, query db.HistoryQuery) ([]models.PlaybackEvent, string, error) {
+	if userID == "" {
+		return nil, "", errors.New("user_id cannot be empty")
+	}
+
+	events, next, err := s.Playback.PlaybackHistory(ctx, userID, query)
+	if err != nil {
+		log.Printf("Failed to query playback history: %v", err)
+		return nil, "", err
+	}
+	return events, next, nil
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"Ad-Recommendations/config"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+)
+
+// ErrInvalidRankingOptions is wrapped by errors about rejected per-request overrides
+var ErrInvalidRankingOptions = errors.New("invalid ranking options")
+
+// ScoringWeights weight each scorer of the ranking pipeline by name
+type ScoringWeights map[string]float64
+
+// RankingOptions control how candidates are scored and how many are returned
+type RankingOptions struct {
+	Weights  ScoringWeights
+	Limit    int     // Maximum number of ads returned
+	MinScore float64 // Ads scoring below this are dropped
+
+	Exploration int // Slots that may be given to exploration, at most Limit
+}
+
+// RankingOverrides are per-request changes to the configured ranking options;
+// nil fields keep the configured value
+type RankingOverrides struct {
+	Limit    *int           `json:"limit,omitempty"`
+	Weights  ScoringWeights `json:"weights,omitempty"` // Merged over the configured weights
+	MinScore *float64       `json:"min_score,omitempty"`
+
+	Exploration *int `json:"exploration,omitempty"` // Exploration budget; 0 turns exploration off
+}
+
+// Bounds applied to per-request overrides
+const (
+	maxSignalWeight = 1.0
+	minScoreBound   = 1.0
+)
+
+// DefaultRankingOptions returns the ranking options set in the configuration
+func DefaultRankingOptions(cfg config.RecommendationConfig) RankingOptions {
+	return RankingOptions{
+		Weights:  maps.Clone(cfg.Weights),
+		Limit:    cfg.Limit,
+		MinScore: cfg.MinScore,
+
+		Exploration: min(cfg.ExplorationBudget, cfg.Limit),
+	}
+}
+
+// ResolveRankingOptions applies per-request overrides to the configured options.
+// Out-of-range values are clamped; values that cannot be clamped meaningfully
+// (unknown scorers, negative or non-finite weights, all-zero weights, a limit
+// below 1, a negative exploration budget) are rejected.
+func ResolveRankingOptions(cfg config.RecommendationConfig, overrides RankingOverrides) (RankingOptions, error) {
+	opts := DefaultRankingOptions(cfg)
+
+	if overrides.Limit != nil {
+		if *overrides.Limit < 1 {
+			return opts, fmt.Errorf("%w: limit must be at least 1", ErrInvalidRankingOptions)
+		}
+		opts.Limit = *overrides.Limit
+		if cfg.MaxLimit > 0 && opts.Limit > cfg.MaxLimit {
+			opts.Limit = cfg.MaxLimit
+		}
+	}
+
+	if overrides.Weights != nil {
+		weights := ScoringWeights{}
+		maps.Copy(weights, opts.Weights)
+		for name, weight := range overrides.Weights {
+			if !slices.Contains(config.Scorers, name) {
+				return opts, fmt.Errorf("%w: unknown scorer %q", ErrInvalidRankingOptions, name)
+			}
+			if math.IsNaN(weight) || math.IsInf(weight, 0) || weight < 0 {
+				return opts, fmt.Errorf("%w: %s weight must be a non-negative number", ErrInvalidRankingOptions, name)
+			}
+			weights[name] = math.Min(weight, maxSignalWeight)
+		}
+		if !weights.anyPositive() {
+			return opts, fmt.Errorf("%w: at least one weight must be positive", ErrInvalidRankingOptions)
+		}
+		opts.Weights = weights
+	}
+
+	if overrides.MinScore != nil {
+		if math.IsNaN(*overrides.MinScore) {
+			return opts, fmt.Errorf("%w: min_score must be a number", ErrInvalidRankingOptions)
+		}
+		opts.MinScore = math.Max(-minScoreBound, math.Min(*overrides.MinScore, minScoreBound))
+	}
+
+	if overrides.Exploration != nil {
+		if *overrides.Exploration < 0 {
+			return opts, fmt.Errorf("%w: exploration must not be negative", ErrInvalidRankingOptions)
+		}
+		opts.Exploration = *overrides.Exploration
+	}
+	opts.Exploration = min(opts.Exploration, opts.Limit)
+
+	return opts, nil
+}
+
+// anyPositive reports whether at least one scorer has a positive weight
+func (w ScoringWeights) anyPositive() bool {
+	for _, weight := range w {
+		if weight > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"Ad-Recommendations/config"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveRankingOptions(t *testing.T) {
+	cfg := config.RecommendationConfig{Weights: map[string]float64{"category": 0.4, "content": 0.6}, Limit: 5, MaxLimit: 20}
+
+	opts, err := ResolveRankingOptions(cfg, RankingOverrides{})
+	assert.NoError(t, err)
+	assert.Equal(t, RankingOptions{Weights: ScoringWeights{"category": 0.4, "content": 0.6}, Limit: 5}, opts)
+
+	limit, minScore := 100, 3.0
+	opts, err = ResolveRankingOptions(cfg, RankingOverrides{
+		Limit:    &limit,
+		Weights:  ScoringWeights{"category": 0, "recency": 2},
+		MinScore: &minScore,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, RankingOptions{Weights: ScoringWeights{"category": 0, "content": 0.6, "recency": 1}, Limit: 20, MinScore: 1}, opts)
+	assert.Equal(t, 0.4, cfg.Weights["category"], "overrides must not change the configured weights")
+
+	zero := 0
+	_, err = ResolveRankingOptions(cfg, RankingOverrides{Limit: &zero})
+	assert.ErrorIs(t, err, ErrInvalidRankingOptions)
+	_, err = ResolveRankingOptions(cfg, RankingOverrides{Weights: ScoringWeights{"category": 0, "content": 0}})
+	assert.ErrorIs(t, err, ErrInvalidRankingOptions)
+	_, err = ResolveRankingOptions(cfg, RankingOverrides{Weights: ScoringWeights{"category": -1}})
+	assert.ErrorIs(t, err, ErrInvalidRankingOptions)
+	_, err = ResolveRankingOptions(cfg, RankingOverrides{Weights: ScoringWeights{"bert": 1}})
+	assert.ErrorIs(t, err, ErrInvalidRankingOptions)
+	exploration := 9
+	opts, err = ResolveRankingOptions(cfg, RankingOverrides{Exploration: &exploration})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, opts.Exploration, "the exploration budget is capped by the limit")
+	exploration = -1
+	_, err = ResolveRankingOptions(cfg, RankingOverrides{Exploration: &exploration})
+	assert.ErrorIs(t, err, ErrInvalidRankingOptions)
+	nan := math.NaN()
+	_, err = ResolveRankingOptions(cfg, RankingOverrides{MinScore: &nan})
+	assert.ErrorIs(t, err, ErrInvalidRankingOptions)
+}
@@ -0,0 +1,298 @@
+package services
+
+import (
+	"Ad-Recommendations/config"
+	"Ad-Recommendations/db"
+	"Ad-Recommendations/models"
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"time"
+)
+
+// RankingRequest carries what the pipeline stages know about one recommendation request
+type RankingRequest struct {
+	UserID          string
+	PlaybackHistory []string                    // Movie categories watched by the user
+	CategoryWeights map[string]float64          // Normalized affinity of the user to each mapped ad category
+	CategorySources map[string][]CategorySource // Movie categories that mapped to each ad category
+	Profile         UserProfile                 // Decayed playback and clicks of the user
+	UserVector      []float64                   // nil when the text signal is configured or embedding failed
+	Options         RankingOptions
+}
+
+// Candidate is an ad being ranked together with the features scored for it
+type Candidate struct {
+	Ad       models.Ad
+	Source   string             // Generator that produced the candidate
+	Features map[string]float64 // Score per scorer name
+	Score    float64            // Combined score
+
+	Multiplier    float64 // Applied to the combined score by post-filters, 1 when unchanged
+	PreFilterRank int     // 1-based position after scoring, before post-filters
+	Explored      bool    // Placed by the explorer rather than by its rank
+}
+
+// CandidateGenerator produces the ads considered for a request
+type CandidateGenerator interface {
+	Name() string
+	// Generate returns candidate ads; ads in seen were produced by an earlier generator and may be skipped
+	Generate(ctx context.Context, req *RankingRequest, seen map[string]bool) ([]models.Ad, error)
+}
+
+// Scorer computes one feature of every candidate, usually in [0, 1]
+type Scorer interface {
+	Name() string
+	// Score returns one score per ad, in the order of ads
+	Score(ctx context.Context, req *RankingRequest, ads []models.Ad) ([]float64, error)
+}
+
+// Combiner merges the features of a candidate into its final score
+type Combiner interface {
+	Combine(features map[string]float64, weights ScoringWeights) float64
+}
+
+// PostFilter drops or reorders candidates after they are sorted by score
+type PostFilter interface {
+	Name() string
+	Filter(ctx context.Context, req *RankingRequest, candidates []Candidate) []Candidate
+}
+
+// Explorer gives some slots of the ranked list to candidates it did not make,
+// so ads without a track record get served
+type Explorer interface {
+	Name() string
+	// Explore returns ranked with up to req.Options.Exploration slots taken by candidates from pool
+	Explore(ctx context.Context, req *RankingRequest, ranked, pool []Candidate) []Candidate
+}
+
+// Pipeline ranks ads in stages: candidate generators, feature scorers, a
+// combiner, post-filters, then an optional explorer. Scorers without a
+// positive weight in the request options are not run.
+type Pipeline struct {
+	Generators []CandidateGenerator
+	Scorers    []Scorer
+	Combiner   Combiner
+	Filters    []PostFilter
+	Explorer   Explorer // Optional
+}
+
+// NewPipeline assembles the ranking pipeline selected in the configuration.
+// Without configured candidate sources, category candidates are followed by
+// nearest-neighbour candidates. Ads clicked repeatedly are re-scored when a
+// repeat click policy is configured, and diversity re-ranking is added when a
+// lambda below 1 or a cap is configured. Collaborative filtering candidates
+// and scores need a cfModel; without one the cf source is skipped.
+func NewPipeline(cfg config.RecommendationConfig, store *db.Store, embeddings *AdEmbeddingService, text *TextIndex, cfModel *CFModel) *Pipeline {
+	sources := cfg.Candidates
+	if len(sources) == 0 {
+		sources = []string{config.CandidatesCategory, config.CandidatesNearest}
+	}
+
+	pipeline := &Pipeline{Combiner: WeightedSum{}}
+	if cfg.RepeatClickPolicy != "" && cfg.RepeatClickPolicy != config.RepeatClickNone &&
+		cfg.RepeatClickThreshold > 0 && cfg.RepeatClickMultiplier != 1 {
+		pipeline.Filters = append(pipeline.Filters, &RepeatClickFilter{Threshold: cfg.RepeatClickThreshold, Multiplier: cfg.RepeatClickMultiplier})
+	}
+	pipeline.Filters = append(pipeline.Filters, MinScoreFilter{})
+	if cfg.DiversityLambda > 0 && (cfg.DiversityLambda < 1 || cfg.MaxPerCategory > 0 || cfg.MaxPerAdvertiser > 0) {
+		pipeline.Filters = append(pipeline.Filters, &DiversityFilter{
+			Embeddings:       embeddings,
+			Lambda:           cfg.DiversityLambda,
+			MaxPerCategory:   cfg.MaxPerCategory,
+			MaxPerAdvertiser: cfg.MaxPerAdvertiser,
+		})
+	}
+	for _, source := range sources {
+		switch source {
+		case config.CandidatesCategory:
+			pipeline.Generators = append(pipeline.Generators, &CategoryCandidates{Ads: store.Ads})
+		case config.CandidatesNearest:
+			if embeddings.Index != nil && cfg.ANNCandidates > 0 {
+				pipeline.Generators = append(pipeline.Generators, &NearestCandidates{Ads: store.Ads, Index: embeddings.Index, K: cfg.ANNCandidates})
+			}
+		case config.CandidatesCF:
+			if cfModel != nil && cfg.CFCandidates > 0 {
+				pipeline.Generators = append(pipeline.Generators, &CFCandidates{Ads: store.Ads, Model: cfModel, K: cfg.CFCandidates})
+			}
+		}
+	}
+
+	pipeline.Scorers = []Scorer{
+		CategoryScorer{},
+		&ContentScorer{Embeddings: embeddings, Text: text},
+		KeywordScorer{},
+		&RecencyScorer{HalfLife: cfg.RecencyHalfLife, Now: time.Now},
+		&PopularityScorer{Clicks: store.Clicks, Window: cfg.PopularityWindow, Now: time.Now},
+	}
+	if cfModel != nil {
+		pipeline.Scorers = append(pipeline.Scorers, &CFScorer{Model: cfModel})
+	}
+	return pipeline
+}
+
+// Rank runs every stage and returns up to req.Options.Limit candidates, best
+// first. It returns nil when no generator produced a candidate.
+func (p *Pipeline) Rank(ctx context.Context, req *RankingRequest) []Candidate {
+	ads, sources := p.generate(ctx, req)
+	if len(ads) == 0 {
+		return nil
+	}
+
+	features := make([]map[string]float64, len(ads))
+	for i := range features {
+		features[i] = map[string]float64{}
+	}
+	for _, scorer := range p.Scorers {
+		if req.Options.Weights[scorer.Name()] <= 0 {
+			continue
+		}
+		scores, err := scorer.Score(ctx, req, ads)
+		if err == nil && len(scores) != len(ads) {
+			err = fmt.Errorf("returned %d scores for %d ads", len(scores), len(ads))
+		}
+		if err != nil {
+			log.Printf("⚠️ Scorer %s failed, ranking without it: %v", scorer.Name(), err)
+			continue
+		}
+		for i, score := range scores {
+			features[i][scorer.Name()] = score
+		}
+	}
+
+	candidates := make([]Candidate, len(ads))
+	for i, ad := range ads {
+		candidates[i] = Candidate{
+			Ad:         ad,
+			Source:     sources[i],
+			Features:   features[i],
+			Score:      p.Combiner.Combine(features[i], req.Options.Weights),
+			Multiplier: 1,
+		}
+		log.Printf("📊 AdID: %s, Category: %s, Source: %s, Features: %v, Final Score: %.4f",
+			ad.AdID, ad.Category, sources[i], features[i], candidates[i].Score)
+	}
+
+	// Sort by final score; ties keep the generator order
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+	for i := range candidates {
+		candidates[i].PreFilterRank = i + 1
+	}
+	// With exploration the filters run without the limit, so the candidates
+	// cut by nothing but the limit are left for the explorer. Diversity picks
+	// greedily, so the first candidates are the same either way.
+	exploring := p.Explorer != nil && req.Options.Exploration > 0
+	filterReq := req
+	if exploring {
+		unlimited := *req
+		unlimited.Options.Limit = 0
+		filterReq = &unlimited
+	}
+	for _, filter := range p.Filters {
+		candidates = filter.Filter(ctx, filterReq, candidates)
+	}
+
+	var pool []Candidate
+	if req.Options.Limit > 0 && len(candidates) > req.Options.Limit {
+		pool = slices.Clone(candidates[req.Options.Limit:])
+		candidates = candidates[:req.Options.Limit]
+	}
+	if exploring && len(pool) > 0 {
+		candidates = p.Explorer.Explore(ctx, req, candidates, pool)
+	}
+
+	for i, candidate := range candidates {
+		log.Printf("🏆 Ranked Ad #%d - ID: %s, Final Score: %.4f, Explored: %t", i+1, candidate.Ad.AdID, candidate.Score, candidate.Explored)
+	}
+	return candidates
+}
+
+// generate collects the candidates of every generator, without duplicates
+func (p *Pipeline) generate(ctx context.Context, req *RankingRequest) ([]models.Ad, []string) {
+	ads := []models.Ad{}
+	sources := []string{}
+	seen := map[string]bool{}
+	for _, generator := range p.Generators {
+		generated, err := generator.Generate(ctx, req, seen)
+		if err != nil {
+			log.Printf("⚠️ Candidate generator %s failed: %v", generator.Name(), err)
+			continue
+		}
+		for _, ad := range generated {
+			if seen[ad.AdID] {
+				continue
+			}
+			seen[ad.AdID] = true
+			ads = append(ads, ad)
+			sources = append(sources, generator.Name())
+		}
+	}
+
+	log.Printf("✅ Total Unique Ads Retrieved: %d", len(ads))
+	return ads, sources
+}
+
+// WeightedSum combines features as their weighted sum; missing features count as 0
+type WeightedSum struct{}
+
+// Combine returns the weighted sum of the features
+func (WeightedSum) Combine(features map[string]float64, weights ScoringWeights) float64 {
+	score := 0.0
+	for name, value := range features {
+		score += weights[name] * value
+	}
+	return score
+}
+
+// RepeatClickFilter multiplies the score of ads the user clicked at least
+// Threshold times by Multiplier, demoting them below 1 and boosting them above,
+// and re-sorts the candidates
+type RepeatClickFilter struct {
+	Threshold  int
+	Multiplier float64
+}
+
+// Name identifies the filter in logs
+func (f *RepeatClickFilter) Name() string { return "repeat_clicks" }
+
+// Filter applies the multiplier to repeatedly clicked ads
+func (f *RepeatClickFilter) Filter(ctx context.Context, req *RankingRequest, candidates []Candidate) []Candidate {
+	changed := false
+	for i := range candidates {
+		clicks := req.Profile.Clicks[candidates[i].Ad.AdID]
+		if clicks < f.Threshold {
+			continue
+		}
+		candidates[i].Score *= f.Multiplier
+		candidates[i].Multiplier *= f.Multiplier
+		changed = true
+		log.Printf("🔁 Ad %s clicked %d times by the user, score multiplied by %.2f", candidates[i].Ad.AdID, clicks, f.Multiplier)
+	}
+	if changed {
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].Score > candidates[j].Score
+		})
+	}
+	return candidates
+}
+
+// MinScoreFilter drops candidates scoring below the request's minimum score
+type MinScoreFilter struct{}
+
+// Name identifies the filter in logs
+func (MinScoreFilter) Name() string { return "min_score" }
+
+// Filter keeps the candidates reaching req.Options.MinScore
+func (MinScoreFilter) Filter(ctx context.Context, req *RankingRequest, candidates []Candidate) []Candidate {
+	kept := candidates[:0]
+	for _, candidate := range candidates {
+		if candidate.Score >= req.Options.MinScore {
+			kept = append(kept, candidate)
+		}
+	}
+	return kept
+}
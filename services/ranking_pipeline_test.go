@@ -0,0 +1,137 @@
+package services
+
+import (
+	"Ad-Recommendations/db"
+	"Ad-Recommendations/models"
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// staticCandidates generates a fixed list of ads
+type staticCandidates []models.Ad
+
+func (g staticCandidates) Name() string { return "static" }
+
+func (g staticCandidates) Generate(ctx context.Context, req *RankingRequest, seen map[string]bool) ([]models.Ad, error) {
+	return g, nil
+}
+
+// staticScorer scores ads from a map keyed by ad ID
+type staticScorer struct {
+	name   string
+	scores map[string]float64
+	calls  int
+}
+
+func (s *staticScorer) Name() string { return s.name }
+
+func (s *staticScorer) Score(ctx context.Context, req *RankingRequest, ads []models.Ad) ([]float64, error) {
+	s.calls++
+	scores := make([]float64, len(ads))
+	for i, ad := range ads {
+		scores[i] = s.scores[ad.AdID]
+	}
+	return scores, nil
+}
+
+func TestPipelineCombinesFiltersAndLimits(t *testing.T) {
+	content := &staticScorer{name: "content", scores: map[string]float64{"a": 0.9, "b": 0.5, "c": 0.8}}
+	unused := &staticScorer{name: "popularity"}
+	pipeline := &Pipeline{
+		Generators: []CandidateGenerator{staticCandidates{{AdID: "a"}, {AdID: "b"}, {AdID: "c"}, {AdID: "a"}}},
+		Scorers:    []Scorer{content, unused},
+		Combiner:   WeightedSum{},
+		Filters:    []PostFilter{MinScoreFilter{}},
+	}
+
+	ranked := pipeline.Rank(context.Background(), &RankingRequest{Options: RankingOptions{
+		Weights:  ScoringWeights{"content": 1, "popularity": 0},
+		Limit:    2,
+		MinScore: 0.6,
+	}})
+
+	assert.Len(t, ranked, 2)
+	assert.Equal(t, "a", ranked[0].Ad.AdID)
+	assert.Equal(t, "c", ranked[1].Ad.AdID)
+	assert.Equal(t, map[string]float64{"content": 0.8}, ranked[1].Features)
+	assert.Equal(t, "static", ranked[1].Source)
+	assert.Zero(t, unused.calls, "scorers without weight are not run")
+}
+
+func TestFeatureScorers(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+	ads := []models.Ad{
+		{AdID: "new", Keywords: []string{"action", "cars"}, CreatedAt: now},
+		{AdID: "old", Keywords: []string{"comedy"}, CreatedAt: now.Add(-30 * 24 * time.Hour)},
+		{AdID: "legacy"},
+	}
+	req := &RankingRequest{PlaybackHistory: []string{"Action"}}
+
+	keywords, err := KeywordScorer{}.Score(ctx, req, ads)
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{0.5, 0, 0}, keywords)
+
+	recency, err := (&RecencyScorer{HalfLife: 30 * 24 * time.Hour, Now: func() time.Time { return now }}).Score(ctx, req, ads)
+	assert.NoError(t, err)
+	assert.InDeltaSlice(t, []float64{1, 0.5, 0}, recency, 1e-9)
+
+	store := db.NewMemoryStore()
+	for _, click := range []models.AdClick{
+		{UserID: "u1", AdID: "new", Timestamp: now.Add(-time.Hour)},
+		{UserID: "u2", AdID: "new", Timestamp: now.Add(-time.Hour)},
+		{UserID: "u1", AdID: "old", Timestamp: now.Add(-time.Hour)},
+		{UserID: "u1", AdID: "legacy", Timestamp: now.Add(-30 * 24 * time.Hour)},
+	} {
+		assert.NoError(t, store.Clicks.LogAdClick(ctx, click))
+	}
+	popularity, err := (&PopularityScorer{Clicks: store.Clicks, Window: 24 * time.Hour, Now: func() time.Time { return now }}).Score(ctx, req, ads)
+	assert.NoError(t, err)
+	assert.InDeltaSlice(t, []float64{1, math.Log(2) / math.Log(3), 0}, popularity, 1e-9)
+}
+
+func TestDiversityFilter(t *testing.T) {
+	ctx := context.Background()
+	candidates := func() []Candidate {
+		return []Candidate{
+			{Ad: models.Ad{AdID: "g1", Category: "Gadgets", Advertiser: "acme"}, Score: 0.9},
+			{Ad: models.Ad{AdID: "g2", Category: "Gadgets", Advertiser: "acme"}, Score: 0.85},
+			{Ad: models.Ad{AdID: "g3", Category: "Gadgets"}, Score: 0.8},
+			{Ad: models.Ad{AdID: "c1", Category: "Cars"}, Score: 0.5},
+		}
+	}
+	ids := func(candidates []Candidate) []string {
+		ids := []string{}
+		for _, candidate := range candidates {
+			ids = append(ids, candidate.Ad.AdID)
+		}
+		return ids
+	}
+	req := &RankingRequest{Options: RankingOptions{Limit: 3}}
+
+	mmr := &DiversityFilter{Lambda: 0.5}
+	assert.Equal(t, []string{"g1", "c1", "g2"}, ids(mmr.Filter(ctx, req, candidates())))
+
+	caps := &DiversityFilter{Lambda: 1, MaxPerCategory: 2, MaxPerAdvertiser: 1}
+	assert.Equal(t, []string{"g1", "g3", "c1"}, ids(caps.Filter(ctx, req, candidates())))
+}
+
+func TestRepeatClickFilter(t *testing.T) {
+	req := &RankingRequest{Profile: UserProfile{Clicks: map[string]int{"car": 3, "van": 1}}}
+	candidates := []Candidate{
+		{Ad: models.Ad{AdID: "car"}, Score: 0.9, Multiplier: 1},
+		{Ad: models.Ad{AdID: "van"}, Score: 0.6, Multiplier: 1},
+	}
+
+	demoted := (&RepeatClickFilter{Threshold: 3, Multiplier: 0.5}).Filter(context.Background(), req, candidates)
+
+	assert.Equal(t, "van", demoted[0].Ad.AdID)
+	assert.Equal(t, "car", demoted[1].Ad.AdID)
+	assert.InDelta(t, 0.45, demoted[1].Score, 1e-9)
+	assert.Equal(t, 0.5, demoted[1].Multiplier)
+	assert.Equal(t, 1.0, demoted[0].Multiplier)
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"Ad-Recommendations/models"
+	"context"
+	"sort"
+)
+
+// Recommendation is a ranked ad together with the explanation of its rank
+type Recommendation struct {
+	Ad          models.Ad   `json:"ad"`
+	Explanation Explanation `json:"explanation"`
+}
+
+// Explanation breaks down how an ad was retrieved and scored
+type Explanation struct {
+	Source            string           `json:"source"` // Candidate generator that retrieved the ad
+	Score             float64          `json:"score"`
+	Contributions     []Contribution   `json:"contributions"`
+	Multiplier        float64          `json:"multiplier"`       // Applied by post-filters to the sum of contributions
+	UserClicks        int              `json:"user_clicks"`      // Clicks of the user on the ad
+	MovieCategories   []CategorySource `json:"movie_categories"` // Playback categories mapped to the ad category
+	RankBeforeFilters int              `json:"rank_before_filters"`
+	RankAfterFilters  int              `json:"rank_after_filters"`
+	Explored          bool             `json:"explored"` // Served in an exploration slot rather than for its rank
+}
+
+// Contribution is the share of one scorer in the final score
+type Contribution struct {
+	Scorer       string  `json:"scorer"`
+	Score        float64 `json:"score"`
+	Weight       float64 `json:"weight"`
+	Contribution float64 `json:"contribution"` // Weight times score
+}
+
+// ExplainRecommendations ranks ads like GenerateRecommendations and explains each of them
+func (s *RecommendationService) ExplainRecommendations(ctx context.Context, userID string, opts RankingOptions) []Recommendation {
+	req, ranked := s.rank(ctx, userID, opts)
+	if ranked == nil {
+		return nil
+	}
+
+	recommendations := make([]Recommendation, len(ranked))
+	for i, candidate := range ranked {
+		recommendations[i] = Recommendation{Ad: candidate.Ad, Explanation: explain(req, candidate, i+1)}
+	}
+	return recommendations
+}
+
+// explain describes a candidate ranked at the given position
+func explain(req *RankingRequest, candidate Candidate, rank int) Explanation {
+	contributions := []Contribution{}
+	for scorer, score := range candidate.Features {
+		weight := req.Options.Weights[scorer]
+		contributions = append(contributions, Contribution{Scorer: scorer, Score: score, Weight: weight, Contribution: weight * score})
+	}
+	// Largest contribution first
+	sort.Slice(contributions, func(i, j int) bool {
+		if contributions[i].Contribution != contributions[j].Contribution {
+			return contributions[i].Contribution > contributions[j].Contribution
+		}
+		return contributions[i].Scorer < contributions[j].Scorer
+	})
+
+	movieCategories := req.CategorySources[candidate.Ad.Category]
+	if movieCategories == nil {
+		movieCategories = []CategorySource{}
+	}
+
+	return Explanation{
+		Source:            candidate.Source,
+		Score:             candidate.Score,
+		Contributions:     contributions,
+		Multiplier:        candidate.Multiplier,
+		UserClicks:        req.Profile.Clicks[candidate.Ad.AdID],
+		MovieCategories:   movieCategories,
+		RankBeforeFilters: candidate.PreFilterRank,
+		RankAfterFilters:  rank,
+		Explored:          candidate.Explored,
+	}
+}
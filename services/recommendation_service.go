@@ -1,280 +1,339 @@
 package services
 
 import (
+	"Ad-Recommendations/config"
 	"Ad-Recommendations/db"
 	"Ad-Recommendations/models"
 	"context"
+	"errors"
 	"log"
 	"math"
-	"sort"
-	"strconv"
-
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"sync"
+	"time"
 )
 
-// FetchMappedAdCategories retrieves ad category mappings from DynamoDB
-func FetchMappedAdCategories(movieCategory string) (map[string]float64, error) {
-	log.Printf("🔍 Fetching mapped ad categories for movie category: %s", movieCategory)
-
-	output, err := db.DynamoClient.GetItem(context.TODO(), &dynamodb.GetItemInput{
-		TableName: aws.String(db.CategoryMappingTableName),
-		Key: map[string]types.AttributeValue{
-			"movie_category": &types.AttributeValueMemberS{Value: movieCategory},
-		},
-	})
+// RecommendationService generates ad recommendations from playback history
+type RecommendationService struct {
+	Playback     db.PlaybackRepository
+	Clicks       db.AdClickRepository
+	Mappings     db.CategoryMappingRepository
+	Ads          db.AdRepository
+	AdEmbeddings *AdEmbeddingService
+	Embedder     Embedder   // Embeds playback history at request time
+	Text         *TextIndex // In-process text relevance, used as a signal or fallback
+	Pipeline     *Pipeline  // Generates, scores and filters candidate ads
+	Config       config.RecommendationConfig
+}
 
-	if err != nil {
-		log.Printf("❌ DynamoDB error fetching category mapping for %s: %v", movieCategory, err)
-		return nil, err
+// NewRecommendationService creates a RecommendationService ranking through the
+// pipeline newPipeline builds for the configuration, the same that builds the
+// pipelines of experiment variants. Playback history is embedded with the
+// model of the stored ad embeddings; the text index scores ads when the text
+// signal is selected or embedding fails.
+func NewRecommendationService(store *db.Store, cfg config.RecommendationConfig, adEmbeddings *AdEmbeddingService, text *TextIndex, newPipeline func(config.RecommendationConfig) *Pipeline) *RecommendationService {
+	return &RecommendationService{
+		Playback:     store.Playback,
+		Clicks:       store.Clicks,
+		Mappings:     store.Mappings,
+		Ads:          store.Ads,
+		AdEmbeddings: adEmbeddings,
+		Embedder:     adEmbeddings.Embedder,
+		Text:         text,
+		Pipeline:     newPipeline(cfg),
+		Config:       cfg,
 	}
+}
+
+// FetchMappedAdCategories retrieves ad category mappings from the mapping store
+func (s *RecommendationService) FetchMappedAdCategories(ctx context.Context, movieCategory string) (map[string]float64, error) {
+	log.Printf("🔍 Fetching mapped ad categories for movie category: %s", movieCategory)
 
-	if output.Item == nil || len(output.Item) == 0 {
+	mapping, err := s.Mappings.GetCategoryMapping(ctx, movieCategory)
+	if errors.Is(err, db.ErrNotFound) {
 		log.Printf("⚠️ No category mapping found for movie category: %s", movieCategory)
 		return map[string]float64{}, nil
 	}
-
-	mappedCategories := make(map[string]float64)
-
-	// Extract mapped categories
-	if categoriesAttr, ok := output.Item["ad_categories"].(*types.AttributeValueMemberSS); ok {
-		for _, category := range categoriesAttr.Value {
-			mappedCategories[category] = 1.0 // Default weight
-		}
+	if err != nil {
+		log.Printf("❌ Error fetching category mapping for %s: %v", movieCategory, err)
+		return nil, err
 	}
 
-	// Extract weight if available
-	if weightAttr, ok := output.Item["weight"].(*types.AttributeValueMemberN); ok {
-		weightValue, err := strconv.ParseFloat(weightAttr.Value, 64)
-		if err == nil {
-			for key := range mappedCategories {
-				mappedCategories[key] = weightValue
-			}
-		}
-	}
+	mappedCategories := mapping.Weights()
 
 	log.Printf("✅ Mapped categories with weights for %s: %v", movieCategory, mappedCategories)
 	return mappedCategories, nil
 }
 
-// FetchUserPlaybackHistory retrieves the playback history for a user
-func FetchUserPlaybackHistory(userID string) ([]string, error) {
+// FetchUserPlaybackHistory retrieves the newest playback events of a user,
+// bounded by the configured history limit and window. Events without a
+// category are left out.
+func (s *RecommendationService) FetchUserPlaybackHistory(ctx context.Context, userID string) ([]models.PlaybackEvent, error) {
 	log.Printf("🔍 Fetching playback history for user: %s", userID)
 
-	output, err := db.DynamoClient.Query(context.TODO(), &dynamodb.QueryInput{
-		TableName:              aws.String(db.PlaybackTableName),
-		KeyConditionExpression: aws.String("user_id = :userID"),
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":userID": &types.AttributeValueMemberS{Value: userID},
-		},
-	})
+	query := db.HistoryQuery{Limit: s.Config.HistoryLimit}
+	if s.Config.HistoryWindow > 0 {
+		query.Since = time.Now().Add(-s.Config.HistoryWindow)
+	}
 
+	events, _, err := s.Playback.PlaybackHistory(ctx, userID, query)
 	if err != nil {
 		log.Printf("❌ Failed to query playback history: %v", err)
 		return nil, err
 	}
 
-	playbackHistory := []string{}
-	for _, item := range output.Items {
-		if category, ok := item["category"].(*types.AttributeValueMemberS); ok {
-			playbackHistory = append(playbackHistory, category.Value)
+	playbackHistory := []models.PlaybackEvent{}
+	for _, event := range events {
+		if event.Category != "" {
+			playbackHistory = append(playbackHistory, event)
 		}
 	}
 
-	log.Printf("✅ Retrieved playback history for %s: %v", userID, playbackHistory)
+	log.Printf("✅ Retrieved %d playback events for %s", len(playbackHistory), userID)
 	return playbackHistory, nil
 }
 
-// Normalize category scores and rescale them
-func NormalizeCategory(userCategories []string) map[string]float64 {
-	normalized := make(map[string]float64)
-	log.Printf("🔎 Mapping categories: %v", userCategories)
+// FavouriteMovieCategory returns the movie category the user watched most,
+// weighted by age, or "" for a user without playback
+func (s *RecommendationService) FavouriteMovieCategory(ctx context.Context, userID string) (string, error) {
+	events, err := s.FetchUserPlaybackHistory(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	return BuildUserProfile(events, s.Config.ProfileHalfLife, time.Now()).FavouriteCategory(), nil
+}
 
-	for _, movieCategory := range userCategories {
-		if movieCategory == "" {
-			log.Println("⚠️ Skipping empty category")
-			continue
-		}
+// FetchUserClicks retrieves the newest ad clicks of a user, bounded like the
+// playback history, and the clicked ads that still exist, by ID
+func (s *RecommendationService) FetchUserClicks(ctx context.Context, userID string) ([]models.AdClick, map[string]models.Ad, error) {
+	query := db.HistoryQuery{Limit: s.Config.HistoryLimit}
+	if s.Config.HistoryWindow > 0 {
+		query.Since = time.Now().Add(-s.Config.HistoryWindow)
+	}
 
-		mappedAdCategories, err := FetchMappedAdCategories(movieCategory)
-		if err != nil {
-			log.Printf("❌ Error fetching mapped categories for %s: %v", movieCategory, err)
-			continue
-		}
+	clicks, _, err := s.Clicks.AdClickHistory(ctx, userID, query)
+	if err != nil {
+		return nil, nil, err
+	}
 
-		for adCategory, weight := range mappedAdCategories {
-			normalized[adCategory] = (normalized[adCategory] + weight) / 2
+	adIDs := []string{}
+	seen := map[string]bool{}
+	for _, click := range clicks {
+		if !seen[click.AdID] {
+			seen[click.AdID] = true
+			adIDs = append(adIDs, click.AdID)
 		}
 	}
 
-	// Normalize the category scores
-	var maxVal, minVal float64 = math.Inf(-1), math.Inf(1)
-	for _, v := range normalized {
-		if v > maxVal {
-			maxVal = v
-		}
-		if v < minVal {
-			minVal = v
-		}
+	results := make([]*models.Ad, len(adIDs))
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, maxConcurrentCategoryQueries)
+	for i, adID := range adIDs {
+		wg.Add(1)
+		go func(i int, adID string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			ad, err := s.Ads.GetAd(ctx, adID)
+			if err != nil {
+				if !errors.Is(err, db.ErrNotFound) {
+					log.Printf("⚠️ Failed to fetch clicked ad %s: %v", adID, err)
+				}
+				return
+			}
+			results[i] = ad
+		}(i, adID)
 	}
+	wg.Wait()
 
-	if maxVal > minVal {
-		for k, v := range normalized {
-			normalized[k] = (v - minVal) / (maxVal - minVal)
+	ads := make(map[string]models.Ad, len(adIDs))
+	for _, ad := range results {
+		if ad != nil {
+			ads[ad.AdID] = *ad
 		}
 	}
 
-	log.Printf("✅ Final Normalized Categories: %v", normalized)
-	return normalized
+	log.Printf("✅ Retrieved %d ad clicks on %d ads for %s", len(clicks), len(ads), userID)
+	return clicks, ads, nil
 }
 
-// FetchAdsForRecommendation retrieves ads based on category weights
-func FetchAdsForRecommendation(categories map[string]float64) ([]models.Ad, map[string]float64, error) {
-	ads := []models.Ad{}
-	seenAds := make(map[string]bool)
-	categoryWeights := make(map[string]float64)
-
-	log.Printf("🔍 Fetching ads for mapped categories: %v", categories)
-
-	for category, weight := range categories {
-		log.Printf("🔍 Querying AdTable for category: %s (weight: %.4f)", category, weight)
+// profileWeights returns the shares of playback and clicks in the user profile.
+// With neither set, as in a zero configuration, only playback counts.
+func (s *RecommendationService) profileWeights() (float64, float64) {
+	if s.Config.PlaybackWeight == 0 && s.Config.ClickWeight == 0 {
+		return 1, 0
+	}
+	return s.Config.PlaybackWeight, s.Config.ClickWeight
+}
 
-		output, err := db.DynamoClient.Scan(context.TODO(), &dynamodb.ScanInput{
-			TableName:        aws.String(db.AdTableName),
-			FilterExpression: aws.String("category = :category"),
-			ExpressionAttributeValues: map[string]types.AttributeValue{
-				":category": &types.AttributeValueMemberS{Value: category},
-			},
-		})
+// CategorySource is a movie category of the playback history that mapped to an ad category
+type CategorySource struct {
+	MovieCategory string  `json:"movie_category"`
+	MappingWeight float64 `json:"mapping_weight"`
+	Plays         int     `json:"plays"`       // Occurrences in the playback history
+	PlayWeight    float64 `json:"play_weight"` // Plays decayed by age
+}
 
+// CategoryAffinities maps the movie categories of a profile to ad categories.
+// The affinity of an ad category is the playback weight times the sum over
+// movie categories of their decayed play weight times the mapping weight,
+// plus the click weight times the decayed clicks on ads of the category. It
+// is divided by the highest affinity so the favourite category scores 1. It
+// also returns, for each ad category, the movie categories that mapped to it.
+func (s *RecommendationService) CategoryAffinities(ctx context.Context, profile UserProfile) (map[string]float64, map[string][]CategorySource) {
+	affinities := make(map[string]float64)
+	sources := map[string][]CategorySource{}
+	playbackWeight, clickWeight := s.profileWeights()
+	log.Printf("🔎 Mapping categories: %v", profile.MovieCategories)
+
+	for _, movieCategory := range profile.Categories() {
+		mappedAdCategories, err := s.FetchMappedAdCategories(ctx, movieCategory)
 		if err != nil {
-			log.Printf("❌ Failed to fetch ads for category %s: %v", category, err)
+			log.Printf("❌ Error fetching mapped categories for %s: %v", movieCategory, err)
 			continue
 		}
 
-		for _, item := range output.Items {
-			adID := item["ad_id"].(*types.AttributeValueMemberS).Value
-
-			if seenAds[adID] {
-				log.Printf("⚠️ Skipping duplicate ad: %s", adID)
-				continue
-			}
-			seenAds[adID] = true
-
-			ad := models.Ad{
-				AdID:        adID,
-				Category:    item["category"].(*types.AttributeValueMemberS).Value,
-				Description: item["description"].(*types.AttributeValueMemberS).Value,
-			}
-
-			ads = append(ads, ad)
-			categoryWeights[category] = weight
+		playWeight := profile.MovieCategories[movieCategory]
+		for adCategory, weight := range mappedAdCategories {
+			affinities[adCategory] += playbackWeight * playWeight * weight
+			sources[adCategory] = append(sources[adCategory], CategorySource{
+				MovieCategory: movieCategory,
+				MappingWeight: weight,
+				Plays:         profile.Plays[movieCategory],
+				PlayWeight:    playWeight,
+			})
 		}
 	}
 
-	log.Printf("✅ Total Unique Ads Retrieved: %d", len(ads))
-	return ads, categoryWeights, nil
-}
-
-// RankAdsByHybridScoring ranks ads using a combination of category score and BERT similarity
-func RankAdsByHybridScoring(ads []models.Ad, adEmbeddings [][]float64, userVector []float64, categoryWeights map[string]float64) []models.Ad {
-	if len(ads) == 0 || len(adEmbeddings) == 0 {
-		log.Println("⚠️ No ads or embeddings available for ranking")
-		return nil
+	if clickWeight > 0 {
+		for adCategory, clicked := range profile.ClickedCategories {
+			affinities[adCategory] += clickWeight * clicked
+		}
 	}
 
-	type ScoredAd struct {
-		Ad    models.Ad
-		Score float64
+	maxAffinity := 0.0
+	for _, affinity := range affinities {
+		maxAffinity = math.Max(maxAffinity, affinity)
 	}
-
-	scores := []ScoredAd{}
-
-	for i, ad := range ads {
-		bertScore := CosineSimilarity(userVector, adEmbeddings[i])
-		categoryScore, exists := categoryWeights[ad.Category]
-		if !exists {
-			categoryScore = 0.0
+	if maxAffinity > 0 {
+		for category := range affinities {
+			affinities[category] /= maxAffinity
 		}
-
-		// Adjust weights to balance category score and BERT similarity
-		finalScore := (0.4 * categoryScore) + (0.6 * bertScore)
-
-		log.Printf("📊 AdID: %s, Category: %s, Category Score: %.4f, BERT Score: %.4f, Final Score: %.4f",
-			ad.AdID, ad.Category, categoryScore, bertScore, finalScore)
-
-		scores = append(scores, ScoredAd{Ad: ad, Score: finalScore})
 	}
 
-	// Sort Ads by Final Score (Descending)
-	sort.Slice(scores, func(i, j int) bool {
-		return scores[i].Score > scores[j].Score
-	})
+	log.Printf("✅ Final Normalized Categories: %v", affinities)
+	return affinities, sources
+}
 
-	// Select Top 5 Ads
-	topN := 5
-	if len(scores) < topN {
-		topN = len(scores)
+// GenerateRecommendations generates ad recommendations ranked with the given options
+func (s *RecommendationService) GenerateRecommendations(ctx context.Context, userID string, opts RankingOptions) []models.Ad {
+	_, ranked := s.rank(ctx, userID, opts)
+	if ranked == nil {
+		return nil
 	}
 
-	rankedAds := make([]models.Ad, topN)
-	for i := 0; i < topN; i++ {
-		rankedAds[i] = scores[i].Ad
-		log.Printf("🏆 Ranked Ad #%d - ID: %s, Final Score: %.4f", i+1, scores[i].Ad.AdID, scores[i].Score)
+	rankedAds := make([]models.Ad, len(ranked))
+	for i, candidate := range ranked {
+		rankedAds[i] = candidate.Ad
 	}
-
 	log.Printf("✅ Final Ranked Ads: %+v", rankedAds)
 	return rankedAds
 }
 
-// GenerateRecommendations generates ad recommendations
-func GenerateRecommendations(userID string) []models.Ad {
+// rank builds the ranking request of a user and runs it through the pipeline.
+// The ranked candidates are nil when the request could not be served.
+func (s *RecommendationService) rank(ctx context.Context, userID string, opts RankingOptions) (*RankingRequest, []Candidate) {
 	log.Printf("🔍 Generating recommendations for user: %s", userID)
 
 	// Fetch user playback history
-	playbackHistory, err := FetchUserPlaybackHistory(userID)
+	events, err := s.FetchUserPlaybackHistory(ctx, userID)
 	if err != nil {
 		log.Printf("❌ Failed to fetch playback history: %v", err)
-		return nil
+		return nil, nil
+	}
+	playbackHistory := make([]string, len(events))
+	for i, event := range events {
+		playbackHistory[i] = event.Category
 	}
 
-	// Normalize category scores
-	mappedCategories := NormalizeCategory(playbackHistory)
-
-	// Fetch ads based on mapped categories
-	ads, categoryWeights, err := FetchAdsForRecommendation(mappedCategories)
-	if err != nil || len(ads) == 0 {
+	// Weigh plays and clicks by age and map them to ad category affinities
+	now := time.Now()
+	profile := BuildUserProfile(events, s.Config.ProfileHalfLife, now)
+	if clicks, clickedAds, err := s.FetchUserClicks(ctx, userID); err != nil {
+		log.Printf("⚠️ Failed to fetch ad clicks, ranking from playback only: %v", err)
+	} else {
+		profile.AddClicks(clicks, clickedAds, s.Config.ProfileHalfLife, now)
+	}
+	mappedCategories, categorySources := s.CategoryAffinities(ctx, profile)
+
+	// Embed the user's profile; a nil vector selects text scoring
+	userVector := s.UserVector(ctx, profile)
+
+	// Generate, score, combine and filter candidates
+	req := &RankingRequest{
+		UserID:          userID,
+		PlaybackHistory: playbackHistory,
+		CategoryWeights: mappedCategories,
+		CategorySources: categorySources,
+		Profile:         profile,
+		UserVector:      userVector,
+		Options:         opts,
+	}
+	ranked := s.Pipeline.Rank(ctx, req)
+	if ranked == nil {
 		log.Println("⚠️ No ads found for mapped categories")
+	}
+	return req, ranked
+}
+
+// UserVector averages the embeddings of the profile's movie categories and
+// clicked ads, weighted by their decayed play or click weight times the
+// playback or click weight of the profile. It returns nil when the text
+// signal is configured or the embedder fails, in which case ranking falls back
+// to the text index.
+func (s *RecommendationService) UserVector(ctx context.Context, profile UserProfile) []float64 {
+	if s.Config.Signal == config.SignalText {
 		return nil
 	}
+	playbackWeight, clickWeight := s.profileWeights()
+
+	embeddings := [][]float64{}
+	weights := []float64{}
 
-	// Extract ad descriptions for BERT embeddings
-	adTexts := make([]string, len(ads))
-	for i, ad := range ads {
-		adTexts[i] = ad.Description
+	categories := profile.Categories()
+	if playbackWeight > 0 && len(categories) > 0 {
+		historyEmbeddings, err := s.Embedder.Embed(ctx, categories)
+		if err != nil {
+			log.Printf("⚠️ Failed to generate embeddings for user history, falling back to text scoring: %v", err)
+			return nil
+		}
+		for i, category := range categories {
+			embeddings = append(embeddings, historyEmbeddings[i])
+			weights = append(weights, playbackWeight*profile.MovieCategories[category])
+		}
 	}
 
-	// Generate BERT embeddings for ads
-	adEmbeddings, err := GenerateBERTEmbeddings(adTexts)
-	if err != nil {
-		log.Println("❌ Failed to generate BERT embeddings for ads")
-		return nil
+	clickedAds := profile.ClickedAdList()
+	if clickWeight > 0 && len(clickedAds) > 0 {
+		adVectors, err := s.AdEmbeddings.EnsureEmbeddings(ctx, clickedAds)
+		if err != nil {
+			log.Printf("⚠️ Failed to load clicked ad embeddings, using playback only: %v", err)
+		}
+		for _, ad := range clickedAds {
+			if adVector, ok := adVectors[ad.AdID]; ok {
+				embeddings = append(embeddings, adVector)
+				weights = append(weights, clickWeight*profile.ClickedAds[ad.AdID])
+			}
+		}
 	}
 
-	// Generate BERT embeddings for user's playback history
-	historyEmbeddings, err := GenerateBERTEmbeddings(playbackHistory)
+	if len(embeddings) == 0 {
+		return nil
+	}
+	userVector, err := ComputeUserVector(embeddings, weights)
 	if err != nil {
-		log.Println("❌ Failed to generate BERT embeddings for user history")
+		log.Printf("⚠️ %v, falling back to text scoring", err)
 		return nil
 	}
-
-	// Compute user embedding vector
-	userVector := ComputeUserVector(historyEmbeddings)
-	log.Printf("📊 Computed User Embedding Vector")
-
-	// Rank Ads using Hybrid Scoring (Category + BERT Scores)
-	rankedAds := RankAdsByHybridScoring(ads, adEmbeddings, userVector, categoryWeights)
-
-	return rankedAds
+	return userVector
 }
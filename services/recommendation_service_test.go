@@ -0,0 +1,201 @@
+package services
+
+import (
+	"Ad-Recommendations/config"
+	"Ad-Recommendations/db"
+	"Ad-Recommendations/models"
+	"Ad-Recommendations/services/ann"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeEmbed maps each known text to a fixed BERT-sized vector
+var fakeEmbed = EmbedderFunc(func(ctx context.Context, texts []string) ([][]float64, error) {
+	axes := map[string][2]float64{
+		"Action":           {1, 0},
+		"Comedy":           {0, 1},
+		"Fast sports car":  {1, 0},
+		"Stand-up tour":    {0, 1},
+		"Noise cancelling": {0.7, 0.7},
+	}
+	embeddings := make([][]float64, len(texts))
+	for i, text := range texts {
+		embeddings[i] = make([]float64, 768)
+		embeddings[i][0] = axes[text][0]
+		embeddings[i][1] = axes[text][1]
+	}
+	return embeddings, nil
+})
+
+// newTestRecommendationService builds a RecommendationService that embeds with fakeEmbed
+// testRankingOptions are the default weights and result count
+var testRankingOptions = RankingOptions{Weights: ScoringWeights{"category": 0.4, "content": 0.6}, Limit: 5}
+
+func newTestRecommendationService(store *db.Store, cfg config.RecommendationConfig) *RecommendationService {
+	embeddings := NewAdEmbeddingService(store, fakeEmbed, config.EmbeddingConfig{Model: "fake", ModelVersion: "1"})
+	return newTestService(store, cfg, embeddings)
+}
+
+// newTestService builds a RecommendationService whose pipelines have no collaborative filtering nor CTR stages
+func newTestService(store *db.Store, cfg config.RecommendationConfig, embeddings *AdEmbeddingService) *RecommendationService {
+	text := NewTextIndex()
+	return NewRecommendationService(store, cfg, embeddings, text, func(cfg config.RecommendationConfig) *Pipeline {
+		return NewPipeline(cfg, store, embeddings, text, nil)
+	})
+}
+
+func TestGenerateRecommendationsWithMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewMemoryStore()
+	assert.NoError(t, (&db.Fixture{
+		Playback: []models.PlaybackEvent{
+			{UserID: "u1", Category: "Action"},
+			{UserID: "u2", Category: "Comedy"},
+		},
+		CategoryMappings: []models.CategoryMapping{
+			{MovieCategory: "Action", AdCategories: []string{"Cars", "Gadgets"}, Weight: 1},
+		},
+		Ads: []models.Ad{
+			{AdID: "car", Category: "Cars", Description: "Fast sports car"},
+			{AdID: "headphones", Category: "Gadgets", Description: "Noise cancelling"},
+			{AdID: "comedy", Category: "Comedy", Description: "Stand-up tour"},
+		},
+	}).Apply(ctx, store))
+
+	service := newTestRecommendationService(store, config.RecommendationConfig{HistoryLimit: 50})
+
+	ranked := service.GenerateRecommendations(ctx, "u1", testRankingOptions)
+
+	assert.Len(t, ranked, 2)
+	assert.Equal(t, "car", ranked[0].AdID)
+	assert.Equal(t, "headphones", ranked[1].AdID)
+}
+
+func TestGenerateRecommendationsFallsBackToTextScoring(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewMemoryStore()
+	assert.NoError(t, (&db.Fixture{
+		Playback: []models.PlaybackEvent{{UserID: "u1", Category: "Action"}},
+		CategoryMappings: []models.CategoryMapping{
+			{MovieCategory: "Action", AdCategories: []string{"Cars"}, Weight: 1},
+		},
+		Ads: []models.Ad{
+			{AdID: "van", Category: "Cars", Description: "Family van"},
+			{AdID: "car", Category: "Cars", Description: "Action packed sports car"},
+		},
+	}).Apply(ctx, store))
+
+	unavailable := EmbedderFunc(func(ctx context.Context, texts []string) ([][]float64, error) {
+		return nil, errors.New("connection refused")
+	})
+	embeddings := NewAdEmbeddingService(store, unavailable, config.EmbeddingConfig{Model: "fake", ModelVersion: "1"})
+	service := newTestService(store, config.RecommendationConfig{}, embeddings)
+
+	ranked := service.GenerateRecommendations(ctx, "u1", testRankingOptions)
+
+	assert.Len(t, ranked, 2)
+	assert.Equal(t, "car", ranked[0].AdID)
+}
+
+func TestGenerateRecommendationsMergesNearestAds(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewMemoryStore()
+	assert.NoError(t, (&db.Fixture{
+		Playback: []models.PlaybackEvent{{UserID: "u1", Category: "Action"}},
+		CategoryMappings: []models.CategoryMapping{
+			{MovieCategory: "Action", AdCategories: []string{"Cars"}, Weight: 1},
+		},
+		Ads: []models.Ad{
+			{AdID: "car", Category: "Cars", Description: "Fast sports car"},
+			{AdID: "racing-game", Category: "Games", Description: "Fast sports car"},
+			{AdID: "old-game", Category: "Games", Description: "Fast sports car", Status: models.AdStatusPaused},
+			{AdID: "tour", Category: "Comedy", Description: "Stand-up tour"},
+		},
+	}).Apply(ctx, store))
+
+	embeddings := NewAdEmbeddingService(store, fakeEmbed, config.EmbeddingConfig{Model: "fake", ModelVersion: "1"})
+	embeddings.Index = ann.New(ann.DefaultConfig)
+	_, err := embeddings.Backfill(ctx, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, embeddings.Index.Len())
+
+	service := newTestService(store, config.RecommendationConfig{ANNCandidates: 2}, embeddings)
+	ranked := service.GenerateRecommendations(ctx, "u1", testRankingOptions)
+
+	ids := []string{}
+	for _, ad := range ranked {
+		ids = append(ids, ad.AdID)
+	}
+	assert.Equal(t, []string{"car", "racing-game"}, ids)
+}
+
+func TestFetchMappedAdCategoriesMissingMapping(t *testing.T) {
+	service := newTestRecommendationService(db.NewMemoryStore(), config.RecommendationConfig{})
+
+	mapped, err := service.FetchMappedAdCategories(context.Background(), "Horror")
+
+	assert.NoError(t, err)
+	assert.Empty(t, mapped)
+}
+
+func TestCategoryCandidatesSkipsPausedAds(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewMemoryStore()
+	assert.NoError(t, (&db.Fixture{Ads: []models.Ad{
+		{AdID: "car", Category: "Cars"},
+		{AdID: "old-car", Category: "Cars", Status: models.AdStatusPaused},
+		{AdID: "watch", Category: "Gadgets"},
+		{AdID: "phone", Category: "Gadgets"},
+	}}).Apply(ctx, store))
+	generator := &CategoryCandidates{Ads: store.Ads}
+
+	ads, err := generator.Generate(ctx, &RankingRequest{
+		CategoryWeights: map[string]float64{"Cars": 0.5, "Gadgets": 0.9, "Books": 0.2},
+	}, map[string]bool{})
+
+	assert.NoError(t, err)
+	assert.Len(t, ads, 3)
+	assert.Equal(t, []string{"phone", "watch", "car"}, []string{ads[0].AdID, ads[1].AdID, ads[2].AdID})
+}
+
+func TestExplainRecommendations(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewMemoryStore()
+	assert.NoError(t, (&db.Fixture{
+		Playback: []models.PlaybackEvent{
+			{UserID: "u1", Category: "Action"},
+			{UserID: "u1", Category: "Action"},
+		},
+		CategoryMappings: []models.CategoryMapping{
+			{MovieCategory: "Action", AdCategories: []string{"Cars"}, Weight: 0.8},
+		},
+		Ads: []models.Ad{
+			{AdID: "car", Category: "Cars", Description: "Fast sports car"},
+			{AdID: "van", Category: "Cars", Description: "Family van"},
+		},
+	}).Apply(ctx, store))
+	service := newTestRecommendationService(store, config.RecommendationConfig{})
+
+	opts := testRankingOptions
+	opts.MinScore = 0.5
+	explained := service.ExplainRecommendations(ctx, "u1", opts)
+
+	assert.Len(t, explained, 1)
+	explanation := explained[0].Explanation
+	assert.Equal(t, "car", explained[0].Ad.AdID)
+	assert.Equal(t, "category", explanation.Source)
+	assert.Equal(t, []CategorySource{{MovieCategory: "Action", MappingWeight: 0.8, Plays: 2, PlayWeight: 2}}, explanation.MovieCategories)
+	assert.Equal(t, 1, explanation.RankBeforeFilters)
+	assert.Equal(t, 1, explanation.RankAfterFilters)
+
+	total := 0.0
+	for _, contribution := range explanation.Contributions {
+		assert.InDelta(t, contribution.Weight*contribution.Score, contribution.Contribution, 1e-9)
+		total += contribution.Contribution
+	}
+	assert.Len(t, explanation.Contributions, 2)
+	assert.InDelta(t, explanation.Score, total, 1e-9)
+}
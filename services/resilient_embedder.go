@@ -0,0 +1,171 @@
+package services
+
+import (
+	"Ad-Recommendations/vector"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// StatusError is returned when an embedding service answers with a non-200 status
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("embedding service returned HTTP %d: %s", e.StatusCode, e.Body)
+}
+
+// ResilientEmbedder calls a remote Embedder with per-call deadlines, bounded
+// retries with jittered backoff, a circuit breaker, and large text lists split
+// into parallel batches
+type ResilientEmbedder struct {
+	Next         Embedder
+	Spec         vector.Spec   // Shape every response is checked against
+	Timeout      time.Duration // Deadline of a single attempt, 0 for none
+	MaxRetries   int           // Retries after the first attempt
+	RetryBackoff time.Duration // Base delay, doubled per retry with full jitter
+	BatchSize    int           // Texts per call, 0 for no limit
+	MaxParallel  int           // Batches in flight at once
+	Breaker      *CircuitBreaker
+}
+
+// maxRetryBackoff caps the delay between two attempts
+const maxRetryBackoff = 5 * time.Second
+
+// Embed splits the texts into batches and embeds them in parallel, preserving order
+func (e *ResilientEmbedder) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	if len(texts) == 0 {
+		return [][]float64{}, nil
+	}
+	if err := e.Breaker.Allow(); err != nil {
+		return nil, err
+	}
+
+	batchSize := e.BatchSize
+	if batchSize <= 0 || batchSize > len(texts) {
+		batchSize = max(len(texts), 1)
+	}
+	parallel := max(e.MaxParallel, 1)
+
+	embeddings := make([][]float64, len(texts))
+	errs := make(chan error, (len(texts)+batchSize-1)/batchSize)
+	semaphore := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+
+	for start := 0; start < len(texts); start += batchSize {
+		end := min(start+batchSize, len(texts))
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			batch, err := e.embedBatch(ctx, texts[start:end])
+			if err != nil {
+				errs <- err
+				return
+			}
+			copy(embeddings[start:end], batch)
+		}(start, end)
+	}
+	wg.Wait()
+	close(errs)
+
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+	return embeddings, nil
+}
+
+// embedBatch embeds one batch, retrying transient failures
+func (e *ResilientEmbedder) embedBatch(ctx context.Context, texts []string) ([][]float64, error) {
+	var lastErr error
+	for attempt := 0; attempt <= e.MaxRetries; attempt++ {
+		if attempt > 0 {
+			if err := sleepContext(ctx, e.backoff(attempt)); err != nil {
+				break
+			}
+			log.Printf("🔁 Retrying embedding batch of %d texts (attempt %d): %v", len(texts), attempt+1, lastErr)
+		}
+
+		embeddings, err := e.attempt(ctx, texts)
+		if err == nil {
+			e.Breaker.Success()
+			return embeddings, nil
+		}
+		lastErr = err
+
+		// Caller cancellation and permanent errors are not retried
+		if ctx.Err() != nil || !retryable(err) {
+			break
+		}
+	}
+
+	// A cancelled caller says nothing about the service, but must not leave a trial in flight
+	if ctx.Err() != nil {
+		e.Breaker.Cancel()
+	} else {
+		e.Breaker.Failure()
+	}
+	return nil, lastErr
+}
+
+// attempt makes one call under the per-call deadline and checks the response shape
+func (e *ResilientEmbedder) attempt(ctx context.Context, texts []string) ([][]float64, error) {
+	if e.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.Timeout)
+		defer cancel()
+	}
+
+	embeddings, err := e.Next.Embed(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.Spec.CheckBatch(embeddings, len(texts)); err != nil {
+		return nil, err
+	}
+	return embeddings, nil
+}
+
+// backoff returns a random delay up to RetryBackoff * 2^(attempt-1)
+func (e *ResilientEmbedder) backoff(attempt int) time.Duration {
+	limit := e.RetryBackoff << (attempt - 1)
+	if limit <= 0 || limit > maxRetryBackoff {
+		limit = maxRetryBackoff
+	}
+	return time.Duration(rand.Int63n(int64(limit) + 1))
+}
+
+// retryable reports whether an embedding error is likely transient
+func retryable(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= http.StatusInternalServerError || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+// sleepContext waits for the duration or until the context is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResilientEmbedderRetriesTransientErrors(t *testing.T) {
+	var calls atomic.Int32
+	flaky := EmbedderFunc(func(ctx context.Context, texts []string) ([][]float64, error) {
+		if calls.Add(1) == 1 {
+			return nil, &StatusError{StatusCode: http.StatusServiceUnavailable}
+		}
+		return NewHashingEmbedder(4).Embed(ctx, texts)
+	})
+	embedder := &ResilientEmbedder{Next: flaky, MaxRetries: 2, RetryBackoff: time.Millisecond}
+
+	embeddings, err := embedder.Embed(context.Background(), []string{"Action"})
+
+	assert.NoError(t, err)
+	assert.Len(t, embeddings, 1)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestResilientEmbedderSplitsBatchesInOrder(t *testing.T) {
+	var calls atomic.Int32
+	counting := EmbedderFunc(func(ctx context.Context, texts []string) ([][]float64, error) {
+		calls.Add(1)
+		return NewHashingEmbedder(4).Embed(ctx, texts)
+	})
+	embedder := &ResilientEmbedder{Next: counting, BatchSize: 2, MaxParallel: 2}
+	texts := []string{"a", "b", "c", "d", "e"}
+
+	embeddings, err := embedder.Embed(context.Background(), texts)
+	assert.NoError(t, err)
+
+	expected, _ := NewHashingEmbedder(4).Embed(context.Background(), texts)
+	assert.Equal(t, expected, embeddings)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestResilientEmbedderCircuitBreaker(t *testing.T) {
+	var calls atomic.Int32
+	down := true
+	service := EmbedderFunc(func(ctx context.Context, texts []string) ([][]float64, error) {
+		calls.Add(1)
+		if down {
+			return nil, &StatusError{StatusCode: http.StatusBadGateway}
+		}
+		return NewHashingEmbedder(4).Embed(ctx, texts)
+	})
+	embedder := &ResilientEmbedder{Next: service, Breaker: NewCircuitBreaker("test", 2, 20*time.Millisecond)}
+
+	for i := 0; i < 2; i++ {
+		_, err := embedder.Embed(context.Background(), []string{"a"})
+		assert.Error(t, err)
+	}
+
+	// The open breaker rejects calls without reaching the service
+	_, err := embedder.Embed(context.Background(), []string{"a"})
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	assert.Equal(t, int32(2), calls.Load())
+
+	// After the cooldown a successful trial closes it again
+	down = false
+	time.Sleep(30 * time.Millisecond)
+	_, err = embedder.Embed(context.Background(), []string{"a"})
+	assert.NoError(t, err)
+	assert.False(t, embedder.Breaker.Open())
+}
+
+func TestCircuitBreakerTrialCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	healthy := false
+	service := EmbedderFunc(func(callCtx context.Context, texts []string) ([][]float64, error) {
+		if healthy {
+			return NewHashingEmbedder(4).Embed(callCtx, texts)
+		}
+		cancel() // The client goes away during the trial call
+		return nil, callCtx.Err()
+	})
+	embedder := &ResilientEmbedder{Next: service, Breaker: NewCircuitBreaker("test", 1, 20*time.Millisecond)}
+	embedder.Breaker.Failure()
+	assert.True(t, embedder.Breaker.Open())
+
+	time.Sleep(30 * time.Millisecond)
+	_, err := embedder.Embed(ctx, []string{"a"})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// The cancelled trial does not keep the breaker open
+	healthy = true
+	_, err = embedder.Embed(context.Background(), []string{"a"})
+	assert.NoError(t, err)
+	assert.False(t, embedder.Breaker.Open())
+
+	// A trial that never finishes expires after the cooldown
+	breaker := NewCircuitBreaker("stale", 1, 20*time.Millisecond)
+	breaker.Failure()
+	time.Sleep(30 * time.Millisecond)
+	assert.NoError(t, breaker.Allow())
+	assert.ErrorIs(t, breaker.Allow(), ErrCircuitOpen)
+	time.Sleep(30 * time.Millisecond)
+	assert.NoError(t, breaker.Allow())
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"Ad-Recommendations/config"
+	"Ad-Recommendations/db"
+	"Ad-Recommendations/models"
+	"Ad-Recommendations/vector"
+	"context"
+	"log"
+	"math"
+	"strings"
+	"time"
+)
+
+// CategoryScorer scores ads by the user's normalized affinity to their category
+type CategoryScorer struct{}
+
+// Name identifies the scorer in weights
+func (CategoryScorer) Name() string { return config.ScorerCategory }
+
+// Score returns the category affinity of each ad, 0 for unmapped categories
+func (CategoryScorer) Score(ctx context.Context, req *RankingRequest, ads []models.Ad) ([]float64, error) {
+	scores := make([]float64, len(ads))
+	for i, ad := range ads {
+		scores[i] = req.CategoryWeights[ad.Category]
+	}
+	return scores, nil
+}
+
+// ContentScorer scores each ad against the user's playback history. Ads are
+// compared to the user vector by embedding similarity; without a user vector,
+// or when ad embeddings cannot be loaded, the text index is used instead so
+// recommendations keep working while the embedding provider is down.
+type ContentScorer struct {
+	Embeddings *AdEmbeddingService
+	Text       *TextIndex
+}
+
+// Name identifies the scorer in weights
+func (s *ContentScorer) Name() string { return config.ScorerContent }
+
+// Score returns the embedding similarity or text relevance of each ad
+func (s *ContentScorer) Score(ctx context.Context, req *RankingRequest, ads []models.Ad) ([]float64, error) {
+	if req.UserVector == nil {
+		return s.Text.Scores(req.PlaybackHistory, ads), nil
+	}
+
+	// Load stored ad embeddings, embedding only ads that are new or changed
+	adVectors, err := s.Embeddings.EnsureEmbeddings(ctx, ads)
+	if err != nil {
+		log.Printf("⚠️ Embedding scoring failed, falling back to text scoring: %v", err)
+		return s.Text.Scores(req.PlaybackHistory, ads), nil
+	}
+
+	scores := make([]float64, len(ads))
+	for i, ad := range ads {
+		score, err := vector.Cosine(req.UserVector, adVectors[ad.AdID])
+		if err != nil {
+			// Ad and user vectors come from different models; text scores stay comparable
+			log.Printf("⚠️ Embedding scoring failed for ad %s, falling back to text scoring: %v", ad.AdID, err)
+			return s.Text.Scores(req.PlaybackHistory, ads), nil
+		}
+		scores[i] = score
+	}
+	return scores, nil
+}
+
+// KeywordScorer scores ads by the share of their keywords found in the playback history
+type KeywordScorer struct{}
+
+// Name identifies the scorer in weights
+func (KeywordScorer) Name() string { return config.ScorerKeywords }
+
+// Score returns the fraction of each ad's keyword tokens that occur in the history
+func (KeywordScorer) Score(ctx context.Context, req *RankingRequest, ads []models.Ad) ([]float64, error) {
+	history := map[string]bool{}
+	for _, token := range tokenize(strings.Join(req.PlaybackHistory, " ")) {
+		history[token] = true
+	}
+
+	scores := make([]float64, len(ads))
+	for i, ad := range ads {
+		keywords := map[string]bool{}
+		for _, token := range tokenize(strings.Join(ad.Keywords, " ")) {
+			keywords[token] = true
+		}
+		if len(keywords) == 0 {
+			continue
+		}
+		matched := 0
+		for token := range keywords {
+			if history[token] {
+				matched++
+			}
+		}
+		scores[i] = float64(matched) / float64(len(keywords))
+	}
+	return scores, nil
+}
+
+// RecencyScorer favours new ads: the score is 1 for a new ad and halves every
+// HalfLife. Ads without a creation time score 0.
+type RecencyScorer struct {
+	HalfLife time.Duration
+	Now      func() time.Time
+}
+
+// Name identifies the scorer in weights
+func (s *RecencyScorer) Name() string { return config.ScorerRecency }
+
+// Score returns the decayed age of each ad
+func (s *RecencyScorer) Score(ctx context.Context, req *RankingRequest, ads []models.Ad) ([]float64, error) {
+	now := s.Now()
+	scores := make([]float64, len(ads))
+	for i, ad := range ads {
+		if ad.CreatedAt.IsZero() {
+			continue
+		}
+		age := now.Sub(ad.CreatedAt)
+		if age <= 0 || s.HalfLife <= 0 {
+			scores[i] = 1
+			continue
+		}
+		scores[i] = math.Exp2(-float64(age) / float64(s.HalfLife))
+	}
+	return scores, nil
+}
+
+// CTRScorer scores ads by their smoothed click-through rate for the user's
+// favourite movie category, relative to the best candidate
+type CTRScorer struct {
+	Estimator *CTREstimator
+}
+
+// Name identifies the scorer in weights
+func (s *CTRScorer) Name() string { return config.ScorerCTR }
+
+// Score returns the estimated click-through rate of each ad normalized by the candidates' maximum
+func (s *CTRScorer) Score(ctx context.Context, req *RankingRequest, ads []models.Ad) ([]float64, error) {
+	movieCategory := req.Profile.FavouriteCategory()
+	scores := make([]float64, len(ads))
+	maxScore := 0.0
+	for i, ad := range ads {
+		scores[i] = s.Estimator.Estimate(ad.AdID, ad.Category, movieCategory)
+		maxScore = math.Max(maxScore, scores[i])
+	}
+	if maxScore > 0 {
+		for i := range scores {
+			scores[i] /= maxScore
+		}
+	}
+	return scores, nil
+}
+
+// PopularityScorer scores ads by their clicks across all users within Window,
+// on a log scale relative to the most clicked candidate
+type PopularityScorer struct {
+	Clicks db.AdClickRepository
+	Window time.Duration
+	Now    func() time.Time
+}
+
+// Name identifies the scorer in weights
+func (s *PopularityScorer) Name() string { return config.ScorerPopularity }
+
+// Score returns log(1 + clicks) of each ad normalized by the candidates' maximum
+func (s *PopularityScorer) Score(ctx context.Context, req *RankingRequest, ads []models.Ad) ([]float64, error) {
+	adIDs := make([]string, len(ads))
+	for i, ad := range ads {
+		adIDs[i] = ad.AdID
+	}
+
+	var since time.Time
+	if s.Window > 0 {
+		since = s.Now().Add(-s.Window)
+	}
+	counts, err := s.Clicks.AdClickCounts(ctx, adIDs, since)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make([]float64, len(ads))
+	maxScore := 0.0
+	for i, ad := range ads {
+		scores[i] = math.Log1p(float64(counts[ad.AdID]))
+		maxScore = math.Max(maxScore, scores[i])
+	}
+	if maxScore > 0 {
+		for i := range scores {
+			scores[i] /= maxScore
+		}
+	}
+	return scores, nil
+}
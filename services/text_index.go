@@ -0,0 +1,208 @@
+package services
+
+import (
+	"Ad-Recommendations/models"
+	"math"
+	"strings"
+	"sync"
+)
+
+// BM25 parameters; the usual defaults for short documents
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// textDocument is the indexed form of one ad
+type textDocument struct {
+	hash   string         // ContentHash of the indexed text, to detect changed ads
+	terms  map[string]int // Term frequencies
+	length int            // Number of tokens
+}
+
+// TextIndex is an in-process TF-IDF / BM25 index over ad descriptions and
+// keywords. It needs no external service, so ranking keeps working when the
+// embedding provider is unavailable.
+type TextIndex struct {
+	mu          sync.RWMutex
+	docs        map[string]textDocument
+	docFreq     map[string]int
+	totalLength int
+}
+
+// NewTextIndex creates an empty TextIndex
+func NewTextIndex() *TextIndex {
+	return &TextIndex{docs: map[string]textDocument{}, docFreq: map[string]int{}}
+}
+
+// adText returns the text of an ad that is indexed for text scoring
+func adText(ad models.Ad) string {
+	return ad.Description + " " + strings.Join(ad.Keywords, " ")
+}
+
+// Rebuild replaces the whole index with the given ads
+func (idx *TextIndex) Rebuild(ads []models.Ad) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.docs = make(map[string]textDocument, len(ads))
+	idx.docFreq = make(map[string]int)
+	idx.totalLength = 0
+	for _, ad := range ads {
+		idx.upsertLocked(ad)
+	}
+}
+
+// Upsert adds an ad to the index or reindexes it
+func (idx *TextIndex) Upsert(ad models.Ad) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.upsertLocked(ad)
+}
+
+// Remove drops an ad from the index
+func (idx *TextIndex) Remove(adID string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.removeLocked(adID)
+}
+
+// Ensure indexes the ads that are missing from the index or whose text changed
+func (idx *TextIndex) Ensure(ads []models.Ad) {
+	idx.mu.RLock()
+	stale := []models.Ad{}
+	for _, ad := range ads {
+		if doc, ok := idx.docs[ad.AdID]; !ok || doc.hash != models.ContentHash(adText(ad)) {
+			stale = append(stale, ad)
+		}
+	}
+	idx.mu.RUnlock()
+
+	if len(stale) == 0 {
+		return
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for _, ad := range stale {
+		idx.upsertLocked(ad)
+	}
+}
+
+// Len returns the number of indexed ads
+func (idx *TextIndex) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.docs)
+}
+
+func (idx *TextIndex) upsertLocked(ad models.Ad) {
+	idx.removeLocked(ad.AdID)
+
+	text := adText(ad)
+	doc := textDocument{hash: models.ContentHash(text), terms: map[string]int{}}
+	for _, token := range tokenize(text) {
+		doc.terms[token]++
+		doc.length++
+	}
+	for term := range doc.terms {
+		idx.docFreq[term]++
+	}
+	idx.totalLength += doc.length
+	idx.docs[ad.AdID] = doc
+}
+
+func (idx *TextIndex) removeLocked(adID string) {
+	doc, ok := idx.docs[adID]
+	if !ok {
+		return
+	}
+	for term := range doc.terms {
+		idx.docFreq[term]--
+		if idx.docFreq[term] <= 0 {
+			delete(idx.docFreq, term)
+		}
+	}
+	idx.totalLength -= doc.length
+	delete(idx.docs, adID)
+}
+
+// idf returns the smoothed inverse document frequency of a term
+func (idx *TextIndex) idf(term string) float64 {
+	n := float64(len(idx.docs))
+	df := float64(idx.docFreq[term])
+	return math.Log(1 + (n-df+0.5)/(df+0.5))
+}
+
+// Scores returns a text relevance score in [0, 1] for each ad against the
+// query texts. It averages the cosine of TF-IDF vectors with BM25 normalized
+// by the best candidate. Ads not in the index are indexed first.
+func (idx *TextIndex) Scores(queries []string, ads []models.Ad) []float64 {
+	idx.Ensure(ads)
+
+	queryTerms := map[string]int{}
+	for _, query := range queries {
+		for _, token := range tokenize(query) {
+			queryTerms[token]++
+		}
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	scores := make([]float64, len(ads))
+	if len(queryTerms) == 0 || len(idx.docs) == 0 {
+		return scores
+	}
+
+	avgLength := float64(idx.totalLength) / float64(len(idx.docs))
+	queryVector := idx.tfidfVector(queryTerms)
+
+	bm25 := make([]float64, len(ads))
+	maxBM25 := 0.0
+	for i, ad := range ads {
+		doc := idx.docs[ad.AdID]
+		for term := range queryTerms {
+			tf := float64(doc.terms[term])
+			if tf == 0 {
+				continue
+			}
+			norm := 1 - bm25B + bm25B*float64(doc.length)/avgLength
+			bm25[i] += idx.idf(term) * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+		}
+		maxBM25 = math.Max(maxBM25, bm25[i])
+	}
+
+	for i, ad := range ads {
+		tfidf := sparseCosine(queryVector, idx.tfidfVector(idx.docs[ad.AdID].terms))
+		normalizedBM25 := 0.0
+		if maxBM25 > 0 {
+			normalizedBM25 = bm25[i] / maxBM25
+		}
+		scores[i] = (tfidf + normalizedBM25) / 2
+	}
+	return scores
+}
+
+// tfidfVector weights term frequencies by inverse document frequency
+func (idx *TextIndex) tfidfVector(terms map[string]int) map[string]float64 {
+	vector := make(map[string]float64, len(terms))
+	for term, tf := range terms {
+		vector[term] = (1 + math.Log(float64(tf))) * idx.idf(term)
+	}
+	return vector
+}
+
+// sparseCosine calculates the cosine similarity of two sparse vectors
+func sparseCosine(a, b map[string]float64) float64 {
+	var dot, normA, normB float64
+	for term, value := range a {
+		dot += value * b[term]
+		normA += value * value
+	}
+	for _, value := range b {
+		normB += value * value
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
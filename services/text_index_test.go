@@ -0,0 +1,38 @@
+package services
+
+import (
+	"Ad-Recommendations/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTextIndexScoresByRelevance(t *testing.T) {
+	ads := []models.Ad{
+		{AdID: "car", Description: "Fast sports car", Keywords: []string{"racing", "action"}},
+		{AdID: "tour", Description: "Stand-up comedy tour"},
+		{AdID: "phone", Description: "New phone with a fast camera"},
+	}
+	index := NewTextIndex()
+	index.Rebuild(ads)
+
+	scores := index.Scores([]string{"Action", "racing"}, ads)
+
+	assert.Greater(t, scores[0], 0.5)
+	assert.Zero(t, scores[1])
+	assert.Zero(t, scores[2])
+}
+
+func TestTextIndexTracksAdChanges(t *testing.T) {
+	index := NewTextIndex()
+	index.Rebuild([]models.Ad{{AdID: "car", Description: "Fast sports car"}})
+
+	index.Remove("car")
+	assert.Zero(t, index.Len())
+
+	// Scoring indexes unseen ads and reindexes changed descriptions
+	ads := []models.Ad{{AdID: "car", Description: "Electric family car"}}
+	scores := index.Scores([]string{"electric"}, ads)
+	assert.Equal(t, 1, index.Len())
+	assert.Greater(t, scores[0], 0.0)
+}
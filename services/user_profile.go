@@ -0,0 +1,101 @@
+package services
+
+import (
+	"Ad-Recommendations/models"
+	"math"
+	"sort"
+	"time"
+)
+
+// UserProfile summarizes a user's playback and click history with exponential
+// time decay: each event counts 2^(-age / half-life), so recent and repeated
+// events weigh more. Weights are sums over events and do not depend on event order.
+type UserProfile struct {
+	MovieCategories map[string]float64 // Decayed play weight per movie category
+	Plays           map[string]int     // Raw play count per movie category
+
+	ClickedAds        map[string]float64   // Decayed click weight per ad ID
+	Clicks            map[string]int       // Raw click count per ad ID
+	ClickedCategories map[string]float64   // Decayed click weight per ad category
+	Ads               map[string]models.Ad // Clicked ads that still exist, by ID
+}
+
+// BuildUserProfile folds playback events into a profile as of now. A half-life
+// of 0 counts every play as 1. Events without a timestamp or from the future
+// count as new.
+func BuildUserProfile(events []models.PlaybackEvent, halfLife time.Duration, now time.Time) UserProfile {
+	profile := UserProfile{
+		MovieCategories:   map[string]float64{},
+		Plays:             map[string]int{},
+		ClickedAds:        map[string]float64{},
+		Clicks:            map[string]int{},
+		ClickedCategories: map[string]float64{},
+		Ads:               map[string]models.Ad{},
+	}
+	for _, event := range events {
+		if event.Category == "" {
+			continue
+		}
+		profile.MovieCategories[event.Category] += decayWeight(event.Timestamp, now, halfLife)
+		profile.Plays[event.Category]++
+	}
+	return profile
+}
+
+// AddClicks folds ad clicks into the profile as of now. ads holds the clicked
+// ads by ID; clicks on ads missing from it still count as clicks but do not
+// add to any category.
+func (p *UserProfile) AddClicks(clicks []models.AdClick, ads map[string]models.Ad, halfLife time.Duration, now time.Time) {
+	for _, click := range clicks {
+		weight := decayWeight(click.Timestamp, now, halfLife)
+		p.ClickedAds[click.AdID] += weight
+		p.Clicks[click.AdID]++
+
+		ad, ok := ads[click.AdID]
+		if !ok {
+			continue
+		}
+		p.Ads[ad.AdID] = ad
+		p.ClickedCategories[ad.Category] += weight
+	}
+}
+
+// ClickedAdList returns the clicked ads that still exist, sorted by ID
+func (p UserProfile) ClickedAdList() []models.Ad {
+	ads := make([]models.Ad, 0, len(p.Ads))
+	for _, ad := range p.Ads {
+		ads = append(ads, ad)
+	}
+	sort.Slice(ads, func(i, j int) bool { return ads[i].AdID < ads[j].AdID })
+	return ads
+}
+
+// Categories returns the movie categories of the profile, sorted by name
+func (p UserProfile) Categories() []string {
+	categories := make([]string, 0, len(p.MovieCategories))
+	for category := range p.MovieCategories {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	return categories
+}
+
+// FavouriteCategory returns the movie category with the highest decayed play
+// weight, the first by name on ties, or "" for a profile without playback
+func (p UserProfile) FavouriteCategory() string {
+	favourite := ""
+	for _, category := range p.Categories() {
+		if favourite == "" || p.MovieCategories[category] > p.MovieCategories[favourite] {
+			favourite = category
+		}
+	}
+	return favourite
+}
+
+// decayWeight returns the weight of an event at t: 1 when new, halving every half-life
+func decayWeight(t, now time.Time, halfLife time.Duration) float64 {
+	if halfLife <= 0 || t.IsZero() || !t.Before(now) {
+		return 1
+	}
+	return math.Exp2(-float64(now.Sub(t)) / float64(halfLife))
+}
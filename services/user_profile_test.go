@@ -0,0 +1,86 @@
+package services
+
+import (
+	"Ad-Recommendations/config"
+	"Ad-Recommendations/db"
+	"Ad-Recommendations/models"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildUserProfileDecaysPlays(t *testing.T) {
+	now := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	week := 7 * 24 * time.Hour
+	events := []models.PlaybackEvent{
+		{Category: "Action", Timestamp: now.Add(-week)},
+		{Category: "Comedy", Timestamp: now},
+		{Category: "Action", Timestamp: now.Add(-2 * week)},
+		{Category: "Action", Timestamp: now},
+	}
+
+	profile := BuildUserProfile(events, week, now)
+
+	assert.InDelta(t, 1.75, profile.MovieCategories["Action"], 1e-9)
+	assert.InDelta(t, 1.0, profile.MovieCategories["Comedy"], 1e-9)
+	assert.Equal(t, map[string]int{"Action": 3, "Comedy": 1}, profile.Plays)
+	assert.Equal(t, []string{"Action", "Comedy"}, profile.Categories())
+
+	// Reordering the events gives the same profile
+	reversed := []models.PlaybackEvent{events[3], events[2], events[1], events[0]}
+	assert.Equal(t, profile, BuildUserProfile(reversed, week, now))
+}
+
+func TestCategoryAffinitiesCountRepeatPlays(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewMemoryStore()
+	assert.NoError(t, (&db.Fixture{CategoryMappings: []models.CategoryMapping{
+		{MovieCategory: "Action", AdCategories: []string{"Cars", "Gadgets"}, Weight: 0.5},
+		{MovieCategory: "Comedy", AdCategories: []string{"Gadgets", "Books"}, Weight: 1},
+	}}).Apply(ctx, store))
+	service := newTestRecommendationService(store, config.RecommendationConfig{})
+
+	affinities, _ := service.CategoryAffinities(ctx, UserProfile{
+		MovieCategories: map[string]float64{"Action": 4, "Comedy": 1},
+		Plays:           map[string]int{"Action": 4, "Comedy": 1},
+	})
+
+	assert.InDeltaMapValues(t, map[string]float64{"Cars": 2.0 / 3, "Gadgets": 1, "Books": 1.0 / 3}, affinities, 1e-9)
+}
+
+func TestClicksFeedCategoryAffinities(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewMemoryStore()
+	assert.NoError(t, (&db.Fixture{
+		Playback: []models.PlaybackEvent{{UserID: "u1", Category: "Action"}},
+		Clicks: []models.AdClick{
+			{UserID: "u1", AdID: "tour"},
+			{UserID: "u1", AdID: "tour"},
+			{UserID: "u1", AdID: "deleted"},
+		},
+		CategoryMappings: []models.CategoryMapping{
+			{MovieCategory: "Action", AdCategories: []string{"Cars"}, Weight: 1},
+		},
+		Ads: []models.Ad{{AdID: "tour", Category: "Comedy", Description: "Stand-up tour"}},
+	}).Apply(ctx, store))
+	service := newTestRecommendationService(store, config.RecommendationConfig{PlaybackWeight: 1, ClickWeight: 1})
+
+	clicks, ads, err := service.FetchUserClicks(ctx, "u1")
+	assert.NoError(t, err)
+	profile := BuildUserProfile(nil, 0, time.Now())
+	profile.MovieCategories["Action"] = 1
+	profile.AddClicks(clicks, ads, 0, time.Now())
+
+	assert.Equal(t, map[string]int{"tour": 2, "deleted": 1}, profile.Clicks)
+	assert.Equal(t, []string{"tour"}, []string{profile.ClickedAdList()[0].AdID})
+
+	affinities, _ := service.CategoryAffinities(ctx, profile)
+	assert.InDeltaMapValues(t, map[string]float64{"Cars": 0.5, "Comedy": 1}, affinities, 1e-9)
+
+	// The clicked ad pulls the user vector away from pure Action
+	userVector := service.UserVector(ctx, profile)
+	assert.InDelta(t, 1.0/3, userVector[0], 1e-9)
+	assert.InDelta(t, 2.0/3, userVector[1], 1e-9)
+}
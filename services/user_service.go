@@ -5,85 +5,64 @@ import (
 	"Ad-Recommendations/models"
 	"context"
 	"errors"
-
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
-// AddUser adds a new user to the Users table in DynamoDB
-func AddUser(user models.User) error {
+// UserService manages user profiles
+type UserService struct {
+	Users db.UserRepository
+}
+
+// NewUserService creates a UserService backed by the given repository
+func NewUserService(users db.UserRepository) *UserService {
+	return &UserService{Users: users}
+}
+
+// AddUser adds a new user to the Users table
+func (s *UserService) AddUser(ctx context.Context, user models.User) error {
 	// Validate input
 	if user.UserID == "" {
 		return errors.New("user_id cannot be empty")
 	}
 
-	// Prepare PutItem input
-	_, err := db.DynamoClient.PutItem(context.TODO(), &dynamodb.PutItemInput{
-		TableName: aws.String(db.UserTableName),
-		Item:      user.ToDynamoDBItem(),
-	})
-
-	if err != nil {
+	if err := s.Users.PutUser(ctx, user); err != nil {
 		return errors.New("failed to add user: " + err.Error())
 	}
 
 	return nil
 }
 
-// GetUser retrieves a user from the Users table in DynamoDB
-// GetUser retrieves a user from DynamoDB
-func GetUser(userID string) (*models.User, error) {
+// GetUser retrieves a user from the Users table
+func (s *UserService) GetUser(ctx context.Context, userID string) (*models.User, error) {
 	// Validate input
 	if userID == "" {
 		return nil, errors.New("user_id cannot be empty")
 	}
 
-	// Query DynamoDB
-	output, err := db.DynamoClient.GetItem(context.TODO(), &dynamodb.GetItemInput{
-		TableName: aws.String(db.UserTableName),
-		Key: map[string]types.AttributeValue{
-			"user_id": &types.AttributeValueMemberS{Value: userID},
-		},
-	})
-
+	user, err := s.Users.GetUser(ctx, userID)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, errors.New("user not found")
+	}
 	if err != nil {
 		return nil, errors.New("failed to fetch user: " + err.Error())
 	}
 
-	// Check if user exists
-	if output.Item == nil {
-		return nil, errors.New("user not found")
-	}
-
-	// Convert DynamoDB item to User model
-	user := models.UserFromDynamoDBItem(output.Item)
-
 	return user, nil
 }
 
 // UpdateUser updates an existing user in the Users table
-func UpdateUser(user models.User) error {
+func (s *UserService) UpdateUser(ctx context.Context, user models.User) error {
 	// Reuse AddUser for the full replacement
-	return AddUser(user)
+	return s.AddUser(ctx, user)
 }
 
-// DeleteUser removes a user from the Users table in DynamoDB
-func DeleteUser(userID string) error {
+// DeleteUser removes a user from the Users table
+func (s *UserService) DeleteUser(ctx context.Context, userID string) error {
 	// Validate input
 	if userID == "" {
 		return errors.New("user_id cannot be empty")
 	}
 
-	// Delete the user
-	_, err := db.DynamoClient.DeleteItem(context.TODO(), &dynamodb.DeleteItemInput{
-		TableName: aws.String(db.UserTableName),
-		Key: map[string]types.AttributeValue{
-			"user_id": &types.AttributeValueMemberS{Value: userID},
-		},
-	})
-
-	if err != nil {
+	if err := s.Users.DeleteUser(ctx, userID); err != nil {
 		return errors.New("failed to delete user: " + err.Error())
 	}
 
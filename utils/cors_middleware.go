@@ -7,6 +7,7 @@ func EnableCORS(w http.ResponseWriter) {
 	w.Header().Set("Access-Control-Allow-Origin", "*") // Allow all origins, modify as needed for security
 	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	w.Header().Set("Access-Control-Expose-Headers", "X-Recommendation-ID, X-Exploration-Slots, X-Experiment, X-Experiment-Variant") // Lets browsers read how recommendations were served
 }
 
 // CorsMiddleware is the middleware for handling CORS preflight requests
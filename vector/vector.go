@@ -0,0 +1,224 @@
+// Package vector holds embedding vectors, validates their shape against the
+// model that produced them, and implements the vector math used for ranking.
+package vector
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrDimensionMismatch is wrapped by every DimensionError
+var ErrDimensionMismatch = errors.New("vector dimension mismatch")
+
+// ErrInvalidVector is returned for empty vectors or vectors holding NaN or Inf
+var ErrInvalidVector = errors.New("invalid vector")
+
+// DimensionError reports a vector whose size differs from the expected one
+type DimensionError struct {
+	Context  string // What was being checked, e.g. the model name
+	Expected int
+	Got      int
+}
+
+func (e *DimensionError) Error() string {
+	return fmt.Sprintf("%s: expected %d-dimensional vector, got %d", e.Context, e.Expected, e.Got)
+}
+
+func (e *DimensionError) Unwrap() error {
+	return ErrDimensionMismatch
+}
+
+// Vector is a compact float32 copy of an embedding, used wherever vectors are
+// held for long (indexes, caches, stores). Arithmetic is done in float64.
+type Vector []float32
+
+// FromFloat64 converts an embedding to its compact form
+func FromFloat64(values []float64) Vector {
+	v := make(Vector, len(values))
+	for i, value := range values {
+		v[i] = float32(value)
+	}
+	return v
+}
+
+// Float64 converts the vector back to float64 values
+func (v Vector) Float64() []float64 {
+	values := make([]float64, len(v))
+	for i, value := range v {
+		values[i] = float64(value)
+	}
+	return values
+}
+
+// Spec describes the vectors produced by one embedding model
+type Spec struct {
+	Model     string
+	Dimension int // 0 when the model's dimension is not configured
+}
+
+// Check validates a single vector produced by the model
+func (s Spec) Check(v []float64) error {
+	if len(v) == 0 {
+		return fmt.Errorf("%s: %w: empty vector", s.Model, ErrInvalidVector)
+	}
+	if s.Dimension > 0 && len(v) != s.Dimension {
+		return &DimensionError{Context: s.Model, Expected: s.Dimension, Got: len(v)}
+	}
+	for _, value := range v {
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return fmt.Errorf("%s: %w: non-finite value", s.Model, ErrInvalidVector)
+		}
+	}
+	return nil
+}
+
+// CheckBatch validates a model response: one vector per input, all of the same valid shape
+func (s Spec) CheckBatch(vectors [][]float64, count int) error {
+	if len(vectors) != count {
+		return fmt.Errorf("%s: expected %d vectors, got %d", s.Model, count, len(vectors))
+	}
+	if len(vectors) == 0 {
+		return nil
+	}
+
+	// Without a configured dimension, the first vector sets it for the batch
+	spec := s
+	if spec.Dimension == 0 {
+		spec.Dimension = len(vectors[0])
+	}
+	for _, v := range vectors {
+		if err := spec.Check(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Cosine returns the cosine similarity of two vectors of the same dimension.
+// Zero vectors have similarity 0.
+func Cosine(a, b []float64) (float64, error) {
+	if len(a) != len(b) {
+		return 0, &DimensionError{Context: "cosine similarity", Expected: len(a), Got: len(b)}
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0, nil
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB)), nil
+}
+
+// Mean returns the element-wise average of vectors of the same dimension
+func Mean(vectors [][]float64) ([]float64, error) {
+	if len(vectors) == 0 {
+		return nil, fmt.Errorf("mean: %w: no vectors", ErrInvalidVector)
+	}
+
+	mean := make([]float64, len(vectors[0]))
+	for _, v := range vectors {
+		if len(v) != len(mean) {
+			return nil, &DimensionError{Context: "mean", Expected: len(mean), Got: len(v)}
+		}
+		for i, value := range v {
+			mean[i] += value
+		}
+	}
+	for i := range mean {
+		mean[i] /= float64(len(vectors))
+	}
+	return mean, nil
+}
+
+// WeightedMean returns the weighted element-wise average of vectors of the
+// same dimension. Weights must be non-negative with a positive sum.
+func WeightedMean(vectors [][]float64, weights []float64) ([]float64, error) {
+	if len(vectors) == 0 {
+		return nil, fmt.Errorf("weighted mean: %w: no vectors", ErrInvalidVector)
+	}
+	if len(weights) != len(vectors) {
+		return nil, fmt.Errorf("weighted mean: %d weights for %d vectors", len(weights), len(vectors))
+	}
+
+	mean := make([]float64, len(vectors[0]))
+	total := 0.0
+	for j, v := range vectors {
+		if len(v) != len(mean) {
+			return nil, &DimensionError{Context: "weighted mean", Expected: len(mean), Got: len(v)}
+		}
+		if weights[j] < 0 || math.IsNaN(weights[j]) {
+			return nil, fmt.Errorf("weighted mean: invalid weight %v", weights[j])
+		}
+		for i, value := range v {
+			mean[i] += weights[j] * value
+		}
+		total += weights[j]
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("weighted mean: %w: weights sum to 0", ErrInvalidVector)
+	}
+	for i := range mean {
+		mean[i] /= total
+	}
+	return mean, nil
+}
+
+// Normalize returns a unit-length copy of the vector; zero vectors stay zero
+func Normalize(v []float64) []float64 {
+	norm := 0.0
+	for _, value := range v {
+		norm += value * value
+	}
+	norm = math.Sqrt(norm)
+
+	normalized := make([]float64, len(v))
+	if norm == 0 {
+		return normalized
+	}
+	for i, value := range v {
+		normalized[i] = value / norm
+	}
+	return normalized
+}
+
+// tagFloat32 starts the Encode format. Untagged data whose length is a
+// multiple of 8 is the original raw little-endian float64 format, which is
+// still decoded; a tagged encoding never has such a length.
+const tagFloat32 byte = 0x04
+
+// Encode packs a vector as a tag byte followed by little-endian float32 values
+func Encode(v []float64) []byte {
+	buf := make([]byte, 1+4*len(v))
+	buf[0] = tagFloat32
+	for i, value := range v {
+		binary.LittleEndian.PutUint32(buf[1+4*i:], math.Float32bits(float32(value)))
+	}
+	return buf
+}
+
+// Decode unpacks a vector written by Encode or in the raw float64 format
+func Decode(buf []byte) ([]float64, error) {
+	if len(buf)%8 == 0 {
+		v := make([]float64, len(buf)/8)
+		for i := range v {
+			v[i] = math.Float64frombits(binary.LittleEndian.Uint64(buf[8*i:]))
+		}
+		return v, nil
+	}
+
+	if buf[0] != tagFloat32 || (len(buf)-1)%4 != 0 {
+		return nil, fmt.Errorf("unknown vector encoding of %d bytes with tag 0x%02x", len(buf), buf[0])
+	}
+	data := buf[1:]
+	v := make([]float64, len(data)/4)
+	for i := range v {
+		v[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:])))
+	}
+	return v, nil
+}
@@ -0,0 +1,64 @@
+package vector
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpecCheckBatch(t *testing.T) {
+	spec := Spec{Model: "bert", Dimension: 3}
+
+	assert.NoError(t, spec.CheckBatch([][]float64{{1, 0, 0}, {0, 1, 0}}, 2))
+	assert.Error(t, spec.CheckBatch([][]float64{{1, 0, 0}}, 2))
+	assert.True(t, errors.Is(spec.CheckBatch([][]float64{{1, 0}}, 1), ErrDimensionMismatch))
+	assert.True(t, errors.Is(spec.Check([]float64{math.NaN(), 0, 0}), ErrInvalidVector))
+
+	// Without a configured dimension the vectors must still agree with each other
+	assert.True(t, errors.Is(Spec{Model: "any"}.CheckBatch([][]float64{{1, 0}, {1, 0, 0}}, 2), ErrDimensionMismatch))
+}
+
+func TestCosineAndMean(t *testing.T) {
+	similarity, err := Cosine([]float64{1, 0}, []float64{1, 1})
+	assert.NoError(t, err)
+	assert.InDelta(t, math.Sqrt(0.5), similarity, 1e-9)
+
+	_, err = Cosine([]float64{1, 0}, []float64{1, 0, 0})
+	assert.True(t, errors.Is(err, ErrDimensionMismatch))
+
+	mean, err := Mean([][]float64{{1, 0}, {0, 1}})
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{0.5, 0.5}, mean)
+
+	_, err = Mean([][]float64{{1, 0}, {1}})
+	assert.Error(t, err)
+
+	weighted, err := WeightedMean([][]float64{{1, 0}, {0, 1}}, []float64{3, 1})
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{0.75, 0.25}, weighted)
+
+	_, err = WeightedMean([][]float64{{1, 0}}, []float64{0})
+	assert.True(t, errors.Is(err, ErrInvalidVector))
+}
+
+func TestEncodeDecode(t *testing.T) {
+	for _, v := range [][]float64{{0.25, -1.5, 3}, {0.5, 2}, {}} {
+		decoded, err := Decode(Encode(v))
+		assert.NoError(t, err)
+		assert.Equal(t, FromFloat64(v).Float64(), decoded)
+	}
+
+	// The original untagged float64 format still decodes
+	raw := make([]byte, 16)
+	binary.LittleEndian.PutUint64(raw, math.Float64bits(0.1))
+	binary.LittleEndian.PutUint64(raw[8:], math.Float64bits(-2))
+	decoded, err := Decode(raw)
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{0.1, -2}, decoded)
+
+	_, err = Decode([]byte{0xff, 1, 2})
+	assert.Error(t, err)
+}